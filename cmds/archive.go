@@ -0,0 +1,174 @@
+package cmds
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+)
+
+// archiveExtensions are the file name suffixes read as archives, mapped to
+// whether the archive is a zip (true) or a tar (false).
+var archiveExtensions = map[string]bool{
+	".zip":    true,
+	".tar":    false,
+	".tar.gz": false,
+	".tgz":    false,
+}
+
+// isArchive reports whether path names a supported archive by extension.
+func isArchive(path string) bool {
+	_, ok := archiveKind(path)
+	return ok
+}
+
+func archiveKind(path string) (zip bool, ok bool) {
+	lower := strings.ToLower(path)
+	for ext, isZip := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return isZip, true
+		}
+	}
+	return false, false
+}
+
+// readArchive parses every supported member of the zip or tar archive at path.
+// As in a directory read with -d, each directory and file name becomes a key on
+// the path down to a member's contents, so two archives line up member by
+// member when diffed. When every member sits under
+// the same top-level directory, as in bundle-1234/..., that directory is
+// dropped so bundles with different names still compare. Members with an
+// unsupported extension and hidden files are skipped. The duplicate keys of
+// each member are found with dups unless it is nil.
+func readArchive(path string, dups *duplicateKeys) (map[string]any, error) {
+	isZip, ok := archiveKind(path)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a supported archive", path)
+	}
+	var members map[string][]byte
+	var err error
+	if isZip {
+		members, err = readZip(path)
+	} else {
+		members, err = readTar(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", path, err)
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("no supported files found in archive %s", path)
+	}
+	prefix := commonDir(members)
+	out := make(map[string]any, len(members))
+	for name, b := range members {
+		// members were filtered to supported extensions while reading
+		parse, _ := format.ForFile(name)
+		keys := strings.Split(strings.TrimPrefix(name, prefix), "/")
+		if dups != nil {
+			parse = dups.parserFor(fmt.Sprintf("%s in %s", name, path), keys, parse)
+		}
+		m, err := parse(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s in archive %s: %w", name, path, err)
+		}
+		nestUnder(out, keys, m)
+	}
+	return out, nil
+}
+
+func readZip(p string) (map[string][]byte, error) {
+	r, err := zip.OpenReader(p)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	members := map[string][]byte{}
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !wantMember(f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+		}
+		// #nosec G110 -- archives are local inputs the user chose to open.
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		members[path.Clean(f.Name)] = b
+	}
+	return members, nil
+}
+
+func readTar(p string) (map[string][]byte, error) {
+	// #nosec G304 -- arbitrary paths are intended behavior of a local CLI.
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if lower := strings.ToLower(p); strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	members := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return members, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || !wantMember(hdr.Name) {
+			continue
+		}
+		// #nosec G110 -- archives are local inputs the user chose to open.
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", hdr.Name, err)
+		}
+		members[path.Clean(hdr.Name)] = b
+	}
+}
+
+// wantMember reports whether an archive member is a supported, non-hidden
+// data file.
+func wantMember(name string) bool {
+	for _, part := range strings.Split(path.Clean(name), "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return false
+		}
+	}
+	_, ok := format.ForFile(name)
+	return ok
+}
+
+// commonDir returns the top-level directory, with its trailing slash, shared
+// by every member, or "" when they do not all share one.
+func commonDir(members map[string][]byte) string {
+	var dir string
+	for name := range members {
+		top, _, ok := strings.Cut(name, "/")
+		if !ok || (dir != "" && top != dir) {
+			return ""
+		}
+		dir = top
+	}
+	return dir + "/"
+}
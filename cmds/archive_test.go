@@ -0,0 +1,173 @@
+package cmds
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/crosleyzack/wndr/pkg/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// member is a file stored in a test archive.
+type member struct {
+	name, content string
+}
+
+func writeZip(t *testing.T, p string, members ...member) {
+	t.Helper()
+	f, err := os.Create(p)
+	require.NoError(t, err)
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for _, m := range members {
+		fw, err := w.Create(m.name)
+		require.NoError(t, err)
+		_, err = fw.Write([]byte(m.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+}
+
+func writeTarGz(t *testing.T, p string, members ...member) {
+	t.Helper()
+	f, err := os.Create(p)
+	require.NoError(t, err)
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	w := tar.NewWriter(gz)
+	for _, m := range members {
+		require.NoError(t, w.WriteHeader(&tar.Header{Name: m.name, Mode: 0o600, Size: int64(len(m.content)), Typeflag: tar.TypeReg}))
+		_, err = w.Write([]byte(m.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, gz.Close())
+}
+
+func TestReadArchive(t *testing.T) {
+	dir := t.TempDir()
+	members := []member{
+		{"bundle-1/status.json", `{"ok": "yes"}`},
+		{"bundle-1/conf/app.yaml", "name: api\n"},
+		{"bundle-1/notes.txt", "ignored"},
+		{"bundle-1/.hidden.json", `{"ignored": "yes"}`},
+	}
+	want := map[string]any{
+		"status.json": map[string]any{"ok": "yes"},
+		"conf":        map[string]any{"app.yaml": map[string]any{"name": "api"}},
+	}
+	for _, name := range []string{"b.zip", "b.tar.gz", "b.tgz"} {
+		t.Run(name, func(t *testing.T) {
+			p := filepath.Join(dir, name)
+			if filepath.Ext(name) == ".zip" {
+				writeZip(t, p, members...)
+			} else {
+				writeTarGz(t, p, members...)
+			}
+			got, err := readArchive(p, nil)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestReadArchiveErrors(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.zip")
+	writeZip(t, empty, member{"readme.md", "# hi"})
+	_, err := readArchive(empty, nil)
+	assert.Error(t, err)
+
+	broken := filepath.Join(dir, "broken.zip")
+	writeZip(t, broken, member{"a.json", "{"})
+	_, err = readArchive(broken, nil)
+	assert.ErrorContains(t, err, "a.json")
+
+	notZip := filepath.Join(dir, "fake.zip")
+	require.NoError(t, os.WriteFile(notZip, []byte("nope"), 0o600))
+	_, err = readArchive(notZip, nil)
+	assert.Error(t, err)
+}
+
+func TestGatherInputsArchive(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "b.zip")
+	writeZip(t, p, member{"a.json", `{"k": "v"}`}, member{"b/c.toml", `x = "y"`})
+	devNull, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	t.Cleanup(func() { devNull.Close() })
+
+	got, err := gatherInputs(nil, []string{p}, devNull)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Nil(t, got[0].data)
+	assert.Equal(t, map[string]any{
+		"a.json": map[string]any{"k": "v"},
+		"b":      map[string]any{"c.toml": map[string]any{"x": "y"}},
+	}, got[0].tree)
+}
+
+func TestArchiveDuplicateKeys(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "b.zip")
+	writeZip(t, p, member{"conf/a.yaml", "x: 1\nx: 2\n"}, member{"b.json", `{"k": "v"}`})
+
+	d := &duplicateKeys{}
+	got, err := readArchive(p, d)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"x": uint64(2)}, got["conf"].(map[string]any)["a.yaml"], "the last value is kept")
+	assert.Equal(t, []tui.Warning{{
+		Path:    nodes.Path{{Key: "conf"}, {Key: "a.yaml"}, {Key: "x"}},
+		Line:    2,
+		Message: "duplicate key in conf/a.yaml in " + p + "; the value on line 1 is dropped",
+	}}, d.warnings())
+
+	cmd := New()
+	cmd.SetArgs([]string{"--strict", "-o", "json", "-f", p})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	assert.ErrorContains(t, cmd.Execute(), "duplicate keys in conf/a.yaml in "+p+": x on lines 1, 2")
+}
+
+func TestDiffArchives(t *testing.T) {
+	dir := t.TempDir()
+	before := filepath.Join(dir, "before.tar.gz")
+	after := filepath.Join(dir, "after.zip")
+	writeTarGz(t, before,
+		member{"bundle-1/same.json", `{"v": "1"}`},
+		member{"bundle-1/changed.yaml", "v: old\n"},
+		member{"bundle-1/removed.json", `{"v": "gone"}`},
+	)
+	writeZip(t, after,
+		member{"bundle-2/same.json", `{"v": "1"}`},
+		member{"bundle-2/changed.yaml", "v: new\n"},
+	)
+
+	cmd := NewDiffCmd()
+	cmd.SetArgs([]string{"-o", "json", "-f", before, "-f", after, "-k", "a", "-k", "b"})
+	out := captureStdout(func() {
+		require.NoError(t, cmd.Execute())
+	})
+	var got map[string]any
+	require.NoError(t, json.Unmarshal([]byte(out), &got))
+	assert.NotContains(t, got, "same.json")
+	assert.Equal(t, map[string]any{"v": map[string]any{"a": "old", "b": "new"}}, got["changed.yaml"])
+	assert.Equal(t, map[string]any{"a": map[string]any{"v": "gone"}, "b": "nil"}, got["removed.json"])
+}
+
+func TestArchiveWithProtobuf(t *testing.T) {
+	// members are parsed by their extension, not as the protobuf inputs are
+	p := filepath.Join(t.TempDir(), "b.zip")
+	writeZip(t, p, member{"conf/a.json", `{"k": "v"}`}, member{"b.yaml", "x: 1\n"})
+	cmd := New()
+	cmd.SetArgs([]string{"--protobuf", "-o", "json", "-f", p})
+	out := captureStdout(func() {
+		require.NoError(t, cmd.Execute())
+	})
+	assert.JSONEq(t, `{"conf": {"a.json": {"k": "v"}}, "b.yaml": {"x": 1}}`, out)
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -13,6 +14,7 @@ import (
 	"github.com/crosleyzack/wndr/pkg/keys"
 	"github.com/crosleyzack/wndr/pkg/modules/tree"
 	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/crosleyzack/wndr/pkg/nodes/jq"
 	"github.com/crosleyzack/wndr/pkg/styles"
 	"github.com/crosleyzack/wndr/pkg/tui"
 	"github.com/spf13/cobra"
@@ -25,12 +27,21 @@ func New() *cobra.Command {
 	var layers uint
 	var nodeValueRepr string
 	var file string
+	var dir string
+	var command string
+	var interval time.Duration
+	var watch bool
+	var filter string
+	var output string
+	var jsonnet jsonnetFlags
+	var protobuf protobufFlags
+	var httpOpts httpFlags
 	cmd := &cobra.Command{
-		Use:     "wndr [-x <layers>] [-f <file> | data]",
+		Use:     "wndr [-x <layers>] [-q <filter>] [-o json|yaml|toml] [-f <file> | -d <dir> | --exec <cmd> | data]",
 		Version: version,
 		Short:   "Explore a tree data file with a TUI graphical interface",
-		Long:    "Takes in a tree data file (JSON, YAML, TOML) either via flag parameter, first argument, or stdin and produces TUI navigable tree to view and explore the data",
-		Example: "wndr -x 2 -f foo.json",
+		Long:    "Takes in a tree data file (JSON, YAML, TOML, Jsonnet) either via flag parameter, first argument, or stdin and produces TUI navigable tree to view and explore the data",
+		Example: "wndr -x 2 -f foo.json\nwndr -q '.items[] | select(.status == \"Running\")' -f pods.json",
 		Args:    cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) (err error) {
 			// get config
@@ -38,24 +49,154 @@ func New() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("failed to parse config: %w", err)
 			}
-			// gather every operand from files, arguments and a piped stdin.
-			inputs, err := gatherInputs(args, []string{file}, os.Stdin)
+			redactor, err := newRedactor(cmd, c)
 			if err != nil {
-				return fmt.Errorf("failed to get data: %w", err)
+				return fmt.Errorf("failed to parse redaction rules: %w", err)
 			}
-			if len(inputs) != 1 {
-				return fmt.Errorf("wndr needs exactly one input, got %d", len(inputs))
+			parse, err := protobuf.parser()
+			if err != nil {
+				return fmt.Errorf("failed to parse protobuf flags: %w", err)
 			}
-
-			// get data as map[string]any
-			m, err := format.Parse(inputs[0])
+			dups := newDuplicateKeys(cmd)
+			parse = dups.parser(parse)
+			jsonnetOpt, err := jsonnet.inputOption()
+			if err != nil {
+				return fmt.Errorf("failed to parse jsonnet flags: %w", err)
+			}
+			httpOpt, err := httpOpts.inputOption()
+			if err != nil {
+				return fmt.Errorf("failed to parse http flags: %w", err)
+			}
+			if interval > 0 && command == "" && !watch {
+				return fmt.Errorf("--interval needs --exec or --watch")
+			}
+			if watch && (file == "" || isURL(file)) {
+				return fmt.Errorf("--watch needs a local file given with -f")
+			}
+			var jqFilter *jq.Filter
+			if filter != "" {
+				if jqFilter, err = jq.Compile(filter); err != nil {
+					return err
+				}
+			}
+			// read gets the data from whichever input was given, and sets
+			// array when it is a document whose top-level value is an array
+			var read func() (map[string]any, error)
+			var array bool
+			opts := []tui.Option{tui.WithRedaction(redactor), tui.WithWarnings(func() []tui.Warning {
+				return dups.warnings()
+			})}
+			switch {
+			case command != "":
+				if file != "" || dir != "" || len(args) > 0 {
+					return fmt.Errorf("--exec cannot be combined with other inputs")
+				}
+				read = func() (map[string]any, error) {
+					dups.reset()
+					out, err := runCommand(command)
+					if err != nil {
+						return nil, err
+					}
+					m, err := parse(out)
+					if err != nil {
+						return nil, fmt.Errorf("failed to parse command output: %w", err)
+					}
+					array = isArrayDocument(out)
+					return m, nil
+				}
+			case dir != "":
+				if file != "" || len(args) > 0 {
+					return fmt.Errorf("--dir cannot be combined with other inputs")
+				}
+				read = func() (map[string]any, error) {
+					dups.reset()
+					// every supported file under dir becomes one tree
+					m, err := gatherDir(dir, jsonnetOpt, withDuplicates(dups))
+					if err != nil {
+						return nil, fmt.Errorf("failed to read directory: %w", err)
+					}
+					return m, nil
+				}
+			default:
+				read = func() (map[string]any, error) {
+					dups.reset()
+					// gather every operand from files, arguments and a piped stdin.
+					inputs, err := gatherInputs(args, []string{file}, os.Stdin, jsonnetOpt, httpOpt, withDuplicates(dups))
+					if err != nil {
+						return nil, fmt.Errorf("failed to get data: %w", err)
+					}
+					if len(inputs) != 1 {
+						return nil, fmt.Errorf("wndr needs exactly one input, got %d", len(inputs))
+					}
+					// get data as map[string]any
+					m, err := inputs[0].parse(parse)
+					if err != nil {
+						return nil, fmt.Errorf("failed to parse data: %w", err)
+					}
+					array = isArrayDocument(inputs[0].data)
+					return m, nil
+				}
+			}
+			if output != "" {
+				// print the data, or the filter results, instead of showing them
+				m, err := read()
+				if err != nil {
+					return err
+				}
+				results := []any{unwrapArray(m, array)}
+				if jqFilter != nil {
+					if results, err = runFilter(jqFilter, m, array); err != nil {
+						return err
+					}
+				}
+				for i, r := range results {
+					results[i] = redactor.Data(nil, r)
+				}
+				printWarnings(os.Stderr, dups.warnings(), dups.syntax)
+				return printResults(os.Stdout, results, output)
+			}
+			load := func() (*nodes.Node, error) {
+				m, err := read()
+				if err != nil {
+					return nil, err
+				}
+				if jqFilter != nil {
+					results, err := runFilter(jqFilter, m, array)
+					if err != nil {
+						return nil, err
+					}
+					m = resultsToMap(results)
+				}
+				// parse into node tree; the tree view summarizes nodes
+				// as they are shown
+				return nodes.New(m, layers, nodes.EmptyRepr), nil
+			}
+			if command != "" {
+				// re-run the command on every refresh
+				opts = append(opts, tui.WithRefresh(load, interval))
+			}
+			if watch {
+				// poll the file and reload whenever its content changes
+				w, err := newFileWatcher(file)
+				if err != nil {
+					return fmt.Errorf("failed to watch %s: %w", file, err)
+				}
+				if interval <= 0 {
+					interval = defaultWatchInterval
+				}
+				opts = append(opts, tui.WithRefresh(load, 0), tui.WithWatch(w.Changed, interval))
+			}
+			if file != "" && len(args) == 0 && jqFilter == nil && !isURL(file) && !isArchive(file) && !jsonnet.all && !protobuf.enabled && protobuf.descriptor == "" {
+				// edits can only be written back to a single local data file
+				if save, ok := fileSaver(file); ok {
+					opts = append(opts, tui.WithSave(file, save))
+				}
+			}
+			n, err := load()
 			if err != nil {
-				return fmt.Errorf("failed to parse data: %w", err)
+				return err
 			}
-			// parse into node tree
-			n := nodes.New(m, layers, nodes.GetRepr(nodeValueRepr))
-			// parse configs
-			if err = renderTree(c, n); err != nil {
+			if err = renderTree(c, n, nodeValueRepr, nil, opts...); err != nil {
 				return fmt.Errorf("failed to render tree: %w", err)
 			}
 			return nil
@@ -63,45 +204,178 @@ func New() *cobra.Command {
 	}
 	cmd.Flags().UintVarP(&layers, "expand", "x", 0, "number of layers to expand by default")
 	cmd.Flags().StringVarP(&file, "file", "f", "", "file to read data from")
+	cmd.Flags().StringVarP(&dir, "dir", "d", "", "directory of data files to read as a single tree")
+	cmd.Flags().StringVar(&command, "exec", "", "shell command whose output is shown; re-run with the refresh key")
+	cmd.Flags().DurationVar(&interval, "interval", 0, "re-run the --exec command, or poll the --watch file, at this interval")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "reload the -f file whenever it changes")
+	cmd.Flags().StringVarP(&filter, "query", "q", "", "jq filter to apply to the data before it is shown")
+	cmd.Flags().StringVarP(&output, "out", "o", "", "print the data, after any -q filter, as json, yaml or toml instead of showing it")
+	jsonnet.register(cmd.Flags())
+	protobuf.register(cmd.Flags())
+	httpOpts.register(cmd.Flags())
+	cmd.PersistentFlags().Bool(revealFlag, false, "show sensitive values instead of redacting them as configured")
+	cmd.PersistentFlags().Bool(strictFlag, false, "fail on JSON and YAML objects with duplicate keys rather than warning of them")
 	cmd.Flags().StringVar(&nodeValueRepr, "format", nodes.LeafValuesOnlyRepr, "Format to use to represent an expandable node value. Available formats: "+strings.Join(nodes.GetAvailableFormats(), "|"))
 	cmd.AddCommand(NewDiffCmd())
+	cmd.AddCommand(NewQueryCmd())
+	cmd.AddCommand(NewGetCmd())
+	cmd.AddCommand(NewSetCmd())
+	cmd.AddCommand(NewDelCmd())
+	cmd.AddCommand(NewPatchCmd())
+	cmd.AddCommand(NewMergeCmd())
 	return cmd
 }
 
+type inputConf struct {
+	// Jsonnet is the evaluation settings for Jsonnet inputs
+	Jsonnet []format.JsonnetOption
+	// AllJsonnet evaluates arguments and stdin as Jsonnet too, rather than
+	// only files with a Jsonnet extension
+	AllJsonnet bool
+	// HTTP is the request settings for URL inputs
+	HTTP httpConf
+	// Duplicates finds the duplicate keys in the files of a directory or
+	// the members of an archive; nil to not look for them
+	Duplicates *duplicateKeys
+}
+
+type inputOption func(*inputConf)
+
+// withJsonnet sets the options used to evaluate Jsonnet inputs. When all is
+// set, positional arguments and stdin are evaluated as Jsonnet as well.
+func withJsonnet(all bool, opts ...format.JsonnetOption) inputOption {
+	return func(c *inputConf) {
+		c.AllJsonnet = all
+		c.Jsonnet = opts
+	}
+}
+
+// withDuplicates finds the duplicate keys in the files of a directory, and the
+// members of an archive, with d.
+func withDuplicates(d *duplicateKeys) inputOption {
+	return func(c *inputConf) {
+		c.Duplicates = d
+	}
+}
+
+func newInputConf(opts ...inputOption) *inputConf {
+	conf := &inputConf{}
+	for _, opt := range opts {
+		opt(conf)
+	}
+	return conf
+}
+
+// input is one operand gathered by gatherInputs: the data read, or the tree
+// already parsed from an archive's members.
+type input struct {
+	data []byte
+	// tree is set, and data nil, for an archive, whose members are each
+	// parsed in the format of their extension
+	tree map[string]any
+}
+
+// parse returns the tree of the input, parsing its data with parse unless it
+// was already parsed.
+func (in input) parse(parse format.Format) (map[string]any, error) {
+	if in.tree != nil {
+		return in.tree, nil
+	}
+	return parse(in.data)
+}
+
 // gatherInputs gathers operands in a stable order: one entry per file (in the
 // order given), then one per positional argument treated as inline data, then
 // stdin when it is piped and not empty. stdin is a parameter so callers can test
-// it without touching the real os.Stdin.
-func gatherInputs(args, files []string, stdin *os.File) ([][]byte, error) {
-	var out [][]byte
+// it without touching the real os.Stdin. Files and arguments that are http(s)
+// URLs are fetched (see fetchURL). Jsonnet files are evaluated and contribute
+// their JSON output; archives contribute the tree of their members (see
+// readArchive).
+func gatherInputs(args, files []string, stdin *os.File, opts ...inputOption) ([]input, error) {
+	conf := newInputConf(opts...)
+	var out []input
 	for _, f := range files {
 		// an unset -f flag arrives as an empty string; skip it so stdin and
 		// positional arguments can still supply the data.
 		if f == "" {
 			continue
 		}
-		// #nosec G304 -- arbitrary paths are intended behavior of a local CLI.
-		b, err := os.ReadFile(f)
+		if isURL(f) {
+			b, err := fetchURL(f, conf.HTTP)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, input{data: b})
+			continue
+		}
+		if isArchive(f) {
+			// an archive contributes one document holding every member
+			m, err := readArchive(f, conf.Duplicates)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, input{tree: m})
+			continue
+		}
+		if conf.AllJsonnet || format.IsJsonnetFile(f) {
+			b, err := format.EvalJsonnetFile(f, conf.Jsonnet...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate %s: %w", f, err)
+			}
+			out = append(out, input{data: b})
+			continue
+		}
+		b, err := readFile(f)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read file %s: %w", f, err)
+			return nil, err
 		}
-		out = append(out, b)
+		out = append(out, input{data: b})
 	}
-	for _, a := range args {
-		out = append(out, []byte(a))
+	for i, a := range args {
+		if isURL(a) {
+			b, err := fetchURL(a, conf.HTTP)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, input{data: b})
+			continue
+		}
+		if conf.AllJsonnet {
+			b, err := format.EvalJsonnet(fmt.Sprintf("<arg %d>", i+1), []byte(a), conf.Jsonnet...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate argument %d: %w", i+1, err)
+			}
+			out = append(out, input{data: b})
+			continue
+		}
+		out = append(out, input{data: []byte(a)})
 	}
 	if piped(stdin) {
 		b, err := io.ReadAll(stdin)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read from pipe: %w", err)
 		}
+		if len(b) > 0 && conf.AllJsonnet {
+			if b, err = format.EvalJsonnet("<stdin>", b, conf.Jsonnet...); err != nil {
+				return nil, fmt.Errorf("failed to evaluate stdin: %w", err)
+			}
+		}
 		if len(b) > 0 {
-			out = append(out, b)
+			out = append(out, input{data: b})
 		}
 	}
 	return out, nil
 }
 
+func readFile(path string) ([]byte, error) {
+	// #nosec G304 -- arbitrary paths are intended behavior of a local CLI.
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return b, nil
+}
+
 // piped reports whether f is a pipe or redirect rather than an interactive
 // terminal, meaning it carries data to read.
 func piped(f *os.File) bool {
@@ -109,8 +383,10 @@ func piped(f *os.File) bool {
 	return err == nil && st.Mode()&os.ModeCharDevice == 0
 }
 
-// renderTree takes in a config and a node tree and renders the TUI tree interface
-func renderTree(conf *tui.Config, n *nodes.Node) error {
+// renderTree takes in a config and a node tree and renders the TUI tree interface.
+// Objects and arrays are summarized in the named format, with the summary
+// templates in conf, or with repr until the format is changed when it is set.
+func renderTree(conf *tui.Config, n *nodes.Node, format string, repr nodes.ReprNode, opts ...tui.Option) error {
 	keyMap := keys.NewKeyMap(&conf.KeyConfig)
 	style := styles.NewStyle(&conf.StyleConfig)
 	// populate KeyBasedStyles before creating the model so the copy it receives is complete
@@ -119,8 +395,13 @@ func renderTree(conf *tui.Config, n *nodes.Node) error {
 			style.KeyBasedStyles[key] = lipgloss.NewStyle().Background(lipgloss.Color(child.Value))
 		}
 	}
-	format := tree.NewFormat(&conf.TreeConfig)
-	model, err := tui.New(format, keyMap, style, n)
+	rules, err := conf.SummaryRules()
+	if err != nil {
+		return fmt.Errorf("failed to parse summary rules: %w", err)
+	}
+	treeFormat := tree.NewFormat(&conf.TreeConfig)
+	treeFormat.Format, treeFormat.Summaries, treeFormat.Repr = format, rules, repr
+	model, err := tui.New(treeFormat, keyMap, style, n, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to create TUI model: %w", err)
 	}
@@ -18,12 +18,15 @@ func NewDiffCmd() *cobra.Command {
 	var files, keys []string
 	var output string
 	var nilValue string
+	var jsonnet jsonnetFlags
+	var protobuf protobufFlags
+	var httpOpts httpFlags
 	cmd := &cobra.Command{
 		Use:     "diff [-f <file>]... [data]...",
 		Aliases: []string{"d"},
 		Version: version,
 		Short:   "Diff two or more tree data files with a TUI graphical interface",
-		Long:    "Takes in two or more tree data sources (JSON, YAML, TOML) via file flags, positional arguments, or a piped stdin and compares them.",
+		Long:    "Takes in two or more tree data sources (JSON, YAML, TOML, Jsonnet) via file flags, positional arguments, or a piped stdin and compares them.",
 		Example: "wndr diff -f foo.json -f bar.json",
 		Args:    cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) (err error) {
@@ -32,8 +35,21 @@ func NewDiffCmd() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("failed to parse config: %w", err)
 			}
+			parse, err := protobuf.parser()
+			if err != nil {
+				return fmt.Errorf("failed to parse protobuf flags: %w", err)
+			}
+			dups := newDuplicateKeys(cmd)
+			jsonnetOpt, err := jsonnet.inputOption()
+			if err != nil {
+				return fmt.Errorf("failed to parse jsonnet flags: %w", err)
+			}
+			httpOpt, err := httpOpts.inputOption()
+			if err != nil {
+				return fmt.Errorf("failed to parse http flags: %w", err)
+			}
 			// gather every operand from files, arguments and a piped stdin.
-			inputs, err := gatherInputs(args, files, os.Stdin)
+			inputs, err := gatherInputs(args, files, os.Stdin, jsonnetOpt, httpOpt, withDuplicates(dups))
 			if err != nil {
 				return fmt.Errorf("failed to get data: %w", err)
 			}
@@ -52,13 +68,19 @@ func NewDiffCmd() *cobra.Command {
 			// parse each input into its own tree.
 			trees := make([]*nodes.Node, len(inputs))
 			for i, in := range inputs {
-				m, err := format.Parse(in)
+				m, err := in.parse(dups.parserFor(keys[i], nil, parse))
 				if err != nil {
 					return fmt.Errorf("failed to parse input %d: %w", i+1, err)
 				}
 				trees[i] = nodes.New(m, 0, nodes.EmptyRepr)
 			}
 
+			redactor, err := newRedactor(cmd, c)
+			if err != nil {
+				return fmt.Errorf("failed to parse redaction rules: %w", err)
+			}
+			// paths are matched as they are in the inputs
+			redactor = redactor.Ignoring(keys...)
 			diffTree, err := diff.Diff(
 				trees,
 				diff.WithKeys(keys...),
@@ -69,6 +91,10 @@ func NewDiffCmd() *cobra.Command {
 			}
 
 			// output the diff tree in the requested format, or render the TUI.
+			if output != "" {
+				redactor.Apply(diffTree, nil)
+				printWarnings(os.Stderr, dups.warnings(), dups.syntax)
+			}
 			switch output {
 			case "json":
 				err := printOutput(diffTree, format.AsJson)
@@ -86,7 +112,7 @@ func NewDiffCmd() *cobra.Command {
 					return fmt.Errorf("failed to print output: %w", err)
 				}
 			default:
-				if err := renderTree(c, diffTree); err != nil {
+				if err := renderTree(c, diffTree, "", nodes.LeafKeyAndValues, tui.WithRedaction(redactor), tui.WithWarnings(dups.warnings)); err != nil {
 					return fmt.Errorf("failed to render tree: %w", err)
 				}
 			}
@@ -98,6 +124,9 @@ func NewDiffCmd() *cobra.Command {
 
 	cmd.Flags().StringSliceVarP(&keys, "key", "k", nil, "key to label each input in the diff (one per input, defaults to _f1.._fN)")
 	cmd.Flags().StringVar(&nilValue, "nilValue", "nil", "what to use as value for missing nodes in one tree")
+	jsonnet.register(cmd.Flags())
+	protobuf.register(cmd.Flags())
+	httpOpts.register(cmd.Flags())
 	return cmd
 }
 
@@ -81,7 +81,7 @@ func TestGatherInputs(t *testing.T) {
 			}
 			got, err := gatherInputs(tt.args, tt.files, stdin)
 			require.NoError(t, err)
-			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want, inputData(got))
 		})
 	}
 }
@@ -96,7 +96,16 @@ func TestGatherInputsEmptyStdinSkipped(t *testing.T) {
 
 	got, err := gatherInputs([]string{"x"}, nil, f)
 	require.NoError(t, err)
-	assert.Equal(t, [][]byte{[]byte("x")}, got)
+	assert.Equal(t, [][]byte{[]byte("x")}, inputData(got))
+}
+
+// inputData returns the data of each input.
+func inputData(inputs []input) [][]byte {
+	out := make([][]byte, len(inputs))
+	for i, in := range inputs {
+		out[i] = in.data
+	}
+	return out
 }
 
 func TestGatherInputsFileError(t *testing.T) {
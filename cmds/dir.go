@@ -0,0 +1,106 @@
+package cmds
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+)
+
+// gatherDir walks the directory root and parses every supported file into one
+// map. Each directory and file name becomes a key on the path down to the
+// file's contents, so envs/prod/api.yaml is found under
+// ["envs", "prod", "api.yaml"]. Hidden files and directories are skipped, as
+// are files whose extension is not a supported format. Jsonnet libraries
+// (.libsonnet) are only evaluated when imported by a .jsonnet file.
+func gatherDir(root string, opts ...inputOption) (map[string]any, error) {
+	conf := newInputConf(opts...)
+	out := map[string]any{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path of %s: %w", path, err)
+		}
+		keys := strings.Split(filepath.ToSlash(rel), "/")
+		m, ok, err := parseFile(path, keys, conf)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		nestUnder(out, keys, m)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no supported files found in %s", root)
+	}
+	return out, nil
+}
+
+// parseFile parses the file at path, found under keys in the tree, according
+// to its extension. ok is false when the file is not a supported format and
+// should be ignored.
+func parseFile(path string, keys []string, conf *inputConf) (m map[string]any, ok bool, err error) {
+	switch {
+	case strings.EqualFold(filepath.Ext(path), ".libsonnet"):
+		return nil, false, nil
+	case format.IsJsonnetFile(path):
+		b, err := format.EvalJsonnetFile(path, conf.Jsonnet...)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to evaluate %s: %w", path, err)
+		}
+		m, err = format.ParseJson(b)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return m, true, nil
+	}
+	parse, ok := format.ForFile(path)
+	if !ok {
+		return nil, false, nil
+	}
+	b, err := readFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if conf.Duplicates != nil {
+		parse = conf.Duplicates.parserFor(path, keys, parse)
+	}
+	m, err = parse(b)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return m, true, nil
+}
+
+// nestUnder stores v in m under the nested keys of path, creating the
+// intermediate maps as needed.
+func nestUnder(m map[string]any, path []string, v map[string]any) {
+	for _, part := range path[:len(path)-1] {
+		child, ok := m[part].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			m[part] = child
+		}
+		m = child
+	}
+	m[path[len(path)-1]] = v
+}
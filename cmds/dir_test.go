@@ -0,0 +1,59 @@
+package cmds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatherDir(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		p := filepath.Join(root, rel)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o700))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o600))
+	}
+	write("envs/prod/api.yaml", "replicas: two\n")
+	write("envs/prod/db.toml", "host = \"db.prod\"\n")
+	write("envs/dev/api.json", `{"replicas": "one"}`)
+	write("shared.jsonnet", `local l = import "lib.libsonnet"; { region: l.region }`)
+	write("lib.libsonnet", `{ region: "eu" }`)
+	write("README.md", "# not data")
+	write(".git/config.json", `{"ignored": "yes"}`)
+
+	got, err := gatherDir(root)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"envs": map[string]any{
+			"prod": map[string]any{
+				"api.yaml": map[string]any{"replicas": "two"},
+				"db.toml":  map[string]any{"host": "db.prod"},
+			},
+			"dev": map[string]any{
+				"api.json": map[string]any{"replicas": "one"},
+			},
+		},
+		"shared.jsonnet": map[string]any{"region": "eu"},
+	}, got)
+}
+
+func TestGatherDirErrors(t *testing.T) {
+	_, err := gatherDir(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+
+	// a directory without any supported file has nothing to show.
+	empty := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(empty, "notes.txt"), []byte("hi"), 0o600))
+	_, err = gatherDir(empty)
+	assert.Error(t, err)
+
+	// a broken file names its path.
+	broken := t.TempDir()
+	bad := filepath.Join(broken, "bad.json")
+	require.NoError(t, os.WriteFile(bad, []byte("{"), 0o600))
+	_, err = gatherDir(broken)
+	assert.ErrorContains(t, err, bad)
+}
@@ -0,0 +1,150 @@
+package cmds
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/crosleyzack/wndr/pkg/tui"
+	"github.com/spf13/cobra"
+)
+
+// strictFlag names the flag that fails on duplicate keys rather than warning
+// of them.
+const strictFlag = "strict"
+
+// duplicateKeys finds the keys written more than once in the documents parsed,
+// of which only the last value is kept.
+type duplicateKeys struct {
+	strict bool
+	// syntax is the syntax paths are written in
+	syntax nodes.PathSyntax
+	mu     sync.Mutex
+	// docs are the duplicates in each document parsed since the last reset
+	docs []documentDuplicates
+}
+
+// documentDuplicates are the duplicate keys in one document, named by source,
+// whose data is found under prefix in the tree.
+type documentDuplicates struct {
+	source string
+	prefix []string
+	dups   []format.Duplicate
+}
+
+// newDuplicateKeys returns a duplicateKeys failing on duplicates when the
+// --strict flag is set, writing paths in the syntax given by --path-syntax
+// for commands that have it and in DotSyntax otherwise.
+func newDuplicateKeys(cmd *cobra.Command) *duplicateKeys {
+	strict, _ := cmd.Flags().GetBool(strictFlag)
+	d := &duplicateKeys{strict: strict}
+	if name, err := cmd.Flags().GetString("path-syntax"); err == nil {
+		// an unknown syntax is reported by the command itself
+		d.syntax, _ = nodes.ParsePathSyntax(name)
+	}
+	return d
+}
+
+// parser wraps parse to find the duplicate keys in each document it parses,
+// failing on them with --strict.
+func (d *duplicateKeys) parser(parse format.Format) format.Format {
+	return d.parserFor("", nil, parse)
+}
+
+// parserFor is parser for documents from source, named in the warnings and
+// errors when set, whose data is placed under prefix in the tree.
+func (d *duplicateKeys) parserFor(source string, prefix []string, parse format.Format) format.Format {
+	return func(data []byte) (map[string]any, error) {
+		m, err := parse(data)
+		if err != nil {
+			return nil, err
+		}
+		dups := format.DuplicateKeys(data)
+		if len(dups) == 0 {
+			return m, nil
+		}
+		if d.strict {
+			found := make([]string, len(dups))
+			for i, dup := range dups {
+				found[i] = dup.Format(d.syntax)
+			}
+			if source != "" {
+				return nil, fmt.Errorf("duplicate keys in %s: %s", source, strings.Join(found, "; "))
+			}
+			return nil, fmt.Errorf("duplicate keys: %s", strings.Join(found, "; "))
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.docs = append(d.docs, documentDuplicates{source: source, prefix: prefix, dups: dups})
+		return m, nil
+	}
+}
+
+// reset forgets the duplicates found so far, before the inputs are read
+// again.
+func (d *duplicateKeys) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.docs = nil
+}
+
+// warnings returns the warnings about the duplicates in every document parsed
+// since the last reset, in the order they were parsed.
+func (d *duplicateKeys) warnings() []tui.Warning {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []tui.Warning
+	for _, doc := range d.docs {
+		prefix := make(nodes.Path, len(doc.prefix))
+		for i, key := range doc.prefix {
+			prefix[i] = nodes.PathElem{Key: key}
+		}
+		for _, w := range duplicateWarnings(doc.dups, doc.source) {
+			w.Path = append(slices.Clone(prefix), w.Path...)
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// duplicateWarnings returns the warnings about dups, naming source in each
+// when it is set.
+func duplicateWarnings(dups []format.Duplicate, source string) []tui.Warning {
+	out := make([]tui.Warning, len(dups))
+	for i, dup := range dups {
+		msg := "duplicate key"
+		if source != "" {
+			msg += " in " + source
+		}
+		// the value written last is the one kept
+		kept, dropped := dup.Lines[len(dup.Lines)-1], dup.Lines[:len(dup.Lines)-1]
+		if len(dropped) == 1 {
+			msg += fmt.Sprintf("; the value on line %d is dropped", dropped[0])
+		} else {
+			msg += fmt.Sprintf("; the values on lines %s are dropped", joinLines(dropped))
+		}
+		out[i] = tui.Warning{Path: dup.Path, Line: kept, Message: msg}
+	}
+	return out
+}
+
+// printWarnings writes warnings to w, one per line, with their paths written
+// in syntax s.
+func printWarnings(w io.Writer, warnings []tui.Warning, s nodes.PathSyntax) {
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "warning: line %d: %s: %s\n", warning.Line, warning.Path.Format(s), warning.Message)
+	}
+}
+
+// joinLines joins line numbers with commas.
+func joinLines(lines []int) string {
+	s := make([]string, len(lines))
+	for i, line := range lines {
+		s[i] = fmt.Sprint(line)
+	}
+	return strings.Join(s, ", ")
+}
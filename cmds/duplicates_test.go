@@ -0,0 +1,105 @@
+package cmds
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/crosleyzack/wndr/pkg/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicateKeys(t *testing.T) {
+	data := []byte("{\n\"a\": 1,\n\"a\": 2,\n\"b\": [{\"c\": 1, \"c\": 2, \"c\": 3}]\n}")
+	d := &duplicateKeys{}
+	m, err := d.parserFor("a.json", nil, format.Parse)(data)
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, m["a"], "the last value is kept")
+	_, err = d.parser(format.Parse)([]byte(`{"a": 1}`))
+	require.NoError(t, err)
+	_, err = d.parserFor("b.yaml", []string{"conf", "b.yaml"}, format.Parse)([]byte("x: 1\nx: 2\n"))
+	require.NoError(t, err)
+	warnings := d.warnings()
+	assert.Equal(t, []tui.Warning{
+		{Path: nodes.Path{{Key: "a"}}, Line: 3, Message: "duplicate key in a.json; the value on line 2 is dropped"},
+		{Path: nodes.Path{{Key: "b"}, {Key: "0", IsIndex: true}, {Key: "c"}}, Line: 4, Message: "duplicate key in a.json; the values on lines 4, 4 are dropped"},
+		{Path: nodes.Path{{Key: "conf"}, {Key: "b.yaml"}, {Key: "x"}}, Line: 2, Message: "duplicate key in b.yaml; the value on line 1 is dropped"},
+	}, warnings, "the duplicates of every document are kept")
+
+	var b bytes.Buffer
+	printWarnings(&b, warnings[:2], nodes.JSONPathSyntax)
+	assert.Equal(t, "warning: line 3: $.a: duplicate key in a.json; the value on line 2 is dropped\n"+
+		"warning: line 4: $.b[0].c: duplicate key in a.json; the values on lines 4, 4 are dropped\n", b.String())
+
+	d.reset()
+	assert.Empty(t, d.warnings())
+
+	d.strict = true
+	_, err = d.parser(format.Parse)(data)
+	assert.EqualError(t, err, "duplicate keys: a on lines 2, 3; b[0].c on lines 4, 4, 4")
+	_, err = d.parserFor("b.yaml", nil, format.Parse)([]byte("x: 1\nx: 2\n"))
+	assert.EqualError(t, err, "duplicate keys in b.yaml: x on lines 1, 2")
+	d.syntax = nodes.PointerSyntax
+	_, err = d.parser(format.Parse)(data)
+	assert.EqualError(t, err, "duplicate keys: /a on lines 2, 3; /b/0/c on lines 4, 4, 4")
+}
+
+func TestStrict(t *testing.T) {
+	cmd := New()
+	cmd.SetArgs([]string{"--strict", "-o", "json", `{"a": 1, "a": 2}`})
+	assert.ErrorContains(t, cmd.Execute(), "duplicate keys: a on lines 1, 1")
+
+	cmd = New()
+	cmd.SetArgs([]string{"-o", "json", `{"a": 1, "a": 2}`})
+	got := captureStdout(func() {
+		require.NoError(t, cmd.Execute())
+	})
+	assert.Equal(t, `{"a":2}`+"\n", got)
+
+	cmd = New()
+	cmd.SetArgs([]string{"-o", "json", "a: 1\nb: 2\na: 3\n"})
+	got = captureStdout(func() {
+		require.NoError(t, cmd.Execute())
+	})
+	assert.JSONEq(t, `{"a": 3, "b": 2}`, got, "YAML keeps the last value too")
+
+	cmd = New()
+	cmd.SetArgs([]string{"--strict", "-o", "json", "a: 1\nb: 2\na: 3\n"})
+	assert.ErrorContains(t, cmd.Execute(), "duplicate keys: a on lines 1, 3")
+}
+
+func TestStrictSubcommands(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("a: 1\na: 2\n"), 0o644))
+	for _, args := range [][]string{
+		{"--strict", "get", "a", "-f", file},
+		{"--strict", "set", "a", "3", "-f", file},
+		{"--strict", "del", "a", "-f", file},
+		{"--strict", "-d", dir, "-o", "json"},
+	} {
+		cmd := New()
+		cmd.SetArgs(args)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		assert.ErrorContains(t, cmd.Execute(), "duplicate keys", args)
+	}
+
+	// paths are written in the syntax of --path-syntax
+	cmd := New()
+	cmd.SetArgs([]string{"--strict", "get", "--path-syntax", "jsonpath", "$.a", "-f", file})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	assert.ErrorContains(t, cmd.Execute(), ": $.a on lines 1, 2")
+
+	cmd = New()
+	cmd.SetArgs([]string{"get", "a", "-f", file})
+	got := captureStdout(func() {
+		require.NoError(t, cmd.Execute())
+	})
+	assert.Equal(t, "2\n", got)
+}
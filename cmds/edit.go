@@ -0,0 +1,247 @@
+package cmds
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/crosleyzack/wndr/pkg/redact"
+	"github.com/crosleyzack/wndr/pkg/tui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// docFlags are the flags shared by get, set and del.
+type docFlags struct {
+	file       string
+	pathSyntax string
+}
+
+func (f *docFlags) register(flags *pflag.FlagSet) {
+	flags.StringVarP(&f.file, "file", "f", "", "file to read data from")
+	flags.StringVar(&f.pathSyntax, "path-syntax", nodes.DotSyntax.String(), fmt.Sprintf("syntax of the path: %s", strings.Join(nodes.GetPathSyntaxes(), ", ")))
+}
+
+// document is the single input of get, set or del.
+type document struct {
+	src    []byte
+	format format.FormatType
+	root   *nodes.Node
+}
+
+// path parses text in the syntax given by --path-syntax.
+func (f *docFlags) path(text string) (nodes.Path, error) {
+	syntax, err := nodes.ParsePathSyntax(f.pathSyntax)
+	if err != nil {
+		return nil, err
+	}
+	return nodes.ParsePath(text, syntax)
+}
+
+// load reads the one document given by the -f flag, args or stdin. Its format
+// is taken from the file extension when there is one, and otherwise detected
+// from the data. Duplicate keys are warned of on stderr, or fail with the
+// --strict flag of cmd.
+func (f *docFlags) load(cmd *cobra.Command, args []string) (*document, error) {
+	inputs, err := gatherInputs(args, []string{f.file}, os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data: %w", err)
+	}
+	if len(inputs) != 1 {
+		return nil, fmt.Errorf("needs exactly one input, got %d", len(inputs))
+	}
+	src := inputs[0].data
+	if inputs[0].tree != nil {
+		// an archive is edited as the JSON of its members
+		if src, err = format.AsJson(inputs[0].tree); err != nil {
+			return nil, fmt.Errorf("failed to encode archive: %w", err)
+		}
+	}
+	doc := &document{src: src}
+	ft, ok := format.TypeForFile(f.file)
+	if !ok {
+		if ft, err = format.Detect(doc.src); err != nil {
+			return nil, fmt.Errorf("failed to parse data: %w", err)
+		}
+	}
+	doc.format = ft
+	dups := newDuplicateKeys(cmd)
+	m, err := dups.parserFor(f.file, nil, ft.Parser())(doc.src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse data: %w", err)
+	}
+	printWarnings(os.Stderr, dups.warnings(), dups.syntax)
+	doc.root = nodes.New(m, 0, nodes.EmptyRepr)
+	return doc, nil
+}
+
+// checkInPlace returns an error when the input cannot be written back in
+// place.
+func (f *docFlags) checkInPlace() error {
+	if f.file == "" || isURL(f.file) || isArchive(f.file) || format.IsJsonnetFile(f.file) {
+		return fmt.Errorf("--in-place needs a json, yaml or toml file given with -f")
+	}
+	return nil
+}
+
+// write replaces the -f file with b when inPlace is set, and otherwise prints
+// it.
+func (f *docFlags) write(b []byte, inPlace bool) error {
+	if inPlace {
+		return writeAtomic(f.file, b)
+	}
+	_, err := os.Stdout.Write(b)
+	return err
+}
+
+// NewGetCmd builds the `get` command, which prints the value at a path.
+func NewGetCmd() *cobra.Command {
+	var flags docFlags
+	var output string
+	cmd := &cobra.Command{
+		Use:     "get <path> [-f <file> | data]",
+		Version: version,
+		Short:   "Print the value at a path",
+		Long: `Prints the value at a path, such as spec.containers[0].image, in tree data (JSON, YAML, TOML) read via file flag, second argument, or stdin.
+
+A scalar is printed as its bare value. An object or array is printed in the format of the input, or the one given by --out. The command fails when nothing is at the path.`,
+		Example: "wndr get spec.replicas -f deploy.yaml",
+		Args:    cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := flags.path(args[0])
+			if err != nil {
+				return err
+			}
+			doc, err := flags.load(cmd, args[1:])
+			if err != nil {
+				return err
+			}
+			n := path.Resolve(doc.root)
+			if n == nil {
+				return fmt.Errorf("%w: %s", nodes.ErrNotFound, args[0])
+			}
+			c, err := tui.NewConfig()
+			if err != nil {
+				return fmt.Errorf("failed to parse config: %w", err)
+			}
+			redactor, err := newRedactor(cmd, c)
+			if err != nil {
+				return fmt.Errorf("failed to parse redaction rules: %w", err)
+			}
+			if nodes.IsLeaf(n) && !nodes.IsRoot(n) {
+				value := n.Value
+				if redactor.Hides(path.Keys(), value) {
+					value = redact.Mask
+				}
+				_, err := fmt.Fprintln(os.Stdout, value)
+				return err
+			}
+			orig, found := valueAt(decodeDocument(doc.src, doc.format), path)
+			value := redactor.Data(path.Keys(), typedValue(n, orig, found))
+			if output == "" {
+				output = []string{"json", "yaml", "toml"}[doc.format]
+				if _, ok := value.(map[string]any); !ok && doc.format == format.FormatToml {
+					output = "json"
+				}
+			}
+			return printResults(os.Stdout, []any{value}, output)
+		},
+	}
+	flags.register(cmd.Flags())
+	cmd.Flags().StringVarP(&output, "out", "o", "", "print objects and arrays as json, yaml or toml (defaults to the input format)")
+	return cmd
+}
+
+// NewSetCmd builds the `set` command, which sets the value at a path.
+func NewSetCmd() *cobra.Command {
+	return newEditCmd(&cobra.Command{
+		Use:   "set <path> <value> [-f <file> | data]",
+		Short: "Set the value at a path",
+		Long: `Sets the scalar at a path, such as spec.replicas, in tree data (JSON, YAML, TOML) read via file flag, third argument, or stdin, adding it when its parent exists but it does not. A new array element may only be added at the end.
+
+The value keeps the type of the one it replaces when it still fits, and is otherwise read as a boolean, number or string. The result is printed, or written back to the -f file with --in-place, changing only the value set. The command fails when the parent of the path does not exist.`,
+		Example: "wndr set spec.replicas 3 -f deploy.yaml -i",
+		Args:    cobra.RangeArgs(2, 3),
+	}, func(path nodes.Path, args []string) (nodes.Op, []string) {
+		return nodes.SetOp{Path: path, Value: args[1]}, args[2:]
+	})
+}
+
+// NewDelCmd builds the `del` command, which deletes the value at a path.
+func NewDelCmd() *cobra.Command {
+	return newEditCmd(&cobra.Command{
+		Use:     "del <path> [-f <file> | data]",
+		Aliases: []string{"delete"},
+		Short:   "Delete the value at a path",
+		Long: `Deletes the value at a path, such as spec.containers[1], and everything below it from tree data (JSON, YAML, TOML) read via file flag, second argument, or stdin. Later elements of an array move down to fill the gap.
+
+The result is printed, or written back to the -f file with --in-place. The command fails when nothing is at the path.`,
+		Example: "wndr del metadata.annotations -f deploy.yaml -i",
+		Args:    cobra.RangeArgs(1, 2),
+	}, func(path nodes.Path, args []string) (nodes.Op, []string) {
+		return nodes.DeleteOp{Path: path}, args[1:]
+	})
+}
+
+// newEditCmd completes cmd to apply the tree operation op builds from the path
+// and arguments, which also returns the arguments left over as inline data.
+// The changed document is printed or written back to its file.
+func newEditCmd(cmd *cobra.Command, op func(path nodes.Path, args []string) (nodes.Op, []string)) *cobra.Command {
+	var flags docFlags
+	var inPlace bool
+	cmd.Version = version
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if inPlace {
+			if err := flags.checkInPlace(); err != nil {
+				return err
+			}
+		}
+		path, err := flags.path(args[0])
+		if err != nil {
+			return err
+		}
+		o, data := op(path, args)
+		doc, err := flags.load(cmd, data)
+		if err != nil {
+			return err
+		}
+		if _, err := o.Apply(doc.root, nil); err != nil {
+			return fmt.Errorf("failed to %s: %w", o, err)
+		}
+		b, reencoded, err := encodeTree(doc.root, doc.src, doc.format)
+		if err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+		warnLayoutLost(os.Stderr, reencoded)
+		return flags.write(b, inPlace)
+	}
+	flags.register(cmd.Flags())
+	cmd.Flags().BoolVarP(&inPlace, "in-place", "i", false, "write the result back to the -f file instead of printing it")
+	return cmd
+}
+
+// valueAt returns the value at path in data decoded from a document, and
+// false when there is none.
+func valueAt(v any, path nodes.Path) (any, bool) {
+	for _, e := range path {
+		switch c := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = c[e.Key]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(e.Key)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, false
+			}
+			v = c[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
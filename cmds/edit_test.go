@@ -0,0 +1,148 @@
+package cmds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const editYaml = `# deployment
+spec:
+  replicas: 1 # scaled by hand
+  containers:
+    - name: app
+      image: app:1.2
+`
+
+func TestEditCmds(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  func() *cobra.Command
+		args []string
+		want string
+	}{
+		{
+			name: "get scalar",
+			cmd:  NewGetCmd,
+			args: []string{"spec.containers[0].image"},
+			want: "app:1.2\n",
+		},
+		{
+			name: "get object in input format",
+			cmd:  NewGetCmd,
+			args: []string{"spec.containers[0]"},
+			want: "image: app:1.2\nname: app\n",
+		},
+		{
+			name: "get as json with another syntax",
+			cmd:  NewGetCmd,
+			args: []string{"/spec", "--path-syntax", "pointer", "-o", "json"},
+			want: `{"containers":[{"image":"app:1.2","name":"app"}],"replicas":1}` + "\n",
+		},
+		{
+			name: "set keeps comments",
+			cmd:  NewSetCmd,
+			args: []string{"spec.replicas", "3"},
+			want: "# deployment\nspec:\n  replicas: 3 # scaled by hand\n  containers:\n    - name: app\n      image: app:1.2\n",
+		},
+		{
+			name: "set adds a key",
+			cmd:  NewSetCmd,
+			args: []string{".spec.paused", "true", "--path-syntax", "jq"},
+			want: "# deployment\nspec:\n  replicas: 1 # scaled by hand\n  containers:\n    - name: app\n      image: app:1.2\n  paused: true\n",
+		},
+		{
+			name: "set adds a number",
+			cmd:  NewSetCmd,
+			args: []string{"spec.minReady", "1"},
+			want: "# deployment\nspec:\n  replicas: 1 # scaled by hand\n  containers:\n    - name: app\n      image: app:1.2\n  minReady: 1\n",
+		},
+		{
+			name: "del",
+			cmd:  NewDelCmd,
+			args: []string{"spec.containers[0].name"},
+			want: "# deployment\nspec:\n  replicas: 1 # scaled by hand\n  containers:\n    - image: app:1.2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "deploy.yaml")
+			require.NoError(t, os.WriteFile(file, []byte(editYaml), 0o600))
+			cmd := tt.cmd()
+			cmd.SetArgs(append(tt.args, "-f", file))
+			got := captureStdout(func() {
+				require.NoError(t, cmd.Execute())
+			})
+			assert.Equal(t, tt.want, got)
+			b, err := os.ReadFile(file)
+			require.NoError(t, err)
+			assert.Equal(t, editYaml, string(b), "the file is only changed with --in-place")
+		})
+	}
+}
+
+func TestEditCmdsInlineData(t *testing.T) {
+	cmd := NewSetCmd()
+	cmd.SetArgs([]string{"a[1].b", "x", `{"a": [1, {"b": 2}]}`})
+	got := captureStdout(func() {
+		require.NoError(t, cmd.Execute())
+	})
+	assert.Equal(t, `{"a": [1, {"b": "x"}]}`, got)
+
+	cmd = NewGetCmd()
+	cmd.SetArgs([]string{"server", "[server]\nport = 80\n"})
+	got = captureStdout(func() {
+		require.NoError(t, cmd.Execute())
+	})
+	assert.Equal(t, "port = 80\n", got)
+}
+
+func TestEditCmdsInPlace(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(file, []byte("[server]\nport = 80 # http\nhost = \"a\"\n"), 0o600))
+
+	cmd := NewSetCmd()
+	cmd.SetArgs([]string{"server.port", "8080", "-f", file, "-i"})
+	require.NoError(t, cmd.Execute())
+	cmd = NewDelCmd()
+	cmd.SetArgs([]string{"server.host", "-f", file, "--in-place"})
+	require.NoError(t, cmd.Execute())
+
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "[server]\nport = 8080 # http\n", string(b))
+	_, err = os.Stat(file + ".bak")
+	assert.True(t, os.IsNotExist(err), "no backup is kept")
+}
+
+func TestEditCmdsErrors(t *testing.T) {
+	for _, tt := range []struct {
+		cmd  func() *cobra.Command
+		args []string
+	}{
+		{NewGetCmd, []string{"missing", `{"a": 1}`}},
+		{NewGetCmd, []string{"a[", `{"a": 1}`}},
+		{NewGetCmd, []string{"a", `{"a": 1}`, "--path-syntax", "xpath"}},
+		{NewSetCmd, []string{"missing.b", "1", `{"a": 1}`}},
+		{NewSetCmd, []string{"a", "1", `{"a": {"b": 1}}`}},
+		{NewSetCmd, []string{"a", "1", `{"a": 1}`, "-i"}},
+		{NewDelCmd, []string{"b", `{"a": 1}`}},
+	} {
+		cmd := tt.cmd()
+		cmd.SetArgs(tt.args)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		assert.Error(t, cmd.Execute(), tt.args)
+	}
+
+	cmd := NewDelCmd()
+	cmd.SetArgs([]string{"b", `{"a": 1}`})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	assert.ErrorIs(t, cmd.Execute(), nodes.ErrNotFound)
+}
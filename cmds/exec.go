@@ -0,0 +1,30 @@
+package cmds
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// runCommand runs command through the system shell and returns its stdout. A
+// failing command reports its stderr.
+func runCommand(command string) ([]byte, error) {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	// #nosec G204 -- running the user's command is the point of --exec.
+	cmd := exec.Command(shell, flag, command)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("command %q failed: %w: %s", command, err, msg)
+		}
+		return nil, fmt.Errorf("command %q failed: %w", command, err)
+	}
+	return stdout.Bytes(), nil
+}
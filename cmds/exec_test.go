@@ -0,0 +1,23 @@
+package cmds
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	out, err := runCommand(`echo '{"pods": ["a", "b"]}'`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"pods": ["a", "b"]}`, string(out))
+
+	_, err = runCommand("echo oops >&2; exit 3")
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "oops")
+	assert.ErrorContains(t, err, "exit status 3")
+}
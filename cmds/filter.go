@@ -0,0 +1,122 @@
+package cmds
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+	"github.com/crosleyzack/wndr/pkg/nodes/jq"
+)
+
+// runFilter runs the jq filter f over the parsed data m, given to the filter
+// as an array again when array reports the document was one (see
+// unwrapArray).
+func runFilter(f *jq.Filter, m map[string]any, array bool) ([]any, error) {
+	results, err := f.Run(unwrapArray(m, array))
+	if err != nil {
+		return nil, fmt.Errorf("failed to run filter %s: %w", f, err)
+	}
+	return results, nil
+}
+
+// isArrayDocument reports whether data is a JSON or YAML document whose
+// top-level value is an array, which parsing stores keyed by index.
+func isArrayDocument(data []byte) bool {
+	ft, err := format.Detect(data)
+	if err != nil {
+		return false
+	}
+	_, ok := decodeDocument(data, ft).([]any)
+	return ok
+}
+
+// unwrapArray returns m as the array it was parsed from when array is set and
+// its keys are exactly 0 through len(m)-1, and m itself otherwise, so an
+// object keyed "0", "1", ... stays an object.
+func unwrapArray(m map[string]any, array bool) any {
+	if !array {
+		return m
+	}
+	arr := make([]any, len(m))
+	for i := range arr {
+		v, ok := m[strconv.Itoa(i)]
+		if !ok {
+			return m
+		}
+		arr[i] = v
+	}
+	return arr
+}
+
+// resultsToMap shapes filter results into a document the tree can show: a
+// single object as itself, and anything else as an array keyed by index, the
+// same way parsing stores a top-level array. A single array result is shown
+// as that array.
+func resultsToMap(results []any) map[string]any {
+	if len(results) == 1 {
+		switch r := results[0].(type) {
+		case map[string]any:
+			return r
+		case []any:
+			results = r
+		}
+	}
+	m := make(map[string]any, len(results))
+	for i, r := range results {
+		m[strconv.Itoa(i)] = r
+	}
+	return m
+}
+
+// printResults writes each result to w in the output format: one compact JSON
+// value per line, YAML documents separated by ---, or a single TOML document.
+func printResults(w io.Writer, results []any, output string) error {
+	switch output {
+	case "json":
+		for _, r := range results {
+			b, err := format.Encode(r, format.FormatJson)
+			if err != nil {
+				return fmt.Errorf("failed to convert result to json: %w", err)
+			}
+			if _, err := fmt.Fprintln(w, string(b)); err != nil {
+				return err
+			}
+		}
+	case "yaml":
+		for i, r := range results {
+			b, err := format.Encode(r, format.FormatYaml)
+			if err != nil {
+				return fmt.Errorf("failed to convert result to yaml: %w", err)
+			}
+			if i > 0 {
+				if _, err := fmt.Fprintln(w, "---"); err != nil {
+					return err
+				}
+			}
+			if _, err := w.Write(b); err != nil {
+				return err
+			}
+		}
+	case "toml":
+		// a TOML document is always a table, and there is no way to hold
+		// several in one stream
+		if len(results) != 1 {
+			return fmt.Errorf("toml output needs exactly one result, got %d", len(results))
+		}
+		m, ok := results[0].(map[string]any)
+		if !ok {
+			return fmt.Errorf("toml output needs an object result")
+		}
+		b, err := format.AsToml(m)
+		if err != nil {
+			return fmt.Errorf("failed to convert result to toml: %w", err)
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown output %q: expected json, yaml or toml", output)
+	}
+	return nil
+}
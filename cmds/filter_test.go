@@ -0,0 +1,113 @@
+package cmds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterOutput(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pods.json")
+	require.NoError(t, os.WriteFile(file, []byte(`[
+  {"name": "api", "status": "Running", "restarts": 3},
+  {"name": "db", "status": "Pending", "restarts": 0}
+]`), 0o600))
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "json lines",
+			args: []string{"-q", `.[] | select(.status == "Running") | .name, .restarts`, "-o", "json"},
+			want: "\"api\"\n3\n",
+		},
+		{
+			name: "yaml documents",
+			args: []string{"-q", `.[] | {name}`, "-o", "yaml"},
+			want: "name: api\n---\nname: db\n",
+		},
+		{
+			name: "toml",
+			args: []string{"-q", `{count: length}`, "-o", "toml"},
+			want: "count = 2.0\n",
+		},
+		{
+			name: "no filter",
+			args: []string{"-o", "json"},
+			want: `[{"name":"api","restarts":3,"status":"Running"},{"name":"db","restarts":0,"status":"Pending"}]` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := New()
+			cmd.SetArgs(append(tt.args, "-f", file))
+			got := captureStdout(func() {
+				require.NoError(t, cmd.Execute())
+			})
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// an object keyed by index is not filtered as an array
+	cmd := New()
+	cmd.SetArgs([]string{"-q", "keys", "-o", "json", `{"0": "a", "1": "b"}`})
+	got := captureStdout(func() {
+		require.NoError(t, cmd.Execute())
+	})
+	assert.Equal(t, `["0","1"]`+"\n", got)
+
+	// a YAML sequence is filtered as an array
+	cmd = New()
+	cmd.SetArgs([]string{"-q", ".[0]", "-o", "json", "[a, b]"})
+	got = captureStdout(func() {
+		require.NoError(t, cmd.Execute())
+	})
+	assert.Equal(t, `"a"`+"\n", got)
+}
+
+func TestFilterErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"-q", ".[", "-o", "json", `{"a": 1}`},
+		{"-q", ".a.b", "-o", "json", `{"a": "x"}`},
+		{"-q", ".a, .a", "-o", "toml", `{"a": {}}`},
+		{"-o", "csv", `{"a": 1}`},
+	} {
+		cmd := New()
+		cmd.SetArgs(args)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		assert.Error(t, cmd.Execute(), args)
+	}
+}
+
+func TestUnwrapArray(t *testing.T) {
+	arr := []byte(`["x", "y"]`)
+	m, err := format.Parse(arr)
+	require.NoError(t, err)
+	assert.True(t, isArrayDocument(arr))
+	assert.Equal(t, []any{"x", "y"}, unwrapArray(m, true))
+
+	// an object keyed by index, and a TOML table, are not arrays
+	obj := []byte(`{"0": "x", "1": "y"}`)
+	m, err = format.Parse(obj)
+	require.NoError(t, err)
+	assert.False(t, isArrayDocument(obj))
+	assert.Equal(t, m, unwrapArray(m, false))
+	assert.False(t, isArrayDocument([]byte("[0]\nk = 1\n")))
+	assert.True(t, isArrayDocument([]byte("- x\n- y\n")))
+	assert.False(t, isArrayDocument([]byte("0: x\n1: y\n")))
+}
+
+func TestResultsToMap(t *testing.T) {
+	obj := map[string]any{"a": 1.0}
+	assert.Equal(t, obj, resultsToMap([]any{obj}))
+	assert.Equal(t, map[string]any{"0": "x", "1": "y"}, resultsToMap([]any{[]any{"x", "y"}}))
+	assert.Equal(t, map[string]any{"0": "x", "1": obj}, resultsToMap([]any{"x", obj}))
+	assert.Equal(t, map[string]any{"0": 2.0}, resultsToMap([]any{2.0}))
+}
@@ -0,0 +1,139 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+	"github.com/spf13/pflag"
+)
+
+// defaultHTTPTimeout bounds a URL request when no --timeout is given.
+const defaultHTTPTimeout = 30 * time.Second
+
+// httpFlags holds the flags controlling how http(s) URL inputs are fetched.
+type httpFlags struct {
+	headers      []string
+	bearerEnv    string
+	basicAuthEnv string
+	timeout      time.Duration
+}
+
+// register adds the HTTP flags to fs.
+func (h *httpFlags) register(fs *pflag.FlagSet) {
+	fs.StringArrayVarP(&h.headers, "header", "H", nil, "header sent with URL requests, as 'Name: value'")
+	fs.StringVar(&h.bearerEnv, "bearer-env", "", "environment variable holding a bearer token for URL requests")
+	fs.StringVar(&h.basicAuthEnv, "basic-auth-env", "", "environment variable holding user:password for URL requests")
+	fs.DurationVar(&h.timeout, "timeout", defaultHTTPTimeout, "timeout for each URL request")
+}
+
+// inputOption returns the gatherInputs option fetching URLs with these flags.
+func (h *httpFlags) inputOption() (inputOption, error) {
+	headers := http.Header{}
+	for _, kv := range h.headers {
+		k, v, ok := strings.Cut(kv, ":")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid header %q: expected 'Name: value'", kv)
+		}
+		headers.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	}
+	if h.bearerEnv != "" && h.basicAuthEnv != "" {
+		return nil, fmt.Errorf("--bearer-env and --basic-auth-env cannot be combined")
+	}
+	if h.bearerEnv != "" {
+		token, ok := os.LookupEnv(h.bearerEnv)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", h.bearerEnv)
+		}
+		headers.Set("Authorization", "Bearer "+token)
+	}
+	var basic *url.Userinfo
+	if h.basicAuthEnv != "" {
+		creds, ok := os.LookupEnv(h.basicAuthEnv)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", h.basicAuthEnv)
+		}
+		user, pass, _ := strings.Cut(creds, ":")
+		basic = url.UserPassword(user, pass)
+	}
+	return withHTTP(headers, basic, h.timeout), nil
+}
+
+type httpConf struct {
+	Headers http.Header
+	// Basic is the user and password sent with basic auth, if any
+	Basic   *url.Userinfo
+	Timeout time.Duration
+}
+
+// withHTTP sets the headers, basic auth and timeout used to fetch URL inputs.
+func withHTTP(headers http.Header, basic *url.Userinfo, timeout time.Duration) inputOption {
+	return func(c *inputConf) {
+		c.HTTP = httpConf{Headers: headers, Basic: basic, Timeout: timeout}
+	}
+}
+
+// isURL reports whether s is an http or https URL rather than a path or inline
+// data.
+func isURL(s string) bool {
+	if !strings.HasPrefix(s, "http://") && !strings.HasPrefix(s, "https://") {
+		return false
+	}
+	u, err := url.Parse(s)
+	return err == nil && u.Host != ""
+}
+
+// fetchURL GETs rawURL and returns the body. When the response Content-Type,
+// or failing that the URL's file extension, names a supported format, the body
+// is parsed with it and returned as JSON so it is not left to format
+// detection; otherwise the body is returned as is.
+func fetchURL(rawURL string, conf httpConf) ([]byte, error) {
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request for %s: %w", rawURL, err)
+	}
+	req.Header.Set("Accept", "application/json, application/yaml, application/toml;q=0.9, */*;q=0.8")
+	for k, vs := range conf.Headers {
+		req.Header[k] = vs
+	}
+	if conf.Basic != nil {
+		pass, _ := conf.Basic.Password()
+		req.SetBasicAuth(conf.Basic.Username(), pass)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s: %w", rawURL, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to fetch %s: %s", rawURL, resp.Status)
+	}
+	parse, ok := format.ForMediaType(resp.Header.Get("Content-Type"))
+	if !ok {
+		parse, ok = format.ForFile(req.URL.Path)
+	}
+	if !ok {
+		return b, nil
+	}
+	m, err := parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response from %s: %w", rawURL, err)
+	}
+	return format.AsJson(m)
+}
@@ -0,0 +1,158 @@
+package cmds
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"v": "json"}`))
+	})
+	mux.HandleFunc("/yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+		_, _ = w.Write([]byte("v: yaml\n"))
+	})
+	mux.HandleFunc("/config.toml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write([]byte(`v = "toml"`))
+	})
+	mux.HandleFunc("/untyped", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(`raw`))
+	})
+	mux.HandleFunc("/auth", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		user, pass, _ := r.BasicAuth()
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"authorization": r.Header.Get("Authorization"),
+			"custom":        r.Header.Get("X-Custom"),
+			"user":          user,
+			"pass":          pass,
+		})
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGatherInputsURL(t *testing.T) {
+	srv := newTestServer(t)
+	devNull, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	t.Cleanup(func() { devNull.Close() })
+
+	got, err := gatherInputs(
+		[]string{srv.URL + "/yaml", srv.URL + "/untyped"},
+		[]string{srv.URL + "/json", srv.URL + "/config.toml"},
+		devNull,
+	)
+	require.NoError(t, err)
+	require.Len(t, got, 4)
+	assert.JSONEq(t, `{"v": "json"}`, string(got[0].data))
+	// the extension is used when the Content-Type is not a data format.
+	assert.JSONEq(t, `{"v": "toml"}`, string(got[1].data))
+	assert.JSONEq(t, `{"v": "yaml"}`, string(got[2].data))
+	// an unknown type is passed through for format detection.
+	assert.Equal(t, "raw", string(got[3].data))
+}
+
+func TestGatherInputsURLAuth(t *testing.T) {
+	srv := newTestServer(t)
+	devNull, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	t.Cleanup(func() { devNull.Close() })
+
+	t.Setenv("WNDR_TEST_TOKEN", "s3cret")
+	t.Setenv("WNDR_TEST_BASIC", "bob:hunter2")
+	tests := []struct {
+		name  string
+		flags httpFlags
+		want  string
+	}{
+		{
+			name:  "bearer token and header",
+			flags: httpFlags{bearerEnv: "WNDR_TEST_TOKEN", headers: []string{"X-Custom: yes"}},
+			want:  `{"authorization": "Bearer s3cret", "custom": "yes", "user": "", "pass": ""}`,
+		},
+		{
+			name:  "basic auth",
+			flags: httpFlags{basicAuthEnv: "WNDR_TEST_BASIC"},
+			want:  `{"authorization": "Basic Ym9iOmh1bnRlcjI=", "custom": "", "user": "bob", "pass": "hunter2"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt, err := tt.flags.inputOption()
+			require.NoError(t, err)
+			got, err := gatherInputs([]string{srv.URL + "/auth"}, nil, devNull, opt)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got[0].data))
+		})
+	}
+}
+
+func TestGatherInputsURLErrors(t *testing.T) {
+	srv := newTestServer(t)
+	devNull, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	t.Cleanup(func() { devNull.Close() })
+
+	_, err = gatherInputs([]string{srv.URL + "/missing"}, nil, devNull)
+	assert.ErrorContains(t, err, "404")
+
+	opt, err := (&httpFlags{timeout: 50 * time.Millisecond}).inputOption()
+	require.NoError(t, err)
+	_, err = gatherInputs([]string{srv.URL + "/slow"}, nil, devNull, opt)
+	assert.Error(t, err)
+}
+
+func TestHTTPFlagsInputOptionErrors(t *testing.T) {
+	_, err := (&httpFlags{headers: []string{"no colon"}}).inputOption()
+	assert.Error(t, err)
+	_, err = (&httpFlags{bearerEnv: "WNDR_TEST_UNSET"}).inputOption()
+	assert.Error(t, err)
+	_, err = (&httpFlags{basicAuthEnv: "WNDR_TEST_UNSET"}).inputOption()
+	assert.Error(t, err)
+	t.Setenv("WNDR_TEST_TOKEN", "x")
+	_, err = (&httpFlags{bearerEnv: "WNDR_TEST_TOKEN", basicAuthEnv: "WNDR_TEST_TOKEN"}).inputOption()
+	assert.Error(t, err)
+}
+
+func TestIsURL(t *testing.T) {
+	assert.True(t, isURL("http://example.com/a.json"))
+	assert.True(t, isURL("https://example.com"))
+	assert.False(t, isURL("https://"))
+	assert.False(t, isURL("ftp://example.com"))
+	assert.False(t, isURL(`{"a": "http://example.com"}`))
+	assert.False(t, isURL("./http.json"))
+}
+
+func TestDiffURLs(t *testing.T) {
+	srv := newTestServer(t)
+	cmd := NewDiffCmd()
+	cmd.SetArgs([]string{"-o", "json", "-k", "a", "-k", "b", srv.URL + "/json", srv.URL + "/yaml"})
+	out := captureStdout(func() {
+		require.NoError(t, cmd.Execute())
+	})
+	var got map[string]any
+	require.NoError(t, json.Unmarshal([]byte(out), &got))
+	assert.Equal(t, map[string]any{"a": "json", "b": "yaml"}, got["v"])
+}
@@ -0,0 +1,77 @@
+package cmds
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+	"github.com/spf13/pflag"
+)
+
+// jsonnetFlags holds the flags controlling how Jsonnet inputs are evaluated.
+// Files ending in .jsonnet or .libsonnet are always evaluated; --jsonnet also
+// evaluates positional arguments and stdin.
+type jsonnetFlags struct {
+	all      bool
+	extStr   []string
+	extCode  []string
+	tlaStr   []string
+	tlaCode  []string
+	libPaths []string
+}
+
+// register adds the Jsonnet flags to fs. The names follow the jsonnet CLI.
+func (j *jsonnetFlags) register(fs *pflag.FlagSet) {
+	fs.BoolVar(&j.all, "jsonnet", false, "evaluate every input as Jsonnet, not only .jsonnet/.libsonnet files")
+	fs.StringArrayVar(&j.extStr, "ext-str", nil, "Jsonnet external variable as key=value (value read from the environment when omitted)")
+	fs.StringArrayVar(&j.extCode, "ext-code", nil, "Jsonnet external variable as key=<jsonnet code>")
+	fs.StringArrayVar(&j.tlaStr, "tla-str", nil, "Jsonnet top-level argument as key=value (value read from the environment when omitted)")
+	fs.StringArrayVar(&j.tlaCode, "tla-code", nil, "Jsonnet top-level argument as key=<jsonnet code>")
+	fs.StringArrayVarP(&j.libPaths, "jpath", "J", nil, "additional Jsonnet library search path")
+}
+
+// inputOption returns the gatherInputs option evaluating Jsonnet inputs with
+// these flags.
+func (j *jsonnetFlags) inputOption() (inputOption, error) {
+	opts := []format.JsonnetOption{format.WithLibraryPaths(j.libPaths...)}
+	for _, b := range []struct {
+		vals []string
+		env  bool
+		opt  func(k, v string) format.JsonnetOption
+	}{
+		{vals: j.extStr, env: true, opt: format.WithExtVar},
+		{vals: j.extCode, opt: format.WithExtCode},
+		{vals: j.tlaStr, env: true, opt: format.WithTLAVar},
+		{vals: j.tlaCode, opt: format.WithTLACode},
+	} {
+		for _, kv := range b.vals {
+			k, v, err := splitBinding(kv, b.env)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, b.opt(k, v))
+		}
+	}
+	return withJsonnet(j.all, opts...), nil
+}
+
+// splitBinding splits a key=value flag. When env is set, a bare key takes its
+// value from the environment variable of the same name.
+func splitBinding(kv string, env bool) (string, string, error) {
+	k, v, ok := strings.Cut(kv, "=")
+	if k == "" {
+		return "", "", fmt.Errorf("invalid binding %q: missing key", kv)
+	}
+	if ok {
+		return k, v, nil
+	}
+	if !env {
+		return "", "", fmt.Errorf("invalid binding %q: expected key=value", kv)
+	}
+	v, ok = os.LookupEnv(k)
+	if !ok {
+		return "", "", fmt.Errorf("invalid binding %q: environment variable %s is not set", kv, k)
+	}
+	return k, v, nil
+}
@@ -0,0 +1,97 @@
+package cmds
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatherInputsJsonnet(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib")
+	require.NoError(t, os.Mkdir(lib, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(lib, "base.libsonnet"), []byte(`{ replicas: 1 }`), 0o600))
+	file := filepath.Join(dir, "app.jsonnet")
+	require.NoError(t, os.WriteFile(file, []byte(`
+local base = import "base.libsonnet";
+function(region) base + { env: std.extVar("env"), region: region }
+`), 0o600))
+	devNull, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	t.Cleanup(func() { devNull.Close() })
+
+	j := jsonnetFlags{
+		extStr:   []string{"env=prod"},
+		tlaStr:   []string{"region=eu"},
+		libPaths: []string{lib},
+	}
+	opt, err := j.inputOption()
+	require.NoError(t, err)
+
+	// the .jsonnet file is evaluated; the plain argument is passed through.
+	got, err := gatherInputs([]string{`{"a": 1}`}, []string{file}, devNull, opt)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.JSONEq(t, `{"replicas":1,"env":"prod","region":"eu"}`, string(got[0].data))
+	assert.Equal(t, `{"a": 1}`, string(got[1].data))
+
+	// with --jsonnet, arguments are evaluated too.
+	j = jsonnetFlags{all: true}
+	opt, err = j.inputOption()
+	require.NoError(t, err)
+	got, err = gatherInputs([]string{`{ a: 1 + 2 }`}, nil, devNull, opt)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":3}`, string(got[0].data))
+}
+
+func TestGatherInputsJsonnetError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "bad.jsonnet")
+	require.NoError(t, os.WriteFile(file, []byte("{\n  a: error 'nope',\n}"), 0o600))
+	devNull, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	t.Cleanup(func() { devNull.Close() })
+
+	_, err = gatherInputs(nil, []string{file}, devNull)
+	require.Error(t, err)
+	var jerr *format.JsonnetError
+	require.True(t, errors.As(err, &jerr))
+	assert.Equal(t, format.JsonnetRuntimeError, jerr.Kind)
+	assert.Equal(t, 2, jerr.Line)
+}
+
+func TestSplitBinding(t *testing.T) {
+	t.Setenv("WNDR_TEST_BINDING", "from-env")
+	tests := []struct {
+		name    string
+		kv      string
+		env     bool
+		wantK   string
+		wantV   string
+		wantErr bool
+	}{
+		{name: "key and value", kv: "a=b", wantK: "a", wantV: "b"},
+		{name: "value containing equals", kv: "a=b=c", wantK: "a", wantV: "b=c"},
+		{name: "empty value", kv: "a=", wantK: "a", wantV: ""},
+		{name: "value from environment", kv: "WNDR_TEST_BINDING", env: true, wantK: "WNDR_TEST_BINDING", wantV: "from-env"},
+		{name: "bare key without env", kv: "WNDR_TEST_BINDING", wantErr: true},
+		{name: "unset environment variable", kv: "WNDR_TEST_UNSET", env: true, wantErr: true},
+		{name: "missing key", kv: "=b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, v, err := splitBinding(tt.kv, tt.env)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantK, k)
+			assert.Equal(t, tt.wantV, v)
+		})
+	}
+}
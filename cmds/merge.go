@@ -0,0 +1,271 @@
+package cmds
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+	"github.com/crosleyzack/wndr/pkg/format/cst"
+	"github.com/crosleyzack/wndr/pkg/modules/tree"
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/crosleyzack/wndr/pkg/redact"
+	"github.com/crosleyzack/wndr/pkg/tui"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// NewMergeCmd builds the `merge` command. It layers any number of inputs, each
+// overriding the ones before it, and shows or prints the effective result.
+func NewMergeCmd() *cobra.Command {
+	var files, strategies []string
+	var pathSyntax string
+	var output string
+	var jsonnet jsonnetFlags
+	var protobuf protobufFlags
+	var httpOpts httpFlags
+	cmd := &cobra.Command{
+		Use:     "merge [-f <file>]... [data]...",
+		Aliases: []string{"m"},
+		Version: version,
+		Short:   "Deep merge tree data files, later inputs taking precedence",
+		Long: `Takes in two or more tree data sources (JSON, YAML, TOML, Jsonnet) via file flags, positional arguments, or a piped stdin and layers them in that order, each overriding the ones before it, then shows the result.
+
+Objects are merged key by key and arrays are replaced. A --strategy <path>=<strategy> flag changes this for the values at a path: deep or shallow merges, or replace, for objects, and replace, append, or merge-by-key:<key> for arrays, which merges the elements with the same value for <key> and appends the rest. An array index in a path matches every element, so containers[0]=shallow covers each element of containers.
+
+The tree shows the input, and the line in it, that each value came from. The shadowed key lists the values it overrode from earlier inputs.`,
+		Example: "wndr merge -f base.yaml -f prod.yaml -s spec.containers=merge-by-key:name",
+		Args:    cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			syntax, err := nodes.ParsePathSyntax(pathSyntax)
+			if err != nil {
+				return err
+			}
+			rules := make([]nodes.MergeRule, len(strategies))
+			for i, s := range strategies {
+				if rules[i], err = nodes.ParseMergeRule(s, syntax); err != nil {
+					return err
+				}
+			}
+			parse, err := protobuf.parser()
+			if err != nil {
+				return fmt.Errorf("failed to parse protobuf flags: %w", err)
+			}
+			dups := newDuplicateKeys(cmd)
+			parse = dups.parser(parse)
+			jsonnetOpt, err := jsonnet.inputOption()
+			if err != nil {
+				return fmt.Errorf("failed to parse jsonnet flags: %w", err)
+			}
+			httpOpt, err := httpOpts.inputOption()
+			if err != nil {
+				return fmt.Errorf("failed to parse http flags: %w", err)
+			}
+			inputs, err := gatherInputs(args, files, os.Stdin, jsonnetOpt, httpOpt, withDuplicates(dups))
+			if err != nil {
+				return fmt.Errorf("failed to get data: %w", err)
+			}
+			if len(inputs) < 2 {
+				return fmt.Errorf("merge needs at least two inputs, got %d", len(inputs))
+			}
+
+			// the leaves of the merged tree keep the IDs of the input nodes
+			// they came from, which find the values they were parsed from
+			sources := mergeSources(files, args, inputs, !jsonnet.all && !protobuf.enabled && protobuf.descriptor == "")
+			trees := make([]*nodes.Node, len(inputs))
+			leaves := map[uuid.UUID]any{}
+			for i, in := range inputs {
+				m, err := in.parse(dups.parserFor(sources[i].name, nil, parse))
+				if err != nil {
+					return fmt.Errorf("failed to parse input %d: %w", i+1, err)
+				}
+				trees[i] = nodes.New(m, 0, nodes.EmptyRepr)
+				collectLeaves(trees[i], m, leaves)
+			}
+			merged, prov := nodes.MergeWithProvenance(trees, rules, nodes.EmptyRepr)
+
+			c, err := tui.NewConfig()
+			if err != nil {
+				return fmt.Errorf("failed to parse config: %w", err)
+			}
+			redactor, err := newRedactor(cmd, c)
+			if err != nil {
+				return fmt.Errorf("failed to parse redaction rules: %w", err)
+			}
+			if output != "" {
+				printWarnings(os.Stderr, dups.warnings(), dups.syntax)
+				return printResults(os.Stdout, []any{redactor.Data(nil, mergedValue(merged, leaves))}, output)
+			}
+			notes := tui.WithAnnotations(provenanceNotes(prov, trees, sources, redactor))
+			warned := tui.WithWarnings(dups.warnings)
+			if err := renderTree(c, merged, nodes.LeafValuesOnlyRepr, nil, notes, warned, tui.WithRedaction(redactor)); err != nil {
+				return fmt.Errorf("failed to render tree: %w", err)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&files, "file", "f", nil, "files to read data from, in the order they are layered")
+	cmd.Flags().StringArrayVarP(&strategies, "strategy", "s", nil, fmt.Sprintf("how to merge the values at a path, as <path>=<strategy> with strategy one of %s; may be repeated", strings.Join(nodes.GetMergeStrategies(), ", ")))
+	cmd.Flags().StringVar(&pathSyntax, "path-syntax", nodes.DotSyntax.String(), fmt.Sprintf("syntax of --strategy paths: %s", strings.Join(nodes.GetPathSyntaxes(), ", ")))
+	cmd.Flags().StringVarP(&output, "out", "o", "", "print the merged data as json, yaml or toml instead of showing it")
+	jsonnet.register(cmd.Flags())
+	protobuf.register(cmd.Flags())
+	httpOpts.register(cmd.Flags())
+	return cmd
+}
+
+// collectLeaves records the value each leaf under n was built from, found at
+// the same place in v, by the leaf's ID.
+func collectLeaves(n *nodes.Node, v any, out map[uuid.UUID]any) {
+	if nodes.IsLeaf(n) && !nodes.IsRoot(n) {
+		out[n.ID] = v
+		return
+	}
+	for key, child := range n.Children.Iter() {
+		switch c := v.(type) {
+		case map[string]any:
+			collectLeaves(child, c[key], out)
+		case []any:
+			if i, err := strconv.Atoi(key); err == nil && i < len(c) {
+				collectLeaves(child, c[i], out)
+			}
+		}
+	}
+}
+
+// mergedValue converts the merged tree back to data, giving each leaf the
+// value it was parsed from.
+func mergedValue(n *nodes.Node, leaves map[uuid.UUID]any) any {
+	if nodes.IsLeaf(n) && !nodes.IsRoot(n) {
+		if v, ok := leaves[n.ID]; ok {
+			return v
+		}
+		return typedLeaf(n, nil)
+	}
+	if size, ok := nodes.ArrayLen(n); ok {
+		arr := make([]any, size)
+		for i := range arr {
+			arr[i] = mergedValue(nodes.Child(n, strconv.Itoa(i)), leaves)
+		}
+		return arr
+	}
+	m := make(map[string]any, n.Children.Len())
+	for key, child := range n.Children.Iter() {
+		m[key] = mergedValue(child, leaves)
+	}
+	return m
+}
+
+// mergeSource names an input to merge for the notes saying where values came
+// from.
+type mergeSource struct {
+	name string
+	// doc is the input as written, which gives the line of each value; nil
+	// when the input was generated, as Jsonnet and archives are, or is not
+	// text
+	doc *cst.Doc
+}
+
+// mergeSources describes inputs, read by gatherInputs from files, args and
+// stdin in that order. text reports whether inputs hold the text as written
+// rather than data evaluated or decoded from it.
+func mergeSources(files, args []string, inputs []input, text bool) []mergeSource {
+	var sources []mergeSource
+	for _, f := range files {
+		if f == "" {
+			continue
+		}
+		src := mergeSource{name: f}
+		if text && !isArchive(f) && !format.IsJsonnetFile(f) {
+			src.doc = parseSource(inputs[len(sources)].data, f)
+		}
+		sources = append(sources, src)
+	}
+	for i := range args {
+		src := mergeSource{name: fmt.Sprintf("argument %d", i+1)}
+		if text {
+			src.doc = parseSource(inputs[len(sources)].data, "")
+		}
+		sources = append(sources, src)
+	}
+	if len(sources) < len(inputs) {
+		src := mergeSource{name: "stdin"}
+		if text {
+			src.doc = parseSource(inputs[len(sources)].data, "")
+		}
+		sources = append(sources, src)
+	}
+	return sources
+}
+
+// parseSource parses b in the format of the file name, or the one detected
+// from b, or returns nil when it cannot be parsed.
+func parseSource(b []byte, name string) *cst.Doc {
+	ft, ok := format.TypeForFile(name)
+	if !ok {
+		var err error
+		if ft, err = format.Detect(b); err != nil {
+			return nil
+		}
+	}
+	doc, err := cst.Parse(b, ft)
+	if err != nil {
+		return nil
+	}
+	return doc
+}
+
+// provenanceNotes returns the notes giving the input, and line, each value of
+// a merge of trees came from, and when detailed the values it overrode, with
+// the values r redacts masked.
+func provenanceNotes(prov *nodes.Provenance, trees []*nodes.Node, sources []mergeSource, r *redact.Redactor) tree.Annotator {
+	lines := map[uuid.UUID]int{}
+	for i, t := range trees {
+		if sources[i].doc == nil {
+			continue
+		}
+		nodes.DFS(t, func(n *nodes.Node, _ int) error {
+			if line, err := sources[i].doc.Line(nodes.GetPathToNode(n)); err == nil {
+				lines[n.ID] = line
+			}
+			return nil
+		}, nodes.WithNextNodes(nodes.AllChildren))
+	}
+	origin := func(n *nodes.Node) string {
+		i, ok := prov.Source(n)
+		if !ok {
+			return ""
+		}
+		if line, ok := lines[n.ID]; ok {
+			return fmt.Sprintf("%s:%d", sources[i].name, line)
+		}
+		return sources[i].name
+	}
+	// the merge does not summarize the values it replaces, so they are
+	// summarized as the tree summarizes its own
+	value := func(n *nodes.Node, repr nodes.ReprNode) string {
+		if r != nil {
+			v, _ := r.Summary(n, repr)
+			return v
+		}
+		if nodes.IsLeaf(n) {
+			return n.Value
+		}
+		return repr(n)
+	}
+	return func(n *nodes.Node, detailed bool, repr nodes.ReprNode) string {
+		note := origin(n)
+		shadowed := prov.Shadowed[n.ID]
+		switch {
+		case len(shadowed) == 0:
+		case !detailed:
+			note += fmt.Sprintf(", overrides %d", len(shadowed))
+		default:
+			for _, s := range shadowed {
+				note += fmt.Sprintf(", shadows %s from %s", value(s, repr), origin(s))
+			}
+		}
+		return note
+	}
+}
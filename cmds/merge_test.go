@@ -0,0 +1,120 @@
+package cmds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeCmd(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	require.NoError(t, os.WriteFile(base, []byte(`
+image:
+  repo: app
+  tag: "1.20"
+replicas: 1
+args: [--a]
+containers:
+  - name: app
+    cpu: 1
+`), 0o600))
+	prod := filepath.Join(dir, "prod.toml")
+	require.NoError(t, os.WriteFile(prod, []byte(`
+replicas = 3
+args = ["--b"]
+
+[image]
+tag = "1.21"
+
+[[containers]]
+name = "app"
+memory = "2Gi"
+
+[[containers]]
+name = "proxy"
+`), 0o600))
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "defaults",
+			args: []string{"-o", "json"},
+			want: `{"args":["--b"],"containers":[{"memory":"2Gi","name":"app"},{"name":"proxy"}],"image":{"repo":"app","tag":"1.21"},"replicas":3}` + "\n",
+		},
+		{
+			name: "strategies",
+			args: []string{"-o", "json", "-s", "args=append", "-s", "containers=merge-by-key:name"},
+			want: `{"args":["--a","--b"],"containers":[{"cpu":1,"memory":"2Gi","name":"app"},{"name":"proxy"}],"image":{"repo":"app","tag":"1.21"},"replicas":3}` + "\n",
+		},
+		{
+			name: "jq paths",
+			args: []string{"-o", "yaml", "-s", ".=shallow", "--path-syntax", "jq"},
+			want: "args:\n- --b\ncontainers:\n- memory: 2Gi\n  name: app\n- name: proxy\nimage:\n  tag: \"1.21\"\nreplicas: 3\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewMergeCmd()
+			cmd.SetArgs(append(tt.args, "-f", base, "-f", prod))
+			got := captureStdout(func() {
+				require.NoError(t, cmd.Execute())
+			})
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMergeCmdErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{`{"a": 1}`},
+		{`{"a": 1}`, `{"a": 2}`, "-s", "a"},
+		{`{"a": 1}`, `{"a": 2}`, "-s", "a=zip"},
+		{`{"a": 1}`, `{"a": 2}`, "-s", "a=deep", "--path-syntax", "xpath"},
+		{`{"a": 1}`, `{"a": 2}`, "-o", "table"},
+	} {
+		cmd := NewMergeCmd()
+		cmd.SetArgs(args)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		assert.Error(t, cmd.Execute(), args)
+	}
+}
+
+func TestProvenanceNotes(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "values.yaml")
+	inputs := []input{
+		{data: []byte("# defaults\nimage:\n  tag: \"1.20\"\nreplicas: 1\n")},
+		{data: []byte("{\n  \"replicas\": 2\n}")},
+		{data: []byte("replicas = 3\nimage = \"nginx\"\n")},
+	}
+	sources := mergeSources([]string{base, ""}, []string{string(inputs[1].data)}, inputs, true)
+	trees := make([]*nodes.Node, len(inputs))
+	for i, in := range inputs {
+		m, err := in.parse(format.Parse)
+		require.NoError(t, err)
+		trees[i] = nodes.New(m, 0, nodes.EmptyRepr)
+	}
+	// as merge does, leaving the summaries to the tree view
+	merged, prov := nodes.MergeWithProvenance(trees, nil, nodes.EmptyRepr)
+	notes := provenanceNotes(prov, trees, sources, nil)
+
+	image := nodes.Child(merged, "image")
+	assert.Equal(t, "stdin:2, overrides 1", notes(image, false, nodes.LeafValuesOnly))
+	assert.Equal(t, "stdin:2, shadows 1.20 from "+base+":2", notes(image, true, nodes.LeafValuesOnly), "shadowed objects are summarized")
+	assert.Equal(t, "stdin:2, shadows ⓘ (1 keys) from "+base+":2", notes(image, true, nodes.KeyCountOnly), "in the format shown")
+	replicas := nodes.Child(merged, "replicas")
+	assert.Equal(t, "stdin:1, overrides 2", notes(replicas, false, nodes.LeafValuesOnly))
+	assert.Equal(t, "stdin:1, shadows 2 from argument 1:2, shadows 1 from "+base+":4", notes(replicas, true, nodes.LeafValuesOnly))
+
+	sources = mergeSources([]string{base}, []string{"a", "b"}, inputs, false)
+	assert.Equal(t, []mergeSource{{name: base}, {name: "argument 1"}, {name: "argument 2"}}, sources)
+}
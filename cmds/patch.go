@@ -0,0 +1,164 @@
+package cmds
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/crosleyzack/wndr/pkg/diff"
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/crosleyzack/wndr/pkg/patch"
+	"github.com/crosleyzack/wndr/pkg/redact"
+	"github.com/crosleyzack/wndr/pkg/tui"
+	"github.com/spf13/cobra"
+)
+
+// NewPatchCmd builds the `patch` command. It applies a JSON Patch or JSON
+// Merge Patch to a single input and prints the result or writes it back.
+func NewPatchCmd() *cobra.Command {
+	var flags docFlags
+	var merge bool
+	var inPlace bool
+	var preview bool
+	cmd := &cobra.Command{
+		Use:     "patch <patch> [-f <file> | data]",
+		Version: version,
+		Short:   "Apply a JSON Patch or JSON Merge Patch",
+		Long: `Applies an RFC 6902 JSON Patch or RFC 7386 JSON Merge Patch to tree data (JSON, YAML, TOML) read via file flag, second argument, or stdin.
+
+<patch> is a file holding the patch, or the patch itself when it starts with [ or {. It may be written in JSON or YAML. An array of operations is applied as a JSON Patch and an object as a merge patch; pass --merge to apply an array as a merge patch too.
+
+The result is printed, or written back to the -f file with --in-place, changing only the values the patch touches. With --preview the changes are shown as a diff tree first, and written only once confirmed. When an operation fails nothing is written, and the error names the operation by its index in the patch and its path.`,
+		Example: "wndr patch ops.json -f deploy.yaml -i --preview",
+		Args:    cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if inPlace {
+				if err := flags.checkInPlace(); err != nil {
+					return err
+				}
+			}
+			p, err := readPatch(args[0])
+			if err != nil {
+				return err
+			}
+			doc, err := flags.load(cmd, args[1:])
+			if err != nil {
+				return err
+			}
+			before := decodeDocument(doc.src, doc.format)
+			after, err := applyPatch(before, p, merge)
+			if err != nil {
+				return err
+			}
+			c, err := tui.NewConfig()
+			if err != nil {
+				return fmt.Errorf("failed to parse config: %w", err)
+			}
+			redactor, err := newRedactor(cmd, c)
+			if err != nil {
+				return fmt.Errorf("failed to parse redaction rules: %w", err)
+			}
+			out := after
+			if !inPlace {
+				// only what is printed is redacted; the file keeps its values
+				out = redactor.Data(nil, after)
+			}
+			b, reencoded, err := encodeData(out, doc.src, doc.format)
+			if err != nil {
+				return fmt.Errorf("failed to encode result: %w", err)
+			}
+			warnLayoutLost(os.Stderr, reencoded)
+			if preview {
+				target := "stdout"
+				if inPlace {
+					target = flags.file
+				}
+				ok, err := previewPatch(c, redactor, before, after, target)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					fmt.Fprintln(os.Stderr, "patch not applied")
+					return nil
+				}
+			}
+			return flags.write(b, inPlace)
+		},
+	}
+	cmd.Flags().StringVarP(&flags.file, "file", "f", "", "file to read data from")
+	cmd.Flags().BoolVar(&merge, "merge", false, "apply the patch as a JSON Merge Patch even when it is an array")
+	cmd.Flags().BoolVarP(&inPlace, "in-place", "i", false, "write the result back to the -f file instead of printing it")
+	cmd.Flags().BoolVar(&preview, "preview", false, "show the changes as a diff tree and ask before writing them")
+	return cmd
+}
+
+// readPatch returns the patch given as arg: the text itself when it starts with
+// [ or {, and otherwise the content of the file it names.
+func readPatch(arg string) ([]byte, error) {
+	if t := strings.TrimSpace(arg); strings.HasPrefix(t, "[") || strings.HasPrefix(t, "{") {
+		return []byte(arg), nil
+	}
+	return readFile(arg)
+}
+
+// applyPatch applies b to doc as a JSON Patch when it is an array of
+// operations and merge is not set, and otherwise as a JSON Merge Patch.
+func applyPatch(doc any, b []byte, merge bool) (any, error) {
+	v, err := patch.Decode(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patch: %w", err)
+	}
+	if _, ok := v.([]any); !ok || merge {
+		return patch.Merge(doc, v), nil
+	}
+	p, err := patch.Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patch: %w", err)
+	}
+	after, err := p.Apply(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply patch: %w", err)
+	}
+	return after, nil
+}
+
+// previewPatch shows the changes from before to after as a diff tree, with the
+// values redactor hides masked, and, once it is closed, asks whether to write
+// them to target.
+func previewPatch(c *tui.Config, redactor *redact.Redactor, before, after any, target string) (bool, error) {
+	redactor = redactor.Ignoring("before", "after")
+	trees := []*nodes.Node{
+		nodes.New(resultsToMap([]any{before}), 0, nodes.EmptyRepr),
+		nodes.New(resultsToMap([]any{after}), 0, nodes.EmptyRepr),
+	}
+	diffTree, err := diff.Diff(trees, diff.WithKeys("before", "after"))
+	if err != nil {
+		return false, fmt.Errorf("failed to create diff tree: %w", err)
+	}
+	if err := renderTree(c, diffTree, "", nodes.LeafKeyAndValues, tui.WithRedaction(redactor)); err != nil {
+		return false, fmt.Errorf("failed to render tree: %w", err)
+	}
+	// stdin may hold the data, so the answer is read from the terminal
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return false, fmt.Errorf("--preview needs a terminal to confirm: %w", err)
+	}
+	defer tty.Close()
+	return confirm(tty, os.Stderr, fmt.Sprintf("write the patched document to %s?", target))
+}
+
+// confirm writes question to w and reports whether the answer read from r is
+// yes.
+func confirm(r io.Reader, w io.Writer, question string) (bool, error) {
+	if _, err := fmt.Fprintf(w, "%s [y/N] ", question); err != nil {
+		return false, err
+	}
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
@@ -0,0 +1,123 @@
+package cmds
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/crosleyzack/wndr/pkg/patch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchCmd(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		src   string
+		args  []string
+		patch string
+		want  string
+	}{
+		{
+			name:  "json patch keeps comments",
+			file:  "deploy.yaml",
+			src:   "# deployment\nreplicas: 1 # by hand\nports: [80]\n",
+			patch: `[{"op": "replace", "path": "/replicas", "value": 3}, {"op": "add", "path": "/ports/-", "value": 443}]`,
+			want:  "# deployment\nreplicas: 3 # by hand\nports: [80, 443]\n",
+		},
+		{
+			name:  "merge patch",
+			file:  "config.toml",
+			src:   "title = \"x\"\n\n[server]\nport = 80\nhost = \"a\"\n",
+			patch: `{"server": {"port": 8080, "host": null}}`,
+			want:  "title = \"x\"\n\n[server]\nport = 8080\n",
+		},
+		{
+			name:  "array as merge patch",
+			file:  "list.json",
+			src:   `[1, 2]`,
+			args:  []string{"--merge"},
+			patch: `[3]`,
+			want:  `[3]`,
+		},
+		{
+			name:  "yaml patch file",
+			file:  "data.json",
+			src:   "{\n  \"a\": 1,\n  \"c\": 2\n}\n",
+			patch: "- op: move\n  from: /a\n  path: /b\n",
+			want:  "{\n  \"c\": 2,\n  \"b\": 1\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, tt.file)
+			require.NoError(t, os.WriteFile(file, []byte(tt.src), 0o600))
+			p := tt.patch
+			if !strings.HasPrefix(p, "[") && !strings.HasPrefix(p, "{") {
+				p = filepath.Join(dir, "patch.yaml")
+				require.NoError(t, os.WriteFile(p, []byte(tt.patch), 0o600))
+			}
+			cmd := NewPatchCmd()
+			cmd.SetArgs(append([]string{p, "-f", file}, tt.args...))
+			got := captureStdout(func() {
+				require.NoError(t, cmd.Execute())
+			})
+			assert.Equal(t, tt.want, got)
+
+			cmd = NewPatchCmd()
+			cmd.SetArgs(append([]string{p, "-f", file, "-i"}, tt.args...))
+			require.NoError(t, cmd.Execute())
+			b, err := os.ReadFile(file)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(b))
+		})
+	}
+}
+
+func TestPatchCmdErrors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.json")
+	src := `{"a": 1}`
+	require.NoError(t, os.WriteFile(file, []byte(src), 0o600))
+
+	cmd := NewPatchCmd()
+	cmd.SetArgs([]string{`[{"op": "test", "path": "/a", "value": 1}, {"op": "replace", "path": "/b/c", "value": 2}]`, "-f", file, "-i"})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	err := cmd.Execute()
+	var opErr *patch.OpError
+	require.ErrorAs(t, err, &opErr)
+	assert.Equal(t, 1, opErr.Index)
+	assert.ErrorIs(t, err, nodes.ErrNotFound)
+	assert.Contains(t, err.Error(), "operation 1 (replace /b/c)")
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, src, string(b), "a failed patch writes nothing")
+
+	for _, args := range [][]string{
+		{`[{"op": "nope", "path": "/a"}]`, src},
+		{`[`, src},
+		{filepath.Join(t.TempDir(), "missing.json"), src},
+		{`{"a": 2}`, src, "-i"},
+	} {
+		cmd := NewPatchCmd()
+		cmd.SetArgs(args)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		assert.Error(t, cmd.Execute(), args)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	for answer, want := range map[string]bool{"y\n": true, "Yes\n": true, "n\n": false, "\n": false, "": false} {
+		var out bytes.Buffer
+		ok, err := confirm(strings.NewReader(answer), &out, "write?")
+		require.NoError(t, err)
+		assert.Equal(t, want, ok, answer)
+		assert.Equal(t, "write? [y/N] ", out.String())
+	}
+}
@@ -0,0 +1,47 @@
+package cmds
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+	"github.com/spf13/pflag"
+)
+
+// protobufFlags holds the flags selecting binary protobuf decoding of inputs.
+type protobufFlags struct {
+	enabled     bool
+	descriptor  string
+	messageType string
+}
+
+// register adds the protobuf flags to fs.
+func (p *protobufFlags) register(fs *pflag.FlagSet) {
+	fs.BoolVar(&p.enabled, "protobuf", false, "decode inputs as binary protobuf (raw field numbers without --proto-descriptor)")
+	fs.StringVar(&p.descriptor, "proto-descriptor", "", "FileDescriptorSet file describing protobuf inputs (implies --protobuf)")
+	fs.StringVar(&p.messageType, "proto-type", "", "fully qualified protobuf message type, such as acme.v1.Order")
+}
+
+// parser returns the Format used to parse every input: binary protobuf when
+// requested, otherwise format.Parse.
+func (p *protobufFlags) parser() (format.Format, error) {
+	if !p.enabled && p.descriptor == "" {
+		if p.messageType != "" {
+			return nil, fmt.Errorf("--proto-type needs --proto-descriptor")
+		}
+		return format.Parse, nil
+	}
+	var opts []format.ProtobufOption
+	if p.descriptor != "" {
+		// #nosec G304 -- arbitrary paths are intended behavior of a local CLI.
+		set, err := os.ReadFile(p.descriptor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read descriptor set %s: %w", p.descriptor, err)
+		}
+		opts = append(opts, format.WithDescriptorSet(set))
+	}
+	if p.messageType != "" {
+		opts = append(opts, format.WithMessageType(p.messageType))
+	}
+	return format.NewProtobuf(opts...)
+}
@@ -0,0 +1,40 @@
+package cmds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtobufFlagsParser(t *testing.T) {
+	// without protobuf flags inputs go through the usual format detection.
+	p := protobufFlags{}
+	parse, err := p.parser()
+	require.NoError(t, err)
+	m, err := parse([]byte(`{"a": "b"}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": "b"}, m)
+
+	// --protobuf without a descriptor decodes raw fields.
+	p = protobufFlags{enabled: true}
+	parse, err = p.parser()
+	require.NoError(t, err)
+	m, err = parse([]byte{0x08, 0x96, 0x01})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"1:varint": "150"}, m)
+
+	_, err = (&protobufFlags{messageType: "acme.v1.Order"}).parser()
+	assert.Error(t, err)
+
+	missing := filepath.Join(t.TempDir(), "missing.pb")
+	_, err = (&protobufFlags{descriptor: missing}).parser()
+	assert.Error(t, err)
+
+	bad := filepath.Join(t.TempDir(), "bad.pb")
+	require.NoError(t, os.WriteFile(bad, []byte("garbage"), 0o600))
+	_, err = (&protobufFlags{descriptor: bad}).parser()
+	assert.Error(t, err)
+}
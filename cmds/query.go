@@ -0,0 +1,155 @@
+package cmds
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/crosleyzack/wndr/pkg/nodes/query"
+	"github.com/crosleyzack/wndr/pkg/tui"
+	"github.com/spf13/cobra"
+)
+
+// NewQueryCmd builds the `query` command. It evaluates a JSONPath expression or
+// JSON Pointer against a single input and prints the matching nodes.
+func NewQueryCmd() *cobra.Command {
+	var file string
+	var printMode string
+	var pathSyntax string
+	var pointer bool
+	var jsonnet jsonnetFlags
+	var protobuf protobufFlags
+	var httpOpts httpFlags
+	cmd := &cobra.Command{
+		Use:     "query <expression> [-f <file> | data]",
+		Aliases: []string{"q"},
+		Version: version,
+		Short:   "Print the nodes matching a JSONPath expression or JSON Pointer",
+		Long: `Evaluates a JSONPath expression (such as $.items[?@.status == 'Running'].name) or JSON Pointer (such as /items/0/name) against tree data (JSON, YAML, TOML, Jsonnet) read via file flag, second argument, or stdin.
+
+Each match is printed on its own line as "<path> = <value>". Objects and arrays are printed as JSON. The command fails when nothing matches.`,
+		Example: "wndr query '$..containers[*].image' -f pod.yaml",
+		Args:    cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			q, err := query.Compile(args[0])
+			if err != nil {
+				return err
+			}
+			if pointer {
+				pathSyntax = nodes.PointerSyntax.String()
+			}
+			syntax, err := nodes.ParsePathSyntax(pathSyntax)
+			if err != nil {
+				return err
+			}
+			c, err := tui.NewConfig()
+			if err != nil {
+				return fmt.Errorf("failed to parse config: %w", err)
+			}
+			redactor, err := newRedactor(cmd, c)
+			if err != nil {
+				return fmt.Errorf("failed to parse redaction rules: %w", err)
+			}
+			parse, err := protobuf.parser()
+			if err != nil {
+				return fmt.Errorf("failed to parse protobuf flags: %w", err)
+			}
+			dups := newDuplicateKeys(cmd)
+			dups.syntax = syntax
+			parse = dups.parser(parse)
+			jsonnetOpt, err := jsonnet.inputOption()
+			if err != nil {
+				return fmt.Errorf("failed to parse jsonnet flags: %w", err)
+			}
+			httpOpt, err := httpOpts.inputOption()
+			if err != nil {
+				return fmt.Errorf("failed to parse http flags: %w", err)
+			}
+			inputs, err := gatherInputs(args[1:], []string{file}, os.Stdin, jsonnetOpt, httpOpt, withDuplicates(dups))
+			if err != nil {
+				return fmt.Errorf("failed to get data: %w", err)
+			}
+			if len(inputs) != 1 {
+				return fmt.Errorf("query needs exactly one input, got %d", len(inputs))
+			}
+			m, err := inputs[0].parse(parse)
+			if err != nil {
+				return fmt.Errorf("failed to parse data: %w", err)
+			}
+			printWarnings(os.Stderr, dups.warnings(), dups.syntax)
+			root := nodes.New(m, 0, nodes.EmptyRepr)
+			matches := q.Eval(root)
+			if len(matches) == 0 {
+				return fmt.Errorf("no nodes match %s", args[0])
+			}
+			// values are redacted once matched, so queries still see them
+			redactor.Apply(root, nodes.EmptyRepr)
+			return printMatches(os.Stdout, matches, printMode, syntax)
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "file to read data from")
+	cmd.Flags().StringVarP(&printMode, "print", "p", "", "what to print for each match: path, value, or json for a JSON array of values (defaults to path = value)")
+	cmd.Flags().StringVar(&pathSyntax, "path-syntax", nodes.JSONPathSyntax.String(), fmt.Sprintf("syntax to print paths in: %s", strings.Join(nodes.GetPathSyntaxes(), ", ")))
+	cmd.Flags().BoolVar(&pointer, "pointer", false, "print paths as JSON Pointers")
+	_ = cmd.Flags().MarkDeprecated("pointer", "use --path-syntax pointer")
+	jsonnet.register(cmd.Flags())
+	protobuf.register(cmd.Flags())
+	httpOpts.register(cmd.Flags())
+	return cmd
+}
+
+// printMatches writes matches to w in the given --print mode.
+func printMatches(w io.Writer, matches []*nodes.Node, printMode string, syntax nodes.PathSyntax) error {
+	if printMode == "json" {
+		values := make([]any, len(matches))
+		for i, n := range matches {
+			values[i] = nodes.TypedValue(n)
+		}
+		b, err := json.Marshal(values)
+		if err != nil {
+			return fmt.Errorf("failed to convert matches to json: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+	for _, n := range matches {
+		var line string
+		switch printMode {
+		case "path":
+			line = nodes.PathTo(n).Format(syntax)
+		case "value":
+			v, err := valueString(n)
+			if err != nil {
+				return err
+			}
+			line = v
+		case "":
+			v, err := valueString(n)
+			if err != nil {
+				return err
+			}
+			line = nodes.PathTo(n).Format(syntax) + " = " + v
+		default:
+			return fmt.Errorf("unknown --print %q: expected path, value or json", printMode)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// valueString renders a leaf as its value and any other node as JSON.
+func valueString(n *nodes.Node) (string, error) {
+	if nodes.IsLeaf(n) {
+		return n.Value, nil
+	}
+	b, err := json.Marshal(nodes.TypedValue(n))
+	if err != nil {
+		return "", fmt.Errorf("failed to convert %s to json: %w", nodes.PathTo(n), err)
+	}
+	return string(b), nil
+}
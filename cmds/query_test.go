@@ -0,0 +1,96 @@
+package cmds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryCmd(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pod.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+spec:
+  containers:
+    - name: app
+      image: app:1.2
+    - name: sidecar
+      image: proxy:3
+      ports: [8080]
+`), 0o600))
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "jsonpath",
+			args: []string{"$..image"},
+			want: "$.spec.containers[0].image = app:1.2\n$.spec.containers[1].image = proxy:3\n",
+		},
+		{
+			name: "pointer paths",
+			args: []string{"$.spec.containers[?@.ports].name", "--path-syntax", "pointer", "-p", "path"},
+			want: "/spec/containers/1/name\n",
+		},
+		{
+			name: "dot paths",
+			args: []string{"$..ports[0]", "--path-syntax", "dot"},
+			want: "spec.containers[1].ports[0] = 8080\n",
+		},
+		{
+			name: "json pointer values",
+			args: []string{"/spec/containers/1", "--print", "value"},
+			want: `{"image":"proxy:3","name":"sidecar","ports":[8080]}` + "\n",
+		},
+		{
+			name: "json array",
+			args: []string{"spec.containers[*].name", "-p", "json"},
+			want: `["app","sidecar"]` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewQueryCmd()
+			cmd.SetArgs(append(tt.args, "-f", file))
+			got := captureStdout(func() {
+				require.NoError(t, cmd.Execute())
+			})
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// an object keyed by numbers is not printed as an array
+	cmd := NewQueryCmd()
+	cmd.SetArgs([]string{"$.ports", `{"ports": {"80": "http", "443": "https"}}`})
+	got := captureStdout(func() {
+		require.NoError(t, cmd.Execute())
+	})
+	assert.Equal(t, `$.ports = {"443":"https","80":"http"}`+"\n", got)
+
+	// values are printed as the types they read as
+	cmd = NewQueryCmd()
+	cmd.SetArgs([]string{"$.*", "-p", "json", `{"a": 1, "b": true, "c": null, "d": [], "e": "x"}`})
+	got = captureStdout(func() {
+		require.NoError(t, cmd.Execute())
+	})
+	assert.Equal(t, `[1,true,null,[],"x"]`+"\n", got)
+}
+
+func TestQueryCmdErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"$.missing", `{"a": 1}`},
+		{"$[", `{"a": 1}`},
+		{"$.a", `{"a": 1}`, "-p", "table"},
+		{"$.a", `{"a": 1}`, "--path-syntax", "xpath"},
+	} {
+		cmd := NewQueryCmd()
+		cmd.SetArgs(args)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		assert.Error(t, cmd.Execute(), args)
+	}
+}
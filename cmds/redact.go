@@ -0,0 +1,19 @@
+package cmds
+
+import (
+	"github.com/crosleyzack/wndr/pkg/redact"
+	"github.com/crosleyzack/wndr/pkg/tui"
+	"github.com/spf13/cobra"
+)
+
+// revealFlag names the flag that turns redaction off.
+const revealFlag = "reveal"
+
+// newRedactor returns the Redactor for the rules in c, or nil when there are
+// none or the --reveal flag is set.
+func newRedactor(cmd *cobra.Command, c *tui.Config) (*redact.Redactor, error) {
+	if reveal, err := cmd.Flags().GetBool(revealFlag); err == nil && reveal {
+		return nil, nil
+	}
+	return redact.New(&c.RedactConfig)
+}
@@ -0,0 +1,83 @@
+package cmds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedaction(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(config, []byte("RedactCommon = true\nRedactPaths = [\"hosts[*].user\"]\n"), 0o600))
+	t.Setenv("WNDR_CONFIG", config)
+	data := `{"password": "hunter2", "hosts": [{"user": "admin", "port": 22}]}`
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "output",
+			args: []string{"-o", "json", data},
+			want: `{"hosts":[{"port":22,"user":"[redacted]"}],"password":"[redacted]"}` + "\n",
+		},
+		{
+			name: "revealed output",
+			args: []string{"--reveal", "-o", "json", data},
+			want: `{"hosts":[{"port":22,"user":"admin"}],"password":"hunter2"}` + "\n",
+		},
+		{
+			name: "get",
+			args: []string{"get", "password", data},
+			want: "[redacted]\n",
+		},
+		{
+			name: "get revealed",
+			args: []string{"get", "--reveal", "password", data},
+			want: "hunter2\n",
+		},
+		{
+			name: "query",
+			args: []string{"query", "$.hosts[0]", data},
+			want: `$.hosts[0] = {"port":22,"user":"[redacted]"}` + "\n",
+		},
+		{
+			name: "patch",
+			args: []string{"patch", `{"hosts": null}`, data},
+			want: `{"password": "[redacted]"}`,
+		},
+		{
+			name: "patch revealed",
+			args: []string{"patch", "--reveal", `{"hosts": null}`, data},
+			want: `{"password": "hunter2"}`,
+		},
+		{
+			name: "diff",
+			args: []string{"diff", "-o", "json", data, `{"password": "swordfish"}`},
+			want: `{"_wndrmeta":{"_f1":"#ad0116","_f2":"#006222"},"hosts":{"_f1":{"0":{"port":"22","user":"[redacted]"}},"_f2":"nil"},"password":{"_f1":"[redacted]","_f2":"[redacted]"}}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := New()
+			cmd.SetArgs(tt.args)
+			got := captureStdout(func() {
+				require.NoError(t, cmd.Execute())
+			})
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// a file patched in place keeps its redacted values
+	file := filepath.Join(t.TempDir(), "data.json")
+	require.NoError(t, os.WriteFile(file, []byte(data), 0o600))
+	cmd := New()
+	cmd.SetArgs([]string{"patch", `{"hosts": null}`, "-f", file, "-i"})
+	require.NoError(t, cmd.Execute())
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, `{"password": "hunter2"}`, string(b))
+}
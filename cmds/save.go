@@ -0,0 +1,243 @@
+package cmds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/crosleyzack/wndr/pkg/format"
+	"github.com/crosleyzack/wndr/pkg/format/cst"
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/crosleyzack/wndr/pkg/tui"
+	yaml "github.com/goccy/go-yaml"
+)
+
+// fileSaver returns a saver writing an edited tree back to path in the format
+// its extension names, and false when path is not a json, yaml or toml file.
+func fileSaver(path string) (tui.Saver, bool) {
+	ft, ok := format.TypeForFile(path)
+	if !ok {
+		return nil, false
+	}
+	return func(root *nodes.Node) (string, error) {
+		old, err := readFile(path)
+		if err != nil {
+			return "", err
+		}
+		b, reencoded, err := encodeTree(root, old, ft)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode %s: %w", path, err)
+		}
+		if err := writeWithBackup(path, old, b); err != nil {
+			return "", err
+		}
+		if reencoded {
+			return layoutLost, nil
+		}
+		return "", nil
+	}, true
+}
+
+// layoutLost tells the user a document was encoded whole, losing its comments
+// and layout.
+const layoutLost = "comments and layout could not be kept, so the whole document was rewritten"
+
+// warnLayoutLost writes layoutLost to w as a warning when reencoded is set.
+func warnLayoutLost(w io.Writer, reencoded bool) {
+	if reencoded {
+		fmt.Fprintf(w, "warning: %s\n", layoutLost)
+	}
+}
+
+// encodeTree writes the tree under root in format ft, as encodeData does. old
+// is the document the tree was read from: its values give the leaves, which
+// the tree holds as text, their types back.
+func encodeTree(root *nodes.Node, old []byte, ft format.FormatType) ([]byte, bool, error) {
+	orig := decodeDocument(old, ft)
+	return encodeData(typedValue(root, orig, orig != nil), old, ft)
+}
+
+// encodeData writes value, a changed version of the document old, in format
+// ft. Only the values that changed are rewritten, keeping comments and layout,
+// unless old uses syntax the in-place writer does not handle; then the whole
+// document is re-encoded and reencoded is set.
+func encodeData(value any, old []byte, ft format.FormatType) (b []byte, reencoded bool, err error) {
+	b, err = cst.Rewrite(old, ft, value)
+	if err == nil {
+		return b, false, nil
+	}
+	if !errors.Is(err, cst.ErrUnsupported) {
+		return nil, false, err
+	}
+	switch ft {
+	case format.FormatJson:
+		b, err = json.MarshalIndent(value, "", "  ")
+		b = append(b, '\n')
+	case format.FormatYaml:
+		b, err = yaml.Marshal(value)
+	default:
+		b, err = toml.Marshal(value)
+	}
+	return b, true, err
+}
+
+// decodeDocument returns the data in src, or nil when it cannot be parsed as
+// format ft.
+func decodeDocument(src []byte, ft format.FormatType) any {
+	m, err := ft.Parser()(src)
+	if err != nil {
+		return nil
+	}
+	// parsing keys a top-level array by index; keep it an array. A TOML
+	// document is always a table.
+	var arr []any
+	switch ft {
+	case format.FormatJson:
+		err = json.Unmarshal(src, &arr)
+	case format.FormatYaml:
+		err = yaml.Unmarshal(src, &arr)
+	default:
+		return m
+	}
+	if err == nil && arr != nil {
+		return arr
+	}
+	return m
+}
+
+// typedValue converts n back to data, giving each leaf the type of the value
+// at the same place in orig when it still fits, and otherwise the type its
+// text reads as. found is false when there was nothing at that place.
+func typedValue(n *nodes.Node, orig any, found bool) any {
+	if nodes.IsLeaf(n) && !nodes.IsRoot(n) {
+		if found && orig == nil && n.Value == "" {
+			// null is shown as an empty value
+			return nil
+		}
+		return typedLeaf(n, orig)
+	}
+	origMap, _ := orig.(map[string]any)
+	origArr, isArr := orig.([]any)
+	if size, ok := nodes.ArrayLen(n); ok && (isArr || origMap == nil) {
+		arr := make([]any, size)
+		for i := range arr {
+			var o any
+			if i < len(origArr) {
+				o = origArr[i]
+			}
+			arr[i] = typedValue(nodes.Child(n, strconv.Itoa(i)), o, i < len(origArr))
+		}
+		return arr
+	}
+	m := make(map[string]any, n.Children.Len())
+	for key, child := range n.Children.Iter() {
+		o, found := origMap[key]
+		m[key] = typedValue(child, o, found)
+	}
+	return m
+}
+
+// typedLeaf converts the leaf n, an empty object or array or the text of a
+// scalar, to a value of the type orig has.
+func typedLeaf(n *nodes.Node, orig any) any {
+	switch n.Kind {
+	case nodes.Object:
+		return map[string]any{}
+	case nodes.Array:
+		return []any{}
+	}
+	value := n.Value
+	if orig != nil && nodes.NewNode("", orig, 0, 0, nodes.EmptyRepr).Value == value {
+		// unchanged
+		return orig
+	}
+	switch o := orig.(type) {
+	case string:
+		return value
+	case time.Time:
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return t
+		}
+		return value
+	case nil:
+	default:
+		switch reflect.ValueOf(o).Kind() {
+		case reflect.Bool:
+			if value == "true" || value == "false" {
+				return value == "true"
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return i
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if u, err := strconv.ParseUint(value, 10, 64); err == nil {
+				return u
+			}
+		case reflect.Float32, reflect.Float64:
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				return f
+			}
+		}
+	}
+	// a new value, or one that no longer fits its old type. Only true and
+	// false are booleans; strconv.ParseBool would take 1 and t as well.
+	if value == "true" || value == "false" {
+		return value == "true"
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
+
+// writeWithBackup keeps old in path.bak, then replaces path with b.
+func writeWithBackup(path string, old, b []byte) error {
+	if err := os.WriteFile(path+".bak", old, fileMode(path)); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return writeAtomic(path, b)
+}
+
+// writeAtomic replaces path with b, keeping its permissions. The new content
+// is written to a temporary file first so path is never left half written.
+func writeAtomic(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	if err := tmp.Chmod(fileMode(path)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	return nil
+}
+
+// fileMode returns the permissions of path, or 0644 when it does not exist.
+func fileMode(path string) os.FileMode {
+	if st, err := os.Stat(path); err == nil {
+		return st.Mode().Perm()
+	}
+	return 0o644
+}
@@ -0,0 +1,127 @@
+package cmds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSaver(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+		edit nodes.Op
+		want string
+		// note is set when the document is encoded whole
+		note string
+	}{
+		{
+			name: "json keeps types",
+			file: "conf.json",
+			data: `{"port": 80, "debug": false, "name": "app", "tags": ["a"], "none": null}`,
+			edit: nodes.SetOp{Path: nodes.Path{{Key: "port"}}, Value: "8080"},
+			want: `{"port": 8080, "debug": false, "name": "app", "tags": ["a"], "none": null}`,
+		},
+		{
+			name: "json top-level array",
+			file: "list.json",
+			data: `[1, 2]`,
+			edit: nodes.DeleteOp{Path: nodes.Path{{Key: "0", IsIndex: true}}},
+			want: `[2]`,
+		},
+		{
+			name: "json new value is inferred",
+			file: "conf.json",
+			data: `{"a": "x"}`,
+			edit: nodes.SetOp{Path: nodes.Path{{Key: "b"}}, Value: "true"},
+			want: `{"a": "x", "b": true}`,
+		},
+		{
+			name: "json string reading [] stays a string",
+			file: "conf.json",
+			data: `{"a": "[]", "b": []}`,
+			edit: nodes.SetOp{Path: nodes.Path{{Key: "c"}}, Value: "{}"},
+			want: `{"a": "[]", "b": [], "c": "{}"}`,
+		},
+		{
+			name: "yaml string stays a string",
+			file: "conf.yaml",
+			data: "# app\nversion: \"1\" # release\nreplicas: 2\n",
+			edit: nodes.SetOp{Path: nodes.Path{{Key: "version"}}, Value: "2"},
+			want: "# app\nversion: \"2\" # release\nreplicas: 2\n",
+		},
+		{
+			name: "yaml anchors are encoded whole",
+			file: "conf.yaml",
+			data: "base: &b 1\ncopy: *b\n",
+			edit: nodes.SetOp{Path: nodes.Path{{Key: "copy"}}, Value: "2"},
+			want: "base: 1\ncopy: 2\n",
+			note: layoutLost,
+		},
+		{
+			name: "yaml top-level sequence",
+			file: "list.yaml",
+			data: "# items\n- a\n- b\n",
+			edit: nodes.SetOp{Path: nodes.Path{{Key: "1", IsIndex: true}}, Value: "c"},
+			want: "# items\n- a\n- c\n",
+		},
+		{
+			name: "toml emptied table",
+			file: "conf.toml",
+			data: "[server]\nport = 80\n",
+			edit: nodes.DeleteOp{Path: nodes.Path{{Key: "server"}, {Key: "port"}}},
+			want: "[server]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(p, []byte(tt.data), 0o600))
+			m, err := format.Parse([]byte(tt.data))
+			require.NoError(t, err)
+			root := nodes.New(m, 0, nodes.LeafValuesOnly)
+			_, err = tt.edit.Apply(root, nil)
+			require.NoError(t, err)
+
+			save, ok := fileSaver(p)
+			require.True(t, ok)
+			note, err := save(root)
+			require.NoError(t, err)
+			assert.Equal(t, tt.note, note)
+
+			got, err := os.ReadFile(p)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+			bak, err := os.ReadFile(p + ".bak")
+			require.NoError(t, err)
+			assert.Equal(t, tt.data, string(bak))
+			st, err := os.Stat(p)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o600), st.Mode().Perm())
+		})
+	}
+}
+
+func TestFileSaverUnsupported(t *testing.T) {
+	for _, f := range []string{"conf.jsonnet", "data.txt", "noext"} {
+		_, ok := fileSaver(f)
+		assert.False(t, ok, f)
+	}
+}
+
+func TestEncodeData(t *testing.T) {
+	// a document the in-place writer cannot read is an error, not re-encoded
+	_, _, err := encodeData(map[string]any{"a": 1}, []byte(`{"a": `), format.FormatJson)
+	assert.Error(t, err)
+
+	b, reencoded, err := encodeData(map[string]any{"a": 5}, []byte(`{"a": 1, "a": 2}`), format.FormatJson)
+	require.NoError(t, err)
+	assert.True(t, reencoded)
+	assert.JSONEq(t, `{"a": 5}`, string(b))
+}
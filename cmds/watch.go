@@ -0,0 +1,73 @@
+package cmds
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"time"
+)
+
+// defaultWatchInterval is how often watched files are polled when --interval
+// is not given.
+const defaultWatchInterval = time.Second
+
+// fileWatcher detects changes to a set of files by polling. A file whose
+// modification time or size moved is re-hashed, and only counts as changed
+// when its content hash differs, so touching a file does not trigger a reload.
+type fileWatcher struct {
+	files map[string]*fileStamp
+}
+
+type fileStamp struct {
+	mod  time.Time
+	size int64
+	sum  [sha256.Size]byte
+}
+
+// newFileWatcher records the current state of paths.
+func newFileWatcher(paths ...string) (*fileWatcher, error) {
+	w := &fileWatcher{files: make(map[string]*fileStamp, len(paths))}
+	for _, p := range paths {
+		stamp, err := stampFile(p)
+		if err != nil {
+			return nil, err
+		}
+		w.files[p] = stamp
+	}
+	return w, nil
+}
+
+// Changed reports whether any watched file changed since the last call.
+func (w *fileWatcher) Changed() (bool, error) {
+	changed := false
+	for p, prev := range w.files {
+		st, err := os.Stat(p)
+		if err != nil {
+			return false, fmt.Errorf("failed to stat %s: %w", p, err)
+		}
+		if st.ModTime().Equal(prev.mod) && st.Size() == prev.size {
+			continue
+		}
+		stamp, err := stampFile(p)
+		if err != nil {
+			return false, err
+		}
+		if stamp.sum != prev.sum {
+			changed = true
+		}
+		w.files[p] = stamp
+	}
+	return changed, nil
+}
+
+func stampFile(p string) (*fileStamp, error) {
+	st, err := os.Stat(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", p, err)
+	}
+	b, err := readFile(p)
+	if err != nil {
+		return nil, err
+	}
+	return &fileStamp{mod: st.ModTime(), size: st.Size(), sum: sha256.Sum256(b)}, nil
+}
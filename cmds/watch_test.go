@@ -0,0 +1,48 @@
+package cmds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileWatcher(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "conf.yaml")
+	require.NoError(t, os.WriteFile(p, []byte("a: 1\n"), 0o600))
+	w, err := newFileWatcher(p)
+	require.NoError(t, err)
+
+	changed, err := w.Changed()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// touching the file without changing its content is not a change
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(p, later, later))
+	changed, err = w.Changed()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// same size, new content and mtime
+	require.NoError(t, os.WriteFile(p, []byte("a: 2\n"), 0o600))
+	require.NoError(t, os.Chtimes(p, later.Add(time.Minute), later.Add(time.Minute)))
+	changed, err = w.Changed()
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	// the change is only reported once
+	changed, err = w.Changed()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	require.NoError(t, os.Remove(p))
+	_, err = w.Changed()
+	assert.Error(t, err)
+
+	_, err = newFileWatcher(p)
+	assert.Error(t, err)
+}
@@ -1,6 +1,6 @@
 // Command wndr explores a tree data file with a TUI graphical interface.
 //
-// It takes in a tree data file (JSON, YAML, or TOML) either via the -f flag,
+// It takes in a tree data file (JSON, YAML, TOML, or Jsonnet) either via the -f flag,
 // as the first argument, or piped through stdin, and produces a navigable tree
 // to view and explore the data. See [github.com/crosleyzack/wndr/cmds] for the
 // command definitions and the main entry point.
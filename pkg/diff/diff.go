@@ -12,7 +12,6 @@ package diff
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/crosleyzack/wndr/pkg/nodes"
 	"github.com/google/uuid"
@@ -93,29 +92,12 @@ func createDiffTree(trees []*nodes.Node, conf *diffConf) (*nodes.Node, error) {
 		return nil, fmt.Errorf("need one key per tree: got %d keys for %d trees", len(keys), len(trees))
 	}
 	diffTree := nodes.New(map[string]any{}, 0, nodes.EmptyRepr)
-	// pruned holds diff-tree paths whose whole subtree is already recorded as a
-	// difference. DFSMulti still walks the descendants of any tree that has
-	// them, so we skip every path under a pruned prefix to avoid recording the
-	// same difference twice.
-	pruned := make(map[string]bool)
-	isPruned := func(path []string) bool {
-		for i := 1; i <= len(path); i++ {
-			if pruned[strings.Join(path[:i], "\x00")] {
-				return true
-			}
-		}
-		return false
-	}
 	err := nodes.DFSMulti(
 		func(path []string, cnodes []nodes.ChildNode) error {
 			// the sentinel root holds no value to compare; always descend.
 			if len(path) == 0 {
 				return nil
 			}
-			// skip anything under a difference we have already recorded.
-			if isPruned(path) {
-				return nil
-			}
 			// resolve each tree's node at this path (nil when the path is
 			// absent from that tree).
 			resolved := make([]*nodes.Node, len(cnodes))
@@ -124,6 +106,11 @@ func createDiffTree(trees []*nodes.Node, conf *diffConf) (*nodes.Node, error) {
 					resolved[i] = cn.Node
 				}
 			}
+			// subtrees holding the same data in every tree have nothing to
+			// record, however deep they are.
+			if identical(resolved) {
+				return nodes.SkipChildren
+			}
 			// when every tree has an equivalent node there is no difference at
 			// this level; let DFSMulti descend to compare their children.
 			if nodesEquivalent(resolved...) {
@@ -139,7 +126,8 @@ func createDiffTree(trees []*nodes.Node, conf *diffConf) (*nodes.Node, error) {
 			}
 			// record the difference: each tree contributes its node (or the nil
 			// value when the path is absent) under its key, then the whole
-			// subtree is pruned so it is not expanded further.
+			// subtree is skipped so the same difference is not recorded again
+			// further down.
 			var err error
 			for i, node := range resolved {
 				add := copyNode(node)
@@ -153,8 +141,7 @@ func createDiffTree(trees []*nodes.Node, conf *diffConf) (*nodes.Node, error) {
 				}
 			}
 			// the whole subtree at this path is now recorded; do not expand it.
-			pruned[strings.Join(path, "\x00")] = true
-			return nil
+			return nodes.SkipChildren
 		},
 		trees...,
 	)
@@ -164,6 +151,17 @@ func createDiffTree(trees []*nodes.Node, conf *diffConf) (*nodes.Node, error) {
 	return diffTree, nil
 }
 
+// identical reports whether every tree has a node at this path and they all
+// hold the same data, comparing the hashes of their subtrees.
+func identical(ns []*nodes.Node) bool {
+	for _, n := range ns {
+		if n == nil || !nodes.DeepEqual(ns[0], n) {
+			return false
+		}
+	}
+	return true
+}
+
 // nodesEquivalent reports whether every given node is equivalent to the others.
 // We only compare the key and value of the nodes, not their children; the
 // children are compared separately while traversing the tree. Fewer than two
@@ -292,24 +290,13 @@ func updateRepr(tree *nodes.Node, f nodes.ReprNode) error {
 	)
 }
 
-// copyNode returns a shallow copy of n. This will corrupt the original tree,
-// but this is fine for our use case and keeps the code efficient.
+// copyNode returns a deep copy of n, so attaching it to the diff tree and
+// rewriting its summaries leaves the input tree untouched.
 func copyNode(n *nodes.Node) *nodes.Node {
 	if n == nil {
 		return nil
 	}
-	c := &nodes.Node{
-		ID:       uuid.New(),
-		Key:      n.Key,
-		Value:    n.Value,
-		Expand:   n.Expand,
-		Parent:   n.Parent,
-		Children: n.Children,
-	}
-	for _, child := range c.Children.Iter() {
-		child.Parent = c
-	}
-	return c
+	return nodes.Clone(n)
 }
 
 // addMeta
@@ -512,6 +512,20 @@ func TestEveryNodeHasSentinelAncestor(t *testing.T) {
 	}
 }
 
+func TestDiffLeavesInputsUntouched(t *testing.T) {
+	a := nodes.New(map[string]any{"a": map[string]any{"b": 1}}, 0, nodes.LeafValuesOnly)
+	b := nodes.New(map[string]any{"a": 5}, 0, nodes.LeafValuesOnly)
+	before := nodes.ToValue(a)
+	sub := nodes.Child(a, "a")
+	summary := sub.Value
+
+	_, err := Diff([]*nodes.Node{a, b}, WithKeys("a", "b"))
+	require.NoError(t, err)
+	assert.Equal(t, before, nodes.ToValue(a))
+	assert.Equal(t, summary, sub.Value)
+	assert.Same(t, sub, nodes.Child(sub, "b").Parent)
+}
+
 func TestCreateDiffTreeKeyCountMismatch(t *testing.T) {
 	// passing fewer keys than trees must fail rather than index past the keys
 	// slice.
@@ -0,0 +1,563 @@
+// Package cst edits JSON, YAML and TOML documents in place. A document is
+// parsed into a concrete syntax tree that records where every key and value
+// sits in the source, so a change only rewrites the bytes of the values it
+// touches. Comments, key order, quoting and indentation elsewhere are kept
+// byte for byte.
+package cst
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+	yaml "github.com/goccy/go-yaml"
+)
+
+var (
+	// ErrUnsupported is returned for documents and changes the writer cannot
+	// make without rewriting more than the values changed, such as YAML
+	// anchors or adding to a TOML table only defined through its sub-tables.
+	// Callers fall back to encoding the whole document.
+	ErrUnsupported = errors.New("cannot be edited in place")
+	// ErrNotFound is returned when a path does not lead to a value
+	ErrNotFound = errors.New("no value at path")
+)
+
+type kind int
+
+const (
+	scalarKind kind = iota
+	objectKind
+	arrayKind
+)
+
+type style int
+
+const (
+	// flowStyle containers are bracketed: JSON, YAML flow collections and
+	// TOML arrays and inline tables
+	flowStyle style = iota
+	// blockStyle containers are YAML block mappings and sequences, one entry
+	// per line
+	blockStyle
+	// tableStyle containers are TOML tables and arrays of tables, made of
+	// key/value lines and [table] sections
+	tableStyle
+)
+
+// node is a value in the document.
+type node struct {
+	kind  kind
+	style style
+	// start and end bound the value's text. They are unset for TOML tables,
+	// which can be spread over the document.
+	start, end int
+	entries    []*entry
+	// insertAt is where a new block or table entry is written, or -1 when
+	// one cannot be added
+	insertAt int
+	// prefix is written before the key of a new TOML entry, naming a table
+	// defined through dotted keys
+	prefix string
+}
+
+// entry is a key and its value in an object, or an element of an array,
+// keyed by its index.
+type entry struct {
+	key   string
+	value *node
+	// start and end bound the whole entry: from its key, or the value or
+	// "- " of an array element, to the end of its value. Entries written on
+	// their own lines end after the newline.
+	start, end int
+	// sep is the end of the key and separator; a value of a new shape is
+	// written after it
+	sep int
+}
+
+// index returns the position of the entry for key, or -1.
+func (n *node) index(key string) int {
+	for i, e := range n.entries {
+		if e.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Doc is a parsed document that can be changed in place.
+type Doc struct {
+	src    []byte
+	format format.FormatType
+	root   *node
+	// items are the TOML key/value lines and sections, by the path they
+	// define
+	items []item
+}
+
+// item is the source of a TOML key/value line or section.
+type item struct {
+	path       []string
+	start, end int
+	// section is set for a [table] section, which spans its header and
+	// key/value lines
+	section bool
+}
+
+// Parse parses src, written in the format f.
+func Parse(src []byte, f format.FormatType) (*Doc, error) {
+	d := &Doc{src: slices.Clone(src), format: f}
+	if err := d.parse(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Doc) parse() error {
+	var err error
+	d.items = nil
+	switch d.format {
+	case format.FormatJson:
+		d.root, err = parseJSON(d.src)
+	case format.FormatYaml:
+		d.root, err = parseYAML(d.src)
+	case format.FormatToml:
+		d.root, d.items, err = parseTOML(d.src)
+	default:
+		err = fmt.Errorf("unknown format %d", d.format)
+	}
+	return err
+}
+
+// Bytes returns the document with the changes made so far.
+func (d *Doc) Bytes() []byte {
+	return slices.Clone(d.src)
+}
+
+// Set changes the value at path to value, adding it when the last key is new
+// to its object or is the length of its array.
+func (d *Doc) Set(path []string, value any) error {
+	if len(path) == 0 {
+		return fmt.Errorf("replacing the whole document: %w", ErrUnsupported)
+	}
+	parent, err := d.find(path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	key := path[len(path)-1]
+	if i := parent.index(key); i >= 0 {
+		err = d.replace(parent, parent.entries[i], value)
+	} else if parent.kind == objectKind || (parent.kind == arrayKind && key == strconv.Itoa(len(parent.entries))) {
+		err = d.add(parent, key, value)
+	} else {
+		return fmt.Errorf("%q: %w", path, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%q: %w", path, err)
+	}
+	return d.parse()
+}
+
+// Delete removes the value at path. Later elements of an array move down.
+func (d *Doc) Delete(path []string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("deleting the whole document: %w", ErrUnsupported)
+	}
+	parent, err := d.find(path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	i := parent.index(path[len(path)-1])
+	if i < 0 {
+		return fmt.Errorf("%q: %w", path, ErrNotFound)
+	}
+	if err := d.remove(parent, i, path); err != nil {
+		return fmt.Errorf("%q: %w", path, err)
+	}
+	return d.parse()
+}
+
+// Line returns the line, counting from 1, that the value at path is written
+// on: the line of its key, or of the start of an array element. The root is
+// on line 1.
+func (d *Doc) Line(path []string) (int, error) {
+	if len(path) == 0 {
+		return 1, nil
+	}
+	parent, err := d.find(path[:len(path)-1])
+	if err != nil {
+		return 0, err
+	}
+	i := parent.index(path[len(path)-1])
+	if i < 0 {
+		return 0, fmt.Errorf("%q: %w", path, ErrNotFound)
+	}
+	return 1 + bytes.Count(d.src[:parent.entries[i].start], []byte("\n")), nil
+}
+
+// find returns the container at path.
+func (d *Doc) find(path []string) (*node, error) {
+	n := d.root
+	for i, key := range path {
+		j := n.index(key)
+		if j < 0 {
+			return nil, fmt.Errorf("%q: %w", path[:i+1], ErrNotFound)
+		}
+		n = n.entries[j].value
+	}
+	if n.kind == scalarKind {
+		return nil, fmt.Errorf("%q is not an object or array: %w", path, ErrNotFound)
+	}
+	return n, nil
+}
+
+// splice replaces src[start:end] with text.
+func (d *Doc) splice(start, end int, text string) {
+	d.src = slices.Concat(d.src[:start], []byte(text), d.src[end:])
+}
+
+func (d *Doc) replace(parent *node, e *entry, value any) error {
+	switch d.format {
+	case format.FormatYaml:
+		return d.replaceYAML(parent, e, value)
+	case format.FormatToml:
+		if e.value.style == tableStyle {
+			return fmt.Errorf("replacing a table: %w", ErrUnsupported)
+		}
+	}
+	text, err := d.inline(value, lineIndent(d.src, e.value.start), multiline(d.src, parent))
+	if err != nil {
+		return err
+	}
+	d.splice(e.value.start, e.value.end, text)
+	return nil
+}
+
+func (d *Doc) add(parent *node, key string, value any) error {
+	switch parent.style {
+	case blockStyle:
+		return d.addYAML(parent, key, value)
+	case tableStyle:
+		return d.addTOML(parent, key, value)
+	}
+	return d.addFlow(parent, key, value)
+}
+
+func (d *Doc) remove(parent *node, i int, path []string) error {
+	switch parent.style {
+	case blockStyle:
+		return d.removeYAML(parent, i, path)
+	case tableStyle:
+		return d.removeTOML(path)
+	}
+	d.removeFlow(parent, i)
+	return nil
+}
+
+// addFlow adds an entry at the end of a bracketed container, separated from
+// the previous one the way it is separated from the one before, and with its
+// key separated from its value the way the previous one's is.
+func (d *Doc) addFlow(parent *node, key string, value any) error {
+	indent := lineIndent(d.src, parent.start)
+	sep, end := "", ""
+	assign := d.assign()
+	at := parent.start + 1
+	if len(parent.entries) > 0 {
+		last := parent.entries[len(parent.entries)-1]
+		if parent.kind == objectKind {
+			assign = d.flowAssign(last)
+		}
+		ws := last.start
+		for ws > parent.start+1 && isSpace(d.src[ws-1]) {
+			ws--
+		}
+		sep = "," + string(d.src[ws:last.start])
+		if len(parent.entries) == 1 && !strings.Contains(sep, "\n") {
+			// nothing separates the only entry from its bracket; it is
+			// compact when its key and value are
+			sep = ", "
+			if parent.kind == objectKind && strings.TrimSpace(assign) == assign {
+				sep = ","
+			}
+		}
+		indent = lineIndent(d.src, last.start)
+		at = last.end
+	} else if d.format == format.FormatToml && parent.kind == objectKind {
+		// inline tables are written { a = 1 }
+		sep, end = " ", " "
+	}
+	text, err := d.inline(value, indent, multiline(d.src, parent))
+	if err != nil {
+		return err
+	}
+	if parent.kind == objectKind {
+		text = d.keyText(key) + assign + text
+	}
+	d.splice(at, at, sep+text+end)
+	return nil
+}
+
+// flowAssign returns the separator between the key and value of the object
+// entry e, such as ": " or ":", or the default one when the value is on a
+// line of its own.
+func (d *Doc) flowAssign(e *entry) string {
+	i := e.sep
+	for i > e.start && isSpace(d.src[i-1]) {
+		i--
+	}
+	// step over the : or =
+	i--
+	for i > e.start && isSpace(d.src[i-1]) {
+		i--
+	}
+	assign := string(d.src[i:e.sep])
+	if strings.Contains(assign, "\n") {
+		return d.assign()
+	}
+	return assign
+}
+
+// removeFlow removes an entry from a bracketed container along with the comma
+// separating it from its neighbour.
+func (d *Doc) removeFlow(parent *node, i int) {
+	e := parent.entries[i]
+	switch {
+	case len(parent.entries) == 1:
+		empty := "{}"
+		if parent.kind == arrayKind {
+			empty = "[]"
+		}
+		d.splice(parent.start, parent.end, empty)
+	case i < len(parent.entries)-1:
+		d.splice(e.start, parent.entries[i+1].start, "")
+	default:
+		d.splice(parent.entries[i-1].end, e.end, "")
+	}
+}
+
+// inline returns value written as a single value in the document's format.
+// Containers are spread over lines, starting at indent, when multi is set
+// and the format allows it.
+func (d *Doc) inline(value any, indent string, multi bool) (string, error) {
+	switch d.format {
+	case format.FormatJson:
+		return jsonText(value, indent, multi)
+	case format.FormatYaml:
+		return yamlInline(value)
+	default:
+		return tomlInline(value)
+	}
+}
+
+func (d *Doc) keyText(key string) string {
+	switch d.format {
+	case format.FormatJson:
+		b, _ := json.Marshal(key)
+		return string(b)
+	case format.FormatYaml:
+		return yamlKey(key)
+	default:
+		return tomlKey(key)
+	}
+}
+
+func (d *Doc) assign() string {
+	if d.format == format.FormatToml {
+		return " = "
+	}
+	return ": "
+}
+
+// Rewrite returns src, written in the format f, changed to hold value. Only
+// the values that differ are rewritten. ErrUnsupported is returned when that
+// cannot be done, in which case the document must be encoded whole.
+func Rewrite(src []byte, f format.FormatType, value any) ([]byte, error) {
+	d, err := Parse(src, f)
+	if err != nil {
+		return nil, err
+	}
+	old, err := decode(src, f)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.rewrite(nil, old, value); err != nil {
+		return nil, err
+	}
+	// the result must read back as value; anything else is a shortcoming of
+	// the writer and is reported rather than written
+	got, err := decode(d.src, f)
+	if err != nil || !sameData(got, value) {
+		return nil, fmt.Errorf("result does not match: %w", ErrUnsupported)
+	}
+	return d.Bytes(), nil
+}
+
+func (d *Doc) rewrite(path []string, old, value any) error {
+	switch o := old.(type) {
+	case map[string]any:
+		if v, ok := value.(map[string]any); ok {
+			return d.rewriteMap(path, o, v)
+		}
+	case []any:
+		if v, ok := value.([]any); ok {
+			return d.rewriteArray(path, o, v)
+		}
+	}
+	if reflect.DeepEqual(old, value) {
+		return nil
+	}
+	return d.Set(path, value)
+}
+
+func (d *Doc) rewriteMap(path []string, old, value map[string]any) error {
+	keys := make([]string, 0, len(old)+len(value))
+	for k := range old {
+		keys = append(keys, k)
+	}
+	for k := range value {
+		if _, ok := old[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	// removing first leaves the added keys next to the ones kept
+	for _, k := range keys {
+		if _, ok := value[k]; !ok {
+			if err := d.Delete(child(path, k)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, k := range keys {
+		v, ok := value[k]
+		if !ok {
+			continue
+		}
+		o, had := old[k]
+		if !had {
+			if err := d.Set(child(path, k), v); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := d.rewrite(child(path, k), o, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Doc) rewriteArray(path []string, old, value []any) error {
+	if len(value) == len(old)-1 {
+		// a single element removed is deleted rather than every later
+		// element rewritten
+		i := 0
+		for i < len(value) && reflect.DeepEqual(old[i], value[i]) {
+			i++
+		}
+		if reflect.DeepEqual(old[i+1:], value[i:]) {
+			return d.Delete(child(path, strconv.Itoa(i)))
+		}
+	}
+	for i := range min(len(old), len(value)) {
+		if err := d.rewrite(child(path, strconv.Itoa(i)), old[i], value[i]); err != nil {
+			return err
+		}
+	}
+	for i := len(old) - 1; i >= len(value); i-- {
+		if err := d.Delete(child(path, strconv.Itoa(i))); err != nil {
+			return err
+		}
+	}
+	for i := len(old); i < len(value); i++ {
+		if err := d.Set(child(path, strconv.Itoa(i)), value[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func child(path []string, key string) []string {
+	return append(slices.Clone(path), key)
+}
+
+// decode reads src the way the format's own decoder does.
+func decode(src []byte, f format.FormatType) (any, error) {
+	var v any
+	var err error
+	switch f {
+	case format.FormatJson:
+		err = json.Unmarshal(src, &v)
+	case format.FormatYaml:
+		err = yaml.Unmarshal(src, &v)
+	default:
+		var m map[string]any
+		m, err = format.ParseToml(src)
+		v = m
+	}
+	return v, err
+}
+
+// sameData reports whether a and b hold the same data, ignoring differences
+// in the Go types decoders pick for numbers.
+func sameData(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	var na, nb any
+	_ = json.Unmarshal(ja, &na)
+	_ = json.Unmarshal(jb, &nb)
+	return reflect.DeepEqual(na, nb)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// lineStart returns the offset of the start of the line holding pos.
+func lineStart(src []byte, pos int) int {
+	for pos > 0 && src[pos-1] != '\n' {
+		pos--
+	}
+	return pos
+}
+
+// lineEnd returns the offset just past the newline ending the line holding
+// pos, or the end of src.
+func lineEnd(src []byte, pos int) int {
+	for pos < len(src) && src[pos] != '\n' {
+		pos++
+	}
+	if pos < len(src) {
+		pos++
+	}
+	return pos
+}
+
+// lineIndent returns the leading whitespace of the line holding pos.
+func lineIndent(src []byte, pos int) string {
+	start := lineStart(src, pos)
+	end := start
+	for end < len(src) && (src[end] == ' ' || src[end] == '\t') {
+		end++
+	}
+	return string(src[start:end])
+}
+
+// multiline reports whether n is a bracketed container written over several
+// lines, so new entries should be too.
+func multiline(src []byte, n *node) bool {
+	if n.style != flowStyle || n.end <= n.start {
+		return false
+	}
+	return slices.Contains(src[n.start:n.end], '\n')
+}
@@ -0,0 +1,326 @@
+package cst
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// edit is a change made to a document: a Set when value is given, otherwise
+// a Delete.
+type edit struct {
+	path  []string
+	value any
+	del   bool
+}
+
+func TestEdits(t *testing.T) {
+	tests := []struct {
+		name   string
+		format format.FormatType
+		src    string
+		edits  []edit
+		want   string
+	}{
+		{
+			name:   "json set keeps layout",
+			format: format.FormatJson,
+			src:    "{\n  \"name\": \"app\",   \"port\": 80,\n  \"tags\": [\"a\", \"b\"]\n}\n",
+			edits:  []edit{{path: []string{"port"}, value: 8080}},
+			want:   "{\n  \"name\": \"app\",   \"port\": 8080,\n  \"tags\": [\"a\", \"b\"]\n}\n",
+		},
+		{
+			name:   "json add to multi-line object",
+			format: format.FormatJson,
+			src:    "{\n  \"a\": 1,\n  \"b\": 2\n}\n",
+			edits:  []edit{{path: []string{"c"}, value: map[string]any{"d": true}}},
+			want:   "{\n  \"a\": 1,\n  \"b\": 2,\n  \"c\": {\n    \"d\": true\n  }\n}\n",
+		},
+		{
+			name:   "json add to inline array",
+			format: format.FormatJson,
+			src:    `{"tags": ["a", "b"]}`,
+			edits:  []edit{{path: []string{"tags", "2"}, value: "c"}},
+			want:   `{"tags": ["a", "b", "c"]}`,
+		},
+		{
+			name:   "json add to compact object",
+			format: format.FormatJson,
+			src:    `{"a":"x","b":{"c":1}}`,
+			edits: []edit{
+				{path: []string{"port"}, value: 1},
+				{path: []string{"b", "d"}, value: true},
+			},
+			want: `{"a":"x","b":{"c":1,"d":true},"port":1}`,
+		},
+		{
+			name:   "json delete first, last and only",
+			format: format.FormatJson,
+			src:    "{\n  \"a\": 1,\n  \"b\": [2],\n  \"c\": 3\n}",
+			edits: []edit{
+				{path: []string{"a"}, del: true},
+				{path: []string{"c"}, del: true},
+				{path: []string{"b", "0"}, del: true},
+			},
+			want: "{\n  \"b\": []\n}",
+		},
+		{
+			name:   "yaml set keeps comments",
+			format: format.FormatYaml,
+			src:    "# service\nname: app # the name\nport: 80\n",
+			edits:  []edit{{path: []string{"name"}, value: "web"}},
+			want:   "# service\nname: web # the name\nport: 80\n",
+		},
+		{
+			name:   "yaml quoted string stays quoted",
+			format: format.FormatYaml,
+			src:    "version: \"1\"\n",
+			edits:  []edit{{path: []string{"version"}, value: "2"}},
+			want:   "version: \"2\"\n",
+		},
+		{
+			name:   "yaml add key to nested mapping",
+			format: format.FormatYaml,
+			src:    "spec:\n  replicas: 1\n  # trailing comment\nother: x\n",
+			edits:  []edit{{path: []string{"spec", "image"}, value: "nginx"}},
+			want:   "spec:\n  replicas: 1\n  image: nginx\n  # trailing comment\nother: x\n",
+		},
+		{
+			name:   "yaml add collection",
+			format: format.FormatYaml,
+			src:    "a: 1\n",
+			edits:  []edit{{path: []string{"b"}, value: map[string]any{"c": []any{"x"}}}},
+			want:   "a: 1\nb:\n  c:\n  - x\n",
+		},
+		{
+			name:   "yaml sequences",
+			format: format.FormatYaml,
+			src:    "ports:\n- 80 # http\n- 443\nnames: [a, b]\n",
+			edits: []edit{
+				{path: []string{"ports", "2"}, value: 8080},
+				{path: []string{"ports", "0"}, del: true},
+				{path: []string{"names", "1"}, del: true},
+			},
+			want: "ports:\n- 443\n- 8080\nnames: [a]\n",
+		},
+		{
+			name:   "yaml compact mapping in sequence",
+			format: format.FormatYaml,
+			src:    "items:\n  - name: a\n    size: 1\n  - name: b\n",
+			edits: []edit{
+				{path: []string{"items", "0", "name"}, del: true},
+				{path: []string{"items", "1", "size"}, value: 2},
+			},
+			want: "items:\n  - size: 1\n  - name: b\n    size: 2\n",
+		},
+		{
+			name:   "yaml emptied mapping",
+			format: format.FormatYaml,
+			src:    "env:\n  A: 1\nname: x\n",
+			edits:  []edit{{path: []string{"env", "A"}, del: true}},
+			want:   "env: {}\nname: x\n",
+		},
+		{
+			name:   "yaml block scalar replaced",
+			format: format.FormatYaml,
+			src:    "script: |\n  echo a\n  echo b\nafter: 1\n",
+			edits:  []edit{{path: []string{"script"}, value: "true"}},
+			want:   "script: \"true\"\nafter: 1\n",
+		},
+		{
+			name:   "yaml null gets a value",
+			format: format.FormatYaml,
+			src:    "a:\nb: 1\n",
+			edits:  []edit{{path: []string{"a"}, value: []any{1}}},
+			want:   "a:\n  - 1\nb: 1\n",
+		},
+		{
+			name:   "toml set and add",
+			format: format.FormatToml,
+			src:    "# config\ntitle = \"x\" # the title\n\n[server]\nport = 80\n\n[db]\nhost = \"h\"\n",
+			edits: []edit{
+				{path: []string{"title"}, value: "y"},
+				{path: []string{"server", "tls"}, value: true},
+				{path: []string{"debug"}, value: false},
+			},
+			want: "# config\ntitle = \"y\" # the title\ndebug = false\n\n[server]\nport = 80\ntls = true\n\n[db]\nhost = \"h\"\n",
+		},
+		{
+			name:   "toml delete section",
+			format: format.FormatToml,
+			src:    "[a]\nx = 1\n\n[a.sub]\ny = 2\n\n[b]\nz = 3\n",
+			edits:  []edit{{path: []string{"a"}, del: true}},
+			want:   "[b]\nz = 3\n",
+		},
+		{
+			name:   "toml dotted keys and inline tables",
+			format: format.FormatToml,
+			src:    "a.b = 1\npoint = { x = 1, y = 2 }\nlist = [\n  1, # one\n  2,\n]\n",
+			edits: []edit{
+				{path: []string{"a", "c"}, value: 3},
+				{path: []string{"point", "y"}, del: true},
+				{path: []string{"list", "2"}, value: 3},
+			},
+			want: "a.b = 1\na.c = 3\npoint = { x = 1 }\nlist = [\n  1, # one\n  2,\n  3,\n]\n",
+		},
+		{
+			name:   "toml array of tables",
+			format: format.FormatToml,
+			src:    "[[item]]\nname = \"a\"\n\n[[item]]\nname = \"b\"\n",
+			edits: []edit{
+				{path: []string{"item", "0"}, del: true},
+				{path: []string{"item", "0", "name"}, value: "c"},
+			},
+			want: "[[item]]\nname = \"c\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Parse([]byte(tt.src), tt.format)
+			require.NoError(t, err)
+			for _, e := range tt.edits {
+				if e.del {
+					require.NoError(t, d.Delete(e.path), "%q", e.path)
+				} else {
+					require.NoError(t, d.Set(e.path, e.value), "%q", e.path)
+				}
+			}
+			assert.Equal(t, tt.want, string(d.Bytes()))
+		})
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		format format.FormatType
+		src    string
+		value  any
+		want   string
+	}{
+		{
+			name:   "json",
+			format: format.FormatJson,
+			src:    "{\n    \"keep\": [1, 2,   3],\n    \"drop\": null,\n    \"n\": 1.50\n}\n",
+			value:  map[string]any{"keep": []any{1.0, 2.0, 3.0}, "n": 2.5, "new": "x"},
+			want:   "{\n    \"keep\": [1, 2,   3],\n    \"n\": 2.5,\n    \"new\": \"x\"\n}\n",
+		},
+		{
+			name:   "json top-level array",
+			format: format.FormatJson,
+			src:    `[1, 2, 3]`,
+			value:  []any{1.0, 3.0},
+			want:   `[1, 3]`,
+		},
+		{
+			name:   "yaml removed array element",
+			format: format.FormatYaml,
+			src:    "# hosts\nhosts:\n  - a # first\n  - b\n  - c # last\n",
+			value:  map[string]any{"hosts": []any{"a", "c"}},
+			want:   "# hosts\nhosts:\n  - a # first\n  - c # last\n",
+		},
+		{
+			name:   "toml",
+			format: format.FormatToml,
+			src:    "[server] # main\nport = 80\nhost = 'localhost'\n",
+			value:  map[string]any{"server": map[string]any{"port": int64(81), "host": "localhost"}},
+			want:   "[server] # main\nport = 81\nhost = 'localhost'\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Rewrite([]byte(tt.src), tt.format, tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestRewriteUnsupported(t *testing.T) {
+	tests := []struct {
+		name   string
+		format format.FormatType
+		src    string
+		value  any
+	}{
+		{name: "yaml anchors", format: format.FormatYaml, src: "a: &x 1\nb: *x\n", value: map[string]any{"a": 2}},
+		{name: "yaml documents", format: format.FormatYaml, src: "a: 1\n---\nb: 2\n", value: map[string]any{"a": 2}},
+		{name: "yaml duplicate keys", format: format.FormatYaml, src: "a: 1\na: 2\n", value: map[string]any{"a": 3}},
+		{name: "toml table from sub-tables", format: format.FormatToml, src: "[a.b]\nx = 1\n", value: map[string]any{"a": map[string]any{"b": map[string]any{"x": 1}, "c": 2}}},
+		{name: "toml null", format: format.FormatToml, src: "a = 1\n", value: map[string]any{"a": nil}},
+		{name: "toml emptied dotted table", format: format.FormatToml, src: "a.b = 1\n", value: map[string]any{"a": map[string]any{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Rewrite([]byte(tt.src), tt.format, tt.value)
+			assert.ErrorIs(t, err, ErrUnsupported)
+		})
+	}
+}
+
+func TestEditErrors(t *testing.T) {
+	d, err := Parse([]byte(`{"a": {"b": 1}, "list": [1]}`), format.FormatJson)
+	require.NoError(t, err)
+	assert.ErrorIs(t, d.Set([]string{"nope", "x"}, 1), ErrNotFound)
+	assert.ErrorIs(t, d.Set([]string{"a", "b", "c"}, 1), ErrNotFound)
+	assert.ErrorIs(t, d.Set([]string{"list", "5"}, 1), ErrNotFound)
+	assert.ErrorIs(t, d.Delete([]string{"a", "x"}), ErrNotFound)
+	assert.ErrorIs(t, d.Delete(nil), ErrUnsupported)
+
+	for _, src := range []string{`{"a": 1`, `{"a" 1}`, `{"a": 1} x`, `{a: 1}`} {
+		_, err := Parse([]byte(src), format.FormatJson)
+		assert.Error(t, err, src)
+	}
+	_, err = Parse([]byte("a: 1\n  b: 2\n"), format.FormatYaml)
+	assert.Error(t, err)
+	_, err = Parse([]byte("[a]\nx = 1\n[a]\ny = 2\n"), format.FormatToml)
+	assert.Error(t, err)
+}
+
+func TestLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		format format.FormatType
+		src    string
+		lines  map[string]int
+	}{
+		{
+			name:   "json",
+			format: format.FormatJson,
+			src:    "{\n  \"a\": 1,\n  \"b\": {\n    \"c\": [\n      true,\n      false\n    ]\n  }\n}\n",
+			lines:  map[string]int{"": 1, "a": 2, "b": 3, "b.c": 4, "b.c.1": 6},
+		},
+		{
+			name:   "yaml",
+			format: format.FormatYaml,
+			src:    "# values\na: 1\nb:\n  # nested\n  c:\n    - x\n    - y\n",
+			lines:  map[string]int{"a": 2, "b": 3, "b.c": 5, "b.c.0": 6, "b.c.1": 7},
+		},
+		{
+			name:   "toml",
+			format: format.FormatToml,
+			src:    "a = 1\n\n[b]\nc = [1, 2]\n\n[[d]]\ne = 1\n",
+			lines:  map[string]int{"a": 1, "b": 3, "b.c": 4, "d.0.e": 7},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Parse([]byte(tt.src), tt.format)
+			require.NoError(t, err)
+			for path, want := range tt.lines {
+				var p []string
+				if path != "" {
+					p = strings.Split(path, ".")
+				}
+				got, err := d.Line(p)
+				require.NoError(t, err, path)
+				assert.Equal(t, want, got, path)
+			}
+			_, err = d.Line([]string{"missing"})
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package cst
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// dialect is how a format writes bracketed containers and their scalars.
+type dialect int
+
+const (
+	jsonDialect dialect = iota
+	yamlDialect
+	tomlDialect
+)
+
+// flowParser parses bracketed containers and the scalars in them.
+type flowParser struct {
+	src     []byte
+	dialect dialect
+}
+
+func parseJSON(src []byte) (*node, error) {
+	p := &flowParser{src: src, dialect: jsonDialect}
+	pos := p.skip(0)
+	n, pos, err := p.value(pos)
+	if err != nil {
+		return nil, err
+	}
+	if pos = p.skip(pos); pos != len(src) {
+		return nil, p.errorf(pos, "unexpected text after the document")
+	}
+	return n, nil
+}
+
+func (p *flowParser) errorf(pos int, format string, args ...any) error {
+	line := 1 + strings.Count(string(p.src[:pos]), "\n")
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skip returns the position of the next character that is not whitespace
+// or, outside JSON, part of a comment.
+func (p *flowParser) skip(pos int) int {
+	for pos < len(p.src) {
+		switch c := p.src[pos]; {
+		case isSpace(c):
+			pos++
+		case c == '#' && p.dialect != jsonDialect:
+			for pos < len(p.src) && p.src[pos] != '\n' {
+				pos++
+			}
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+// value parses the value starting at pos and returns the position after it.
+func (p *flowParser) value(pos int) (*node, int, error) {
+	if pos >= len(p.src) {
+		return nil, pos, p.errorf(pos, "missing value")
+	}
+	switch p.src[pos] {
+	case '{':
+		return p.container(pos, objectKind, '}')
+	case '[':
+		return p.container(pos, arrayKind, ']')
+	}
+	end, err := p.scalar(pos)
+	if err != nil {
+		return nil, pos, err
+	}
+	return &node{kind: scalarKind, start: pos, end: end, insertAt: -1}, end, nil
+}
+
+func (p *flowParser) container(start int, k kind, closer byte) (*node, int, error) {
+	n := &node{kind: k, style: flowStyle, start: start, insertAt: -1}
+	pos := p.skip(start + 1)
+	for {
+		if pos >= len(p.src) {
+			return nil, pos, p.errorf(start, "unclosed %c", p.src[start])
+		}
+		if p.src[pos] == closer {
+			n.end = pos + 1
+			return n, n.end, nil
+		}
+		e := &entry{start: pos, key: strconv.Itoa(len(n.entries))}
+		if k == objectKind {
+			key, end, err := p.key(pos)
+			if err != nil {
+				return nil, pos, err
+			}
+			e.key = key
+			pos = p.skip(end)
+			if pos >= len(p.src) || p.src[pos] != p.assign() {
+				return nil, pos, p.errorf(pos, "expected %c after key", p.assign())
+			}
+			pos = p.skip(pos + 1)
+		}
+		e.sep = pos
+		v, end, err := p.value(pos)
+		if err != nil {
+			return nil, pos, err
+		}
+		e.value, e.end = v, end
+		n.entries = append(n.entries, e)
+		pos = p.skip(end)
+		if pos < len(p.src) && p.src[pos] == ',' {
+			pos = p.skip(pos + 1)
+		} else if pos >= len(p.src) || p.src[pos] != closer {
+			return nil, pos, p.errorf(pos, "expected , or %c", closer)
+		}
+	}
+}
+
+// peek returns the character at pos, or 0 at the end of the source.
+func (p *flowParser) peek(pos int) byte {
+	if pos >= len(p.src) {
+		return 0
+	}
+	return p.src[pos]
+}
+
+func (p *flowParser) assign() byte {
+	if p.dialect == tomlDialect {
+		return '='
+	}
+	return ':'
+}
+
+// key parses an object key and returns it with the position after it.
+func (p *flowParser) key(pos int) (string, int, error) {
+	switch c := p.src[pos]; {
+	case c == '"' || c == '\'':
+		end, err := p.quoted(pos)
+		if err != nil {
+			return "", pos, err
+		}
+		key, err := p.unquote(p.src[pos:end])
+		if err != nil {
+			return "", pos, p.errorf(pos, "bad key: %v", err)
+		}
+		if p.dialect == tomlDialect && p.peek(p.skip(end)) == '.' {
+			return "", pos, p.errorf(pos, "dotted keys in inline tables are not supported")
+		}
+		return key, end, nil
+	case p.dialect == jsonDialect:
+		return "", pos, p.errorf(pos, "expected a quoted key")
+	}
+	end := pos
+	for end < len(p.src) {
+		c := p.src[end]
+		if p.dialect == tomlDialect {
+			if !isBareKey(c) {
+				break
+			}
+		} else if c == ':' || c == ',' || c == '}' || c == '\n' {
+			break
+		}
+		end++
+	}
+	if p.dialect == tomlDialect && p.peek(p.skip(end)) == '.' {
+		return "", pos, p.errorf(pos, "dotted keys in inline tables are not supported")
+	}
+	key := strings.TrimRight(string(p.src[pos:end]), " \t")
+	if key == "" {
+		return "", pos, p.errorf(pos, "missing key")
+	}
+	return key, pos + len(key), nil
+}
+
+// scalar returns the end of the scalar starting at pos.
+func (p *flowParser) scalar(pos int) (int, error) {
+	if c := p.src[pos]; c == '"' || (c == '\'' && p.dialect != jsonDialect) {
+		return p.quoted(pos)
+	}
+	end := pos
+	for end < len(p.src) {
+		c := p.src[end]
+		if c == ',' || c == ']' || c == '}' || c == '\n' || c == '\r' {
+			break
+		}
+		if c == '#' && p.dialect != jsonDialect && end > pos && isSpace(p.src[end-1]) {
+			break
+		}
+		if p.dialect == jsonDialect && isSpace(c) {
+			break
+		}
+		end++
+	}
+	for end > pos && isSpace(p.src[end-1]) {
+		end--
+	}
+	if end == pos {
+		return pos, p.errorf(pos, "missing value")
+	}
+	return end, nil
+}
+
+// quoted returns the end of the quoted string starting at pos, which may be
+// a TOML multi-line string.
+func (p *flowParser) quoted(pos int) (int, error) {
+	q := p.src[pos]
+	if p.dialect == tomlDialect && strings.HasPrefix(string(p.src[pos:]), strings.Repeat(string(q), 3)) {
+		i := strings.Index(string(p.src[pos+3:]), strings.Repeat(string(q), 3))
+		if i < 0 {
+			return pos, p.errorf(pos, "unclosed string")
+		}
+		end := pos + 3 + i + 3
+		// up to two more quotes may end the string's content
+		for k := 0; k < 2 && end < len(p.src) && p.src[end] == q; k++ {
+			end++
+		}
+		return end, nil
+	}
+	for i := pos + 1; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '\\':
+			if q == '"' {
+				i++
+			}
+		case '\n':
+			if p.dialect != yamlDialect {
+				return pos, p.errorf(pos, "unclosed string")
+			}
+		case q:
+			if q == '\'' && p.dialect == yamlDialect && i+1 < len(p.src) && p.src[i+1] == '\'' {
+				// '' is an escaped quote
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+	}
+	return pos, p.errorf(pos, "unclosed string")
+}
+
+// unquote returns the text of a quoted key.
+func (p *flowParser) unquote(b []byte) (string, error) {
+	s := string(b)
+	if s[0] == '\'' {
+		s = s[1 : len(s)-1]
+		if p.dialect == yamlDialect {
+			s = strings.ReplaceAll(s, "''", "'")
+		}
+		return s, nil
+	}
+	var out string
+	err := json.Unmarshal(b, &out)
+	if err != nil {
+		// YAML and TOML allow escapes JSON does not, which Go shares
+		return strconv.Unquote(s)
+	}
+	return out, nil
+}
+
+func isBareKey(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// jsonText returns value as JSON. With multi set, collections are spread
+// over lines, each starting with indent.
+func jsonText(value any, indent string, multi bool) (string, error) {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if multi {
+		unit := "  "
+		if strings.Contains(indent, "\t") {
+			unit = "\t"
+		}
+		enc.SetIndent(indent, unit)
+	}
+	if err := enc.Encode(value); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
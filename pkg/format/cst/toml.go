@@ -0,0 +1,409 @@
+package cst
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tomlParser parses the key/value lines and [table] sections of a TOML
+// document, handing values to a flowParser.
+type tomlParser struct {
+	src   []byte
+	flow  *flowParser
+	root  *node
+	items []item
+	// table is the table of the current section, at path
+	table *node
+	path  []string
+	// section is the index in items of the current section, or -1 at the top
+	section int
+}
+
+func parseTOML(src []byte) (*node, []item, error) {
+	root := &node{kind: objectKind, style: tableStyle, start: -1, end: -1}
+	p := &tomlParser{
+		src:     src,
+		flow:    &flowParser{src: src, dialect: tomlDialect},
+		root:    root,
+		table:   root,
+		section: -1,
+	}
+	for pos := 0; pos < len(src); {
+		c := p.lineRest(pos)
+		var err error
+		switch {
+		case c == len(src) || src[c] == '\n' || src[c] == '\r' || src[c] == '#':
+			pos = lineEnd(src, c)
+			continue
+		case src[c] == '[':
+			pos, err = p.header(pos, c)
+		default:
+			pos, err = p.keyValue(pos, c)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return root, p.items, nil
+}
+
+func (p *tomlParser) lineRest(pos int) int {
+	for pos < len(p.src) && (p.src[pos] == ' ' || p.src[pos] == '\t') {
+		pos++
+	}
+	return pos
+}
+
+// endLine checks that only a comment follows pos on its line, and returns
+// the start of the next line.
+func (p *tomlParser) endLine(pos int) (int, error) {
+	c := p.lineRest(pos)
+	if c < len(p.src) && p.src[c] != '\n' && p.src[c] != '\r' && p.src[c] != '#' {
+		return c, p.flow.errorf(c, "unexpected text")
+	}
+	return lineEnd(p.src, c), nil
+}
+
+// keyPath parses a dotted key from pos up to a character in stop.
+func (p *tomlParser) keyPath(pos int, stop string) ([]string, int, error) {
+	var keys []string
+	for {
+		pos = p.lineRest(pos)
+		if pos == len(p.src) {
+			return nil, pos, p.flow.errorf(pos, "missing key")
+		}
+		var key string
+		switch p.src[pos] {
+		case '"', '\'':
+			end, err := p.flow.quoted(pos)
+			if err != nil {
+				return nil, pos, err
+			}
+			if key, err = p.flow.unquote(p.src[pos:end]); err != nil {
+				return nil, pos, p.flow.errorf(pos, "bad key: %v", err)
+			}
+			pos = end
+		default:
+			end := pos
+			for end < len(p.src) && isBareKey(p.src[end]) {
+				end++
+			}
+			if end == pos {
+				return nil, pos, p.flow.errorf(pos, "bad key")
+			}
+			key, pos = string(p.src[pos:end]), end
+		}
+		keys = append(keys, key)
+		pos = p.lineRest(pos)
+		if pos < len(p.src) && p.src[pos] == '.' {
+			pos++
+			continue
+		}
+		if pos == len(p.src) || !strings.ContainsRune(stop, rune(p.src[pos])) {
+			return nil, pos, p.flow.errorf(pos, "expected %s after key", stop)
+		}
+		return keys, pos, nil
+	}
+}
+
+func (p *tomlParser) header(line, pos int) (int, error) {
+	array := strings.HasPrefix(string(p.src[pos:]), "[[")
+	open, closer := 1, "]"
+	if array {
+		open, closer = 2, "]]"
+	}
+	keys, end, err := p.keyPath(pos+open, "]")
+	if err != nil {
+		return pos, err
+	}
+	if !strings.HasPrefix(string(p.src[end:]), closer) {
+		return end, p.flow.errorf(end, "expected %s", closer)
+	}
+	next, err := p.endLine(end + len(closer))
+	if err != nil {
+		return next, err
+	}
+	t, path := p.root, []string{}
+	for _, key := range keys[:len(keys)-1] {
+		if t, path, err = p.descend(t, path, key, line); err != nil {
+			return line, err
+		}
+	}
+	key := keys[len(keys)-1]
+	i := t.index(key)
+	if array {
+		var arr *node
+		if i < 0 {
+			arr = &node{kind: arrayKind, style: tableStyle, start: -1, end: -1, insertAt: -1}
+			t.entries = append(t.entries, &entry{key: key, value: arr, start: line, end: next})
+		} else if arr = t.entries[i].value; arr.kind != arrayKind || arr.style != tableStyle {
+			return line, p.flow.errorf(line, "%q is not an array of tables", key)
+		}
+		elem := &node{kind: objectKind, style: tableStyle, start: -1, end: -1, insertAt: next}
+		index := strconv.Itoa(len(arr.entries))
+		arr.entries = append(arr.entries, &entry{key: index, value: elem, start: line, end: next})
+		p.table, p.path = elem, append(path, key, index)
+	} else {
+		if i < 0 {
+			p.table = &node{kind: objectKind, style: tableStyle, start: -1, end: -1}
+			t.entries = append(t.entries, &entry{key: key, value: p.table, start: line, end: next})
+		} else if p.table = t.entries[i].value; p.table.kind != objectKind || p.table.style != tableStyle || p.table.insertAt >= 0 {
+			return line, p.flow.errorf(line, "table %q defined twice", key)
+		}
+		p.table.insertAt = next
+		p.path = append(path, key)
+	}
+	p.section = len(p.items)
+	p.items = append(p.items, item{path: slices.Clone(p.path), start: line, end: next, section: true})
+	return next, nil
+}
+
+// descend returns the table key names in t, creating it when it is new. An
+// array of tables leads to its last table.
+func (p *tomlParser) descend(t *node, path []string, key string, line int) (*node, []string, error) {
+	i := t.index(key)
+	if i < 0 {
+		child := &node{kind: objectKind, style: tableStyle, start: -1, end: -1, insertAt: -1}
+		t.entries = append(t.entries, &entry{key: key, value: child, start: line, end: line})
+		return child, append(path, key), nil
+	}
+	v := t.entries[i].value
+	if v.style != tableStyle {
+		return nil, nil, p.flow.errorf(line, "%q is not a table", key)
+	}
+	if v.kind == arrayKind {
+		last := len(v.entries) - 1
+		return v.entries[last].value, append(path, key, strconv.Itoa(last)), nil
+	}
+	return v, append(path, key), nil
+}
+
+func (p *tomlParser) keyValue(line, pos int) (int, error) {
+	keys, end, err := p.keyPath(pos, "=")
+	if err != nil {
+		return pos, err
+	}
+	sep := end + 1
+	v, vend, err := p.flow.value(p.lineRest(sep))
+	if err != nil {
+		return pos, err
+	}
+	next, err := p.endLine(vend)
+	if err != nil {
+		return next, err
+	}
+	t, path := p.table, slices.Clone(p.path)
+	for j, key := range keys[:len(keys)-1] {
+		if t, path, err = p.descend(t, path, key, line); err != nil {
+			return line, err
+		}
+		// keys are added to a table made by dotted keys with the same dots
+		t.insertAt = next
+		t.prefix = tomlKeyPath(keys[:j+1]) + "."
+	}
+	key := keys[len(keys)-1]
+	if t.index(key) >= 0 {
+		return line, p.flow.errorf(line, "duplicate key %q", key)
+	}
+	t.entries = append(t.entries, &entry{key: key, value: v, start: line, end: next, sep: sep})
+	p.table.insertAt = next
+	p.items = append(p.items, item{path: append(path, key), start: line, end: next})
+	if p.section >= 0 {
+		p.items[p.section].end = next
+	}
+	return next, nil
+}
+
+func (d *Doc) addTOML(parent *node, key string, value any) error {
+	if parent.kind == arrayKind {
+		return fmt.Errorf("adding to an array of tables: %w", ErrUnsupported)
+	}
+	if parent.insertAt < 0 {
+		return fmt.Errorf("adding to a table defined by its sub-tables: %w", ErrUnsupported)
+	}
+	text, err := tomlInline(value)
+	if err != nil {
+		return err
+	}
+	at := parent.insertAt
+	text = parent.prefix + tomlKey(key) + " = " + text
+	if at > 0 {
+		text = lineIndent(d.src, at-1) + text
+	}
+	if at > 0 && d.src[at-1] != '\n' {
+		text = "\n" + text
+	} else {
+		text += "\n"
+	}
+	d.splice(at, at, text)
+	return nil
+}
+
+// removeTOML removes the lines and sections defining path and everything
+// below it.
+func (d *Doc) removeTOML(path []string) error {
+	var spans [][2]int
+	for _, it := range d.items {
+		if len(it.path) < len(path) || !slices.Equal(it.path[:len(path)], path) {
+			continue
+		}
+		end := it.end
+		// a removed section takes the blank lines after it along
+		for it.section && end < len(d.src) && strings.TrimSpace(string(d.src[end:lineEnd(d.src, end)])) == "" {
+			end = lineEnd(d.src, end)
+		}
+		spans = append(spans, [2]int{it.start, end})
+	}
+	if len(spans) == 0 {
+		return ErrNotFound
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	merged := spans[:1]
+	for _, s := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if s[0] <= last[1] {
+			last[1] = max(last[1], s[1])
+			continue
+		}
+		merged = append(merged, s)
+	}
+	for i := len(merged) - 1; i >= 0; i-- {
+		d.splice(merged[i][0], merged[i][1], "")
+	}
+	return nil
+}
+
+// tomlInline returns value written as a TOML value on one line.
+func tomlInline(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", fmt.Errorf("null: %w", ErrUnsupported)
+	case string:
+		return tomlString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return tomlFloat(v), nil
+	case float32:
+		return tomlFloat(float64(v)), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case map[string]any:
+		if len(v) == 0 {
+			return "{}", nil
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			text, err := tomlInline(v[k])
+			if err != nil {
+				return "", err
+			}
+			parts[i] = tomlKey(k) + " = " + text
+		}
+		return "{ " + strings.Join(parts, ", ") + " }", nil
+	case []any:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			text, err := tomlInline(e)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = text
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	case fmt.Stringer:
+		// local dates and times print as TOML
+		return v.String(), nil
+	}
+	switch rv := reflect.ValueOf(value); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("%T: %w", value, ErrUnsupported)
+}
+
+func tomlFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if len(s) > 20 {
+		s = strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	if !strings.ContainsAny(s, ".e") {
+		// a float needs a fraction or exponent to stay one
+		s += ".0"
+	}
+	return s
+}
+
+// tomlString returns s as a TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// tomlKey returns key bare when it can be, and quoted otherwise.
+func tomlKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for i := 0; i < len(key); i++ {
+		if !isBareKey(key[i]) {
+			return tomlString(key)
+		}
+	}
+	return key
+}
+
+func tomlKeyPath(keys []string) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = tomlKey(k)
+	}
+	return strings.Join(parts, ".")
+}
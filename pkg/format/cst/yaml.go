@@ -0,0 +1,443 @@
+package cst
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	yaml "github.com/goccy/go-yaml"
+)
+
+// yamlParser parses YAML block collections, handing flow collections to a
+// flowParser. It covers the YAML found in configuration files: one document
+// of block and flow collections, plain, quoted and block scalars, comments,
+// anchors and tags. Complex keys, directives and several documents are not
+// supported.
+type yamlParser struct {
+	src  []byte
+	flow *flowParser
+}
+
+func parseYAML(src []byte) (*node, error) {
+	p := &yamlParser{src: src, flow: &flowParser{src: src, dialect: yamlDialect}}
+	first := p.next(0)
+	if first < len(src) && src[first] == '%' {
+		return nil, fmt.Errorf("directives: %w", ErrUnsupported)
+	}
+	if p.marker(first, "---") {
+		if rest := p.lineRest(first + 3); rest < len(src) && src[rest] != '#' && src[rest] != '\n' {
+			return nil, fmt.Errorf("a value after ---: %w", ErrUnsupported)
+		}
+		first = p.next(lineEnd(src, first))
+	}
+	if first == len(src) {
+		return &node{kind: scalarKind, start: first, end: first, insertAt: -1}, nil
+	}
+	n, end, err := p.block(first, -1)
+	if err != nil {
+		return nil, err
+	}
+	if after := p.next(lineEnd(src, end)); after < len(src) {
+		if p.marker(after, "...") && p.next(lineEnd(src, after)) == len(src) {
+			return n, nil
+		}
+		if p.marker(after, "---") {
+			return nil, fmt.Errorf("several documents: %w", ErrUnsupported)
+		}
+		return nil, p.flow.errorf(after, "unexpected text")
+	}
+	return n, nil
+}
+
+// next returns the first character of the next line, from the one holding
+// pos, that is not blank or a comment, or the end of the source.
+func (p *yamlParser) next(pos int) int {
+	for pos < len(p.src) {
+		c := p.lineRest(pos)
+		if c < len(p.src) && p.src[c] != '\n' && p.src[c] != '#' && p.src[c] != '\r' {
+			return c
+		}
+		pos = lineEnd(p.src, c)
+	}
+	return len(p.src)
+}
+
+// lineRest skips spaces and tabs from pos.
+func (p *yamlParser) lineRest(pos int) int {
+	for pos < len(p.src) && (p.src[pos] == ' ' || p.src[pos] == '\t') {
+		pos++
+	}
+	return pos
+}
+
+// marker reports whether the line at pos starts with the document marker m.
+func (p *yamlParser) marker(pos int, m string) bool {
+	if pos != lineStart(p.src, pos) || !strings.HasPrefix(string(p.src[pos:]), m) {
+		return false
+	}
+	pos += len(m)
+	return pos == len(p.src) || isSpace(p.src[pos])
+}
+
+func (p *yamlParser) column(pos int) int {
+	return pos - lineStart(p.src, pos)
+}
+
+func (p *yamlParser) isSeqItem(pos int) bool {
+	return p.src[pos] == '-' && (pos+1 == len(p.src) || isSpace(p.src[pos+1]))
+}
+
+// block parses the node whose text starts at first, inside a collection
+// whose entries are at column parent.
+func (p *yamlParser) block(first, parent int) (*node, int, error) {
+	if p.isSeqItem(first) {
+		return p.sequence(first)
+	}
+	if _, _, ok, err := p.mapKey(first); err != nil {
+		return nil, first, err
+	} else if ok {
+		return p.mapping(first)
+	}
+	return p.inline(first, parent)
+}
+
+func (p *yamlParser) sequence(first int) (*node, int, error) {
+	col := p.column(first)
+	n := &node{kind: arrayKind, style: blockStyle, start: first}
+	pos := first
+	for {
+		e := &entry{key: fmt.Sprint(len(n.entries)), start: pos, sep: pos + 1}
+		v, end, err := p.entryValue(pos+1, col, true)
+		if err != nil {
+			return nil, pos, err
+		}
+		e.value, e.end, n.end = v, lineEnd(p.src, end), end
+		n.entries = append(n.entries, e)
+		pos = p.next(e.end)
+		if pos == len(p.src) || p.column(pos) < col || p.marker(pos, "---") || p.marker(pos, "...") {
+			break
+		}
+		if p.column(pos) > col {
+			return nil, pos, p.flow.errorf(pos, "unexpected indentation")
+		}
+		if !p.isSeqItem(pos) {
+			// the sequence was the value of a key at the same column
+			break
+		}
+	}
+	n.insertAt = n.entries[len(n.entries)-1].end
+	return n, n.end, nil
+}
+
+func (p *yamlParser) mapping(first int) (*node, int, error) {
+	col := p.column(first)
+	n := &node{kind: objectKind, style: blockStyle, start: first}
+	pos := first
+	for {
+		key, sep, ok, err := p.mapKey(pos)
+		if err != nil {
+			return nil, pos, err
+		}
+		if !ok {
+			return nil, pos, p.flow.errorf(pos, "expected a key")
+		}
+		if n.index(key) >= 0 {
+			// YAML parses keeping the last value, but which to edit is unclear
+			return nil, pos, fmt.Errorf("%w: %w", p.flow.errorf(pos, "duplicate key %q", key), ErrUnsupported)
+		}
+		e := &entry{key: key, start: pos, sep: sep}
+		v, end, err := p.entryValue(sep, col, false)
+		if err != nil {
+			return nil, pos, err
+		}
+		e.value, e.end, n.end = v, lineEnd(p.src, end), end
+		n.entries = append(n.entries, e)
+		pos = p.next(e.end)
+		if pos == len(p.src) || p.column(pos) < col || p.marker(pos, "---") || p.marker(pos, "...") {
+			break
+		}
+		if p.column(pos) > col || p.isSeqItem(pos) {
+			return nil, pos, p.flow.errorf(pos, "unexpected indentation")
+		}
+	}
+	n.insertAt = n.entries[len(n.entries)-1].end
+	return n, n.end, nil
+}
+
+// mapKey parses the key of a block mapping entry at pos, returning the
+// position after its colon. ok is false when pos does not start a key.
+func (p *yamlParser) mapKey(pos int) (key string, sep int, ok bool, err error) {
+	switch p.src[pos] {
+	case '?':
+		if pos+1 == len(p.src) || isSpace(p.src[pos+1]) {
+			return "", pos, false, fmt.Errorf("complex keys: %w", ErrUnsupported)
+		}
+	case '"', '\'':
+		end, err := p.flow.quoted(pos)
+		if err != nil {
+			return "", pos, false, nil
+		}
+		c := p.lineRest(end)
+		if !p.colon(c) {
+			return "", pos, false, nil
+		}
+		key, err := p.flow.unquote(p.src[pos:end])
+		if err != nil {
+			return "", pos, false, p.flow.errorf(pos, "bad key: %v", err)
+		}
+		return key, c + 1, true, nil
+	case '[', '{', '-', '#', '|', '>', '&', '*', '!':
+		return "", pos, false, nil
+	}
+	for c := pos; c < len(p.src) && p.src[c] != '\n'; c++ {
+		if p.src[c] == '#' && isSpace(p.src[c-1]) {
+			break
+		}
+		if p.colon(c) {
+			key = strings.TrimRight(string(p.src[pos:c]), " \t")
+			if key == "<<" {
+				return "", pos, false, fmt.Errorf("merge keys: %w", ErrUnsupported)
+			}
+			return key, c + 1, true, nil
+		}
+	}
+	return "", pos, false, nil
+}
+
+// colon reports whether pos holds a colon ending a key.
+func (p *yamlParser) colon(pos int) bool {
+	return pos < len(p.src) && p.src[pos] == ':' && (pos+1 == len(p.src) || isSpace(p.src[pos+1]))
+}
+
+// entryValue parses the value following a key's colon, or the dash of a
+// sequence entry, at pos. col is the column of the entry.
+func (p *yamlParser) entryValue(pos, col int, seq bool) (*node, int, error) {
+	c := p.lineRest(pos)
+	// anchors and tags are kept in front of the value
+	for c < len(p.src) && (p.src[c] == '&' || p.src[c] == '!') {
+		for c < len(p.src) && !isSpace(p.src[c]) {
+			c++
+		}
+		c = p.lineRest(c)
+	}
+	if c == len(p.src) || p.src[c] == '\n' || p.src[c] == '\r' || p.src[c] == '#' {
+		// the value is a collection on the following lines, or null
+		next := p.next(c)
+		if next < len(p.src) && (p.column(next) > col || (!seq && p.column(next) == col && p.isSeqItem(next))) {
+			return p.block(next, col)
+		}
+		return &node{kind: scalarKind, start: pos, end: pos, insertAt: -1}, pos, nil
+	}
+	if seq {
+		// compact collections start on the dash's line
+		return p.block(c, col)
+	}
+	return p.inline(c, col)
+}
+
+// inline parses a scalar or flow collection starting at pos, belonging to an
+// entry at column col.
+func (p *yamlParser) inline(pos, col int) (*node, int, error) {
+	n := &node{kind: scalarKind, start: pos, insertAt: -1}
+	switch p.src[pos] {
+	case '|', '>':
+		// a block scalar holds every following line indented past col
+		n.end = p.comment(pos)
+		for line := lineEnd(p.src, pos); line < len(p.src); line = lineEnd(p.src, line) {
+			c := p.lineRest(line)
+			if c == len(p.src) || p.src[c] == '\n' || p.src[c] == '\r' {
+				continue
+			}
+			if p.column(c) <= col {
+				break
+			}
+			n.end = lineEnd(p.src, c)
+			if p.src[n.end-1] == '\n' {
+				n.end--
+			}
+		}
+		return n, n.end, nil
+	case '[', '{':
+		v, _, err := p.flow.value(pos)
+		if err != nil {
+			return nil, pos, err
+		}
+		n = v
+	case '"', '\'':
+		end, err := p.flow.quoted(pos)
+		if err != nil {
+			return nil, pos, err
+		}
+		n.end = end
+	case '*':
+		return nil, pos, fmt.Errorf("aliases: %w", ErrUnsupported)
+	default:
+		n.end = p.comment(pos)
+	}
+	if c := p.lineRest(n.end); c < len(p.src) && p.src[c] != '\n' && p.src[c] != '\r' && p.src[c] != '#' {
+		return nil, c, p.flow.errorf(c, "unexpected text after value")
+	}
+	if next := p.next(lineEnd(p.src, n.end)); next < len(p.src) && p.column(next) > col {
+		return nil, next, fmt.Errorf("line continuation: %w", ErrUnsupported)
+	}
+	return n, n.end, nil
+}
+
+// comment returns the end of the text from pos to a comment or the end of
+// the line, without trailing spaces.
+func (p *yamlParser) comment(pos int) int {
+	end := pos
+	for end < len(p.src) && p.src[end] != '\n' && !(p.src[end] == '#' && end > pos && isSpace(p.src[end-1])) {
+		end++
+	}
+	for end > pos && isSpace(p.src[end-1]) {
+		end--
+	}
+	return end
+}
+
+func (d *Doc) replaceYAML(parent *node, e *entry, value any) error {
+	if parent.style == flowStyle {
+		text, err := yamlInline(value)
+		if err != nil {
+			return err
+		}
+		d.splice(e.value.start, e.value.end, text)
+		return nil
+	}
+	text, err := yamlValue(value, columnOf(d.src, e.start), parent.kind == arrayKind)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(text, " ") && e.value.style != blockStyle && e.value.end > e.value.start {
+		// a value on the key's line is replaced where it is, keeping the
+		// spacing and any comment around it
+		d.splice(e.value.start, e.value.end, text[1:])
+		return nil
+	}
+	d.splice(e.sep, e.value.end, text)
+	return nil
+}
+
+func (d *Doc) addYAML(parent *node, key string, value any) error {
+	last := parent.entries[len(parent.entries)-1]
+	col := columnOf(d.src, last.start)
+	head := strings.Repeat(" ", col) + "-"
+	if parent.kind == objectKind {
+		head = strings.Repeat(" ", col) + yamlKey(key) + ":"
+	}
+	body, err := yamlValue(value, col, parent.kind == arrayKind)
+	if err != nil {
+		return err
+	}
+	text := head + body + "\n"
+	at := last.end
+	if at > 0 && d.src[at-1] != '\n' {
+		// the document does not end with a newline; keep it that way
+		text = "\n" + head + body
+	}
+	d.splice(at, at, text)
+	return nil
+}
+
+func (d *Doc) removeYAML(parent *node, i int, path []string) error {
+	if len(parent.entries) == 1 {
+		// an emptied block collection is written {} or []
+		var empty any = map[string]any{}
+		if parent.kind == arrayKind {
+			empty = []any{}
+		}
+		if len(path) == 1 {
+			text, _ := yamlInline(empty)
+			d.splice(parent.start, parent.end, text)
+			return nil
+		}
+		grand, err := d.find(path[:len(path)-2])
+		if err != nil {
+			return err
+		}
+		return d.replace(grand, grand.entries[grand.index(path[len(path)-2])], empty)
+	}
+	e := parent.entries[i]
+	if start := lineStart(d.src, e.start); strings.TrimSpace(string(d.src[start:e.start])) == "" {
+		d.splice(start, e.end, "")
+		return nil
+	}
+	// the first entry of a compact collection shares its line with a dash;
+	// the next entry takes its place
+	d.splice(e.start, parent.entries[i+1].start, "")
+	return nil
+}
+
+func columnOf(src []byte, pos int) int {
+	return pos - lineStart(src, pos)
+}
+
+// yamlValue returns value as written after a key's colon or, with dash set,
+// a sequence entry's dash: on the same line, or for a collection as a block
+// indented past col. After a dash the block starts on the dash's line.
+func yamlValue(value any, col int, dash bool) (string, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			return " {}", nil
+		}
+	case []any:
+		if len(v) == 0 {
+			return " []", nil
+		}
+	default:
+		text, err := yamlInline(value)
+		return " " + text, err
+	}
+	b, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	indent := strings.Repeat(" ", col+2)
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	if dash {
+		return " " + strings.TrimPrefix(strings.Join(lines, "\n"), indent), nil
+	}
+	return "\n" + strings.Join(lines, "\n"), nil
+}
+
+// yamlInline returns value written on one line: a scalar, or a flow
+// collection.
+func yamlInline(value any) (string, error) {
+	switch value.(type) {
+	case map[string]any, []any:
+		// JSON is a flow collection
+		b, err := json.Marshal(value)
+		return string(b), err
+	case nil:
+		return "null", nil
+	}
+	b, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	text := strings.TrimSuffix(string(b), "\n")
+	if s, ok := value.(string); ok && strings.Contains(text, "\n") {
+		// long and multi-line strings are marshalled as block scalars
+		b, err := json.Marshal(s)
+		return string(b), err
+	}
+	return text, nil
+}
+
+// yamlKey returns key written as a mapping key, quoted when it would not
+// read back as the same string.
+func yamlKey(key string) string {
+	text, err := yamlInline(key)
+	if err != nil {
+		b, _ := json.Marshal(key)
+		return string(b)
+	}
+	return text
+}
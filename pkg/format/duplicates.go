@@ -0,0 +1,205 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// Duplicate is a key written more than once in the same object of a document.
+// Only the value written last is kept when the document is parsed.
+type Duplicate struct {
+	// Path is the keys, and array indexes, leading to the key, ending with it
+	Path nodes.Path
+	// Lines are the lines the key is written on, counting from 1
+	Lines []int
+}
+
+func (d Duplicate) String() string {
+	return d.Format(nodes.DotSyntax)
+}
+
+// Format describes d with its path written in syntax s.
+func (d Duplicate) Format(s nodes.PathSyntax) string {
+	lines := make([]string, len(d.Lines))
+	for i, line := range d.Lines {
+		lines[i] = strconv.Itoa(line)
+	}
+	return fmt.Sprintf("%s on lines %s", d.Path.Format(s), strings.Join(lines, ", "))
+}
+
+// DuplicateKeys returns the keys written more than once in an object of data,
+// in the order they are first repeated. JSON and YAML documents are checked;
+// TOML documents with duplicate keys fail to parse, so other data has none.
+func DuplicateKeys(data []byte) []Duplicate {
+	if json.Valid(data) {
+		return jsonDuplicateKeys(data)
+	}
+	return yamlDuplicateKeys(data)
+}
+
+// jsonDuplicateKeys returns the duplicate keys of a JSON document.
+func jsonDuplicateKeys(data []byte) []Duplicate {
+	// frame is an object or array being read
+	type frame struct {
+		object bool
+		// key is the key of the value being read in an object, or its
+		// index in an array
+		key string
+		// index counts the elements of an array
+		index int
+		// seen holds the line of each key of an object, and its position
+		// in out once repeated
+		seen map[string]*Duplicate
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var (
+		out   []*Duplicate
+		stack []*frame
+		line  = 1
+		read  int64
+	)
+	// path returns the path to the value being read
+	path := func() nodes.Path {
+		p := make(nodes.Path, len(stack))
+		for i, f := range stack {
+			p[i] = nodes.PathElem{Key: f.key, IsIndex: !f.object}
+		}
+		return p
+	}
+	// value starts a value in the innermost array
+	value := func() {
+		if n := len(stack); n > 0 && !stack[n-1].object {
+			top := stack[n-1]
+			top.key = strconv.Itoa(top.index)
+			top.index++
+		}
+	}
+	// expectKey reports whether the next string read is a key
+	expectKey := true
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			// the end of the data, or data that is not JSON
+			break
+		}
+		offset := dec.InputOffset()
+		line += bytes.Count(data[read:offset], []byte("\n"))
+		read = offset
+		var top *frame
+		if n := len(stack); n > 0 {
+			top = stack[n-1]
+		}
+		if s, ok := tok.(string); ok && top != nil && top.object && expectKey {
+			top.key = s
+			expectKey = false
+			d, ok := top.seen[s]
+			switch {
+			case !ok:
+				top.seen[s] = &Duplicate{Lines: []int{line}}
+			case len(d.Lines) == 1:
+				d.Path = path()
+				d.Lines = append(d.Lines, line)
+				out = append(out, d)
+			default:
+				d.Lines = append(d.Lines, line)
+			}
+			continue
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			value()
+			object := tok == json.Delim('{')
+			f := &frame{object: object}
+			if object {
+				f.seen = map[string]*Duplicate{}
+			}
+			stack = append(stack, f)
+			expectKey = object
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		default:
+			value()
+		}
+		// after a value, an object reads its next key
+		expectKey = len(stack) > 0 && stack[len(stack)-1].object
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	dups := make([]Duplicate, len(out))
+	for i, d := range out {
+		dups[i] = *d
+	}
+	return dups
+}
+
+// yamlDuplicateKeys returns the duplicate keys of the first document of a
+// YAML stream, or none when data is not YAML.
+func yamlDuplicateKeys(data []byte) []Duplicate {
+	file, err := parser.ParseBytes(data, 0, parser.AllowDuplicateMapKey())
+	if err != nil || len(file.Docs) == 0 {
+		return nil
+	}
+	var out []*Duplicate
+	var walk func(n ast.Node, path nodes.Path)
+	walk = func(n ast.Node, path nodes.Path) {
+		switch t := n.(type) {
+		case *ast.DocumentNode:
+			walk(t.Body, path)
+		case *ast.AnchorNode:
+			walk(t.Value, path)
+		case *ast.TagNode:
+			walk(t.Value, path)
+		case *ast.SequenceNode:
+			for i, v := range t.Values {
+				walk(v, append(path, nodes.PathElem{Key: strconv.Itoa(i), IsIndex: true}))
+			}
+		case *ast.MappingValueNode:
+			walk(&ast.MappingNode{Values: []*ast.MappingValueNode{t}}, path)
+		case *ast.MappingNode:
+			seen := map[string]*Duplicate{}
+			for _, v := range t.Values {
+				key := yamlKey(v.Key)
+				line := v.Key.GetToken().Position.Line
+				d, ok := seen[key]
+				switch {
+				case !ok:
+					seen[key] = &Duplicate{Lines: []int{line}}
+				case len(d.Lines) == 1:
+					d.Path = append(slices.Clone(path), nodes.PathElem{Key: key})
+					d.Lines = append(d.Lines, line)
+					out = append(out, d)
+				default:
+					d.Lines = append(d.Lines, line)
+				}
+				walk(v.Value, append(path, nodes.PathElem{Key: key}))
+			}
+		}
+	}
+	walk(file.Docs[0], nil)
+	if len(out) == 0 {
+		return nil
+	}
+	dups := make([]Duplicate, len(out))
+	for i, d := range out {
+		dups[i] = *d
+	}
+	return dups
+}
+
+// yamlKey returns the key written by k, unquoted.
+func yamlKey(k ast.MapKeyNode) string {
+	if s, ok := k.(*ast.StringNode); ok {
+		return s.Value
+	}
+	return k.String()
+}
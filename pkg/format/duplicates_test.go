@@ -0,0 +1,60 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicateKeys(t *testing.T) {
+	data := []byte(`{
+  "name": "a",
+  "items": [
+    {"id": 1, "id": 2},
+    {"tags": {"x": 1}, "tags": {"x": 2, "x": 3}}
+  ],
+  "name": "b",
+  "name": "c"
+}`)
+	dups := DuplicateKeys(data)
+	assert.Equal(t, []Duplicate{
+		{Path: dupPath(t, "items[0].id"), Lines: []int{4, 4}},
+		{Path: dupPath(t, "items[1].tags"), Lines: []int{5, 5}},
+		{Path: dupPath(t, "items[1].tags.x"), Lines: []int{5, 5}},
+		{Path: dupPath(t, "name"), Lines: []int{2, 7, 8}},
+	}, dups)
+	assert.Equal(t, "name on lines 2, 7, 8", dups[3].String())
+	assert.Equal(t, "/items/1/tags/x on lines 5, 5", dups[2].Format(nodes.PointerSyntax))
+
+	assert.Nil(t, DuplicateKeys([]byte(`{"a": {"b": 1}, "b": [{"a": 1}, {"a": 2}]}`)))
+	assert.Equal(t, []Duplicate{{Path: dupPath(t, "[1].a"), Lines: []int{1, 1}}}, DuplicateKeys([]byte(`[{}, {"a": 1, "a": 2}]`)))
+	assert.Nil(t, DuplicateKeys([]byte("a = 1\nb = 2\n")), "TOML has none")
+}
+
+func TestDuplicateKeysYaml(t *testing.T) {
+	data := []byte(`name: a
+items:
+  - id: 1
+    id: 2
+  - tags: {x: 1}
+    tags: {x: 2, x: 3}
+"name": b
+name: c
+`)
+	assert.Equal(t, []Duplicate{
+		{Path: dupPath(t, "items[0].id"), Lines: []int{3, 4}},
+		{Path: dupPath(t, "items[1].tags"), Lines: []int{5, 6}},
+		{Path: dupPath(t, "items[1].tags.x"), Lines: []int{6, 6}},
+		{Path: dupPath(t, "name"), Lines: []int{1, 7, 8}},
+	}, DuplicateKeys(data))
+	assert.Nil(t, DuplicateKeys([]byte("a:\n  b: 1\nb:\n  - a: 1\n  - a: 2\n")))
+}
+
+// dupPath parses the dot path s.
+func dupPath(t *testing.T, s string) nodes.Path {
+	p, err := nodes.ParsePath(s, nodes.DotSyntax)
+	require.NoError(t, err)
+	return p
+}
@@ -3,7 +3,13 @@
 package format
 
 import (
+	"encoding/json"
 	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	yaml "github.com/goccy/go-yaml"
 )
 
 type Format func(data []byte) (map[string]any, error)
@@ -31,6 +37,76 @@ func Parse(data []byte) (m map[string]any, err error) {
 	return m, nil
 }
 
+// Detect returns the format data is written in, trying JSON, YAML and TOML
+// in the order Parse does.
+func Detect(data []byte) (FormatType, error) {
+	var err error
+	for _, f := range []FormatType{FormatJson, FormatYaml, FormatToml} {
+		if _, err = f.Parser()(data); err == nil {
+			return f, nil
+		}
+	}
+	return 0, err
+}
+
+// Parser returns the parser for documents in format f.
+func (f FormatType) Parser() Format {
+	switch f {
+	case FormatYaml:
+		return ParseYaml
+	case FormatToml:
+		return ParseToml
+	default:
+		return ParseJson
+	}
+}
+
+// ForFile returns the parser matching the extension of path, and false when
+// the extension is not a supported data format. Jsonnet is not included as it
+// must be evaluated first; see IsJsonnetFile.
+func ForFile(path string) (Format, bool) {
+	f, ok := TypeForFile(path)
+	if !ok {
+		return nil, false
+	}
+	return f.Parser(), true
+}
+
+// TypeForFile returns the format the extension of path names, and false when
+// the extension is not a supported data format.
+func TypeForFile(path string) (FormatType, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJson, true
+	case ".yaml", ".yml":
+		return FormatYaml, true
+	case ".toml":
+		return FormatToml, true
+	default:
+		return 0, false
+	}
+}
+
+// ForMediaType returns the parser for a MIME media type such as a response
+// Content-Type, and false when the type is not a supported data format.
+// Parameters such as charset are ignored.
+func ForMediaType(mediaType string) (Format, bool) {
+	mt, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return nil, false
+	}
+	switch {
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		return ParseJson, true
+	case mt == "application/yaml" || mt == "application/x-yaml" || mt == "text/yaml" || mt == "text/x-yaml" || strings.HasSuffix(mt, "+yaml"):
+		return ParseYaml, true
+	case mt == "application/toml" || mt == "text/toml":
+		return ParseToml, true
+	default:
+		return nil, false
+	}
+}
+
 func As(m map[string]any, f FormatType) ([]byte, error) {
 	switch f {
 	case FormatJson:
@@ -43,3 +119,22 @@ func As(m map[string]any, f FormatType) ([]byte, error) {
 		return nil, fmt.Errorf("unsupported format type: %v", f)
 	}
 }
+
+// Encode writes v, a value as decoded from a document, in format f: objects as
+// As does, and other values, which only JSON and YAML can hold at the top
+// level, as those formats encode them.
+func Encode(v any, f FormatType) ([]byte, error) {
+	if m, ok := v.(map[string]any); ok {
+		return As(m, f)
+	}
+	switch f {
+	case FormatJson:
+		return json.Marshal(v)
+	case FormatYaml:
+		return yaml.Marshal(v)
+	case FormatToml:
+		return nil, fmt.Errorf("toml needs an object, got %T", v)
+	default:
+		return nil, fmt.Errorf("unsupported format type: %v", f)
+	}
+}
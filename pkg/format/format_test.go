@@ -52,3 +52,100 @@ func TestAs(t *testing.T) {
 		})
 	}
 }
+
+func TestForFile(t *testing.T) {
+	tests := []struct {
+		path string
+		data string
+		ok   bool
+	}{
+		{path: "a.json", data: `{"key": "value"}`, ok: true},
+		{path: "dir/a.YAML", data: "key: value", ok: true},
+		{path: "a.yml", data: "key: value", ok: true},
+		{path: "a.toml", data: `key = "value"`, ok: true},
+		{path: "a.jsonnet"},
+		{path: "README.md"},
+		{path: "noext"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			parse, ok := ForFile(tt.path)
+			require.Equal(t, tt.ok, ok)
+			if !ok {
+				return
+			}
+			m, err := parse([]byte(tt.data))
+			require.NoError(t, err)
+			require.Equal(t, map[string]any{"key": "value"}, m)
+		})
+	}
+}
+
+func TestForMediaType(t *testing.T) {
+	tests := []struct {
+		mediaType string
+		data      string
+		ok        bool
+	}{
+		{mediaType: "application/json", data: `{"key": "value"}`, ok: true},
+		{mediaType: "application/json; charset=utf-8", data: `{"key": "value"}`, ok: true},
+		{mediaType: "application/vnd.github+json", data: `{"key": "value"}`, ok: true},
+		{mediaType: "application/yaml", data: "key: value", ok: true},
+		{mediaType: "text/x-yaml", data: "key: value", ok: true},
+		{mediaType: "application/toml", data: `key = "value"`, ok: true},
+		{mediaType: "text/plain"},
+		{mediaType: "not a media type;;"},
+		{mediaType: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mediaType, func(t *testing.T) {
+			parse, ok := ForMediaType(tt.mediaType)
+			require.Equal(t, tt.ok, ok)
+			if !ok {
+				return
+			}
+			m, err := parse([]byte(tt.data))
+			require.NoError(t, err)
+			require.Equal(t, map[string]any{"key": "value"}, m)
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    FormatType
+		wantErr bool
+	}{
+		{data: `{"a": 1}`, want: FormatJson},
+		{data: `[1, 2]`, want: FormatJson},
+		{data: "a: 1\nb: [x]\n", want: FormatYaml},
+		{data: "a = 1\n[b]\nc = 2\n", want: FormatToml},
+		{data: "{", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			got, err := Detect([]byte(tt.data))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	b, err := Encode([]any{"a", 1}, FormatJson)
+	require.NoError(t, err)
+	require.Equal(t, `["a",1]`, string(b))
+	b, err = Encode("a", FormatYaml)
+	require.NoError(t, err)
+	require.Equal(t, "a\n", string(b))
+	b, err = Encode(map[string]any{"a": 1}, FormatToml)
+	require.NoError(t, err)
+	require.Equal(t, "a = 1\n", string(b))
+	_, err = Encode([]any{1}, FormatToml)
+	require.Error(t, err)
+}
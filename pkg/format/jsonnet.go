@@ -0,0 +1,264 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+// JsonnetExtensions are the file extensions evaluated as Jsonnet rather than
+// parsed as plain data.
+var JsonnetExtensions = []string{".jsonnet", ".libsonnet"}
+
+// IsJsonnetFile reports whether path names a Jsonnet source file by extension.
+func IsJsonnetFile(path string) bool {
+	ext := filepath.Ext(path)
+	for _, e := range JsonnetExtensions {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
+type jsonnetConf struct {
+	ExtVars  map[string]string
+	ExtCode  map[string]string
+	TLAVars  map[string]string
+	TLACode  map[string]string
+	LibPaths []string
+}
+
+func defaultJsonnetConf() *jsonnetConf {
+	return &jsonnetConf{
+		ExtVars: map[string]string{},
+		ExtCode: map[string]string{},
+		TLAVars: map[string]string{},
+		TLACode: map[string]string{},
+	}
+}
+
+// JsonnetOption configures a Jsonnet evaluation.
+type JsonnetOption func(*jsonnetConf)
+
+// WithExtVar binds the external variable key to the string val, read in
+// Jsonnet with std.extVar(key).
+func WithExtVar(key, val string) JsonnetOption {
+	return func(c *jsonnetConf) {
+		c.ExtVars[key] = val
+	}
+}
+
+// WithExtCode binds the external variable key to the result of evaluating the
+// Jsonnet expression code.
+func WithExtCode(key, code string) JsonnetOption {
+	return func(c *jsonnetConf) {
+		c.ExtCode[key] = code
+	}
+}
+
+// WithTLAVar passes the string val as the top-level argument key when the
+// document evaluates to a function.
+func WithTLAVar(key, val string) JsonnetOption {
+	return func(c *jsonnetConf) {
+		c.TLAVars[key] = val
+	}
+}
+
+// WithTLACode passes the result of evaluating the Jsonnet expression code as
+// the top-level argument key when the document evaluates to a function.
+func WithTLACode(key, code string) JsonnetOption {
+	return func(c *jsonnetConf) {
+		c.TLACode[key] = code
+	}
+}
+
+// WithLibraryPaths adds directories searched, in order, when resolving
+// imports that are not found relative to the importing file.
+func WithLibraryPaths(paths ...string) JsonnetOption {
+	return func(c *jsonnetConf) {
+		c.LibPaths = append(c.LibPaths, paths...)
+	}
+}
+
+// JsonnetErrorKind classifies a failed Jsonnet evaluation.
+type JsonnetErrorKind string
+
+const (
+	// JsonnetStaticError is a syntax or static analysis failure, raised
+	// before any code runs.
+	JsonnetStaticError JsonnetErrorKind = "static"
+	// JsonnetRuntimeError is a failure raised while evaluating, such as
+	// error expressions, failed assertions or type errors.
+	JsonnetRuntimeError JsonnetErrorKind = "runtime"
+	// JsonnetInternalError is a failure inside the evaluator itself, such as
+	// an unreadable file.
+	JsonnetInternalError JsonnetErrorKind = "internal"
+)
+
+// JsonnetFrame is a single frame of a Jsonnet stack trace.
+type JsonnetFrame struct {
+	// Name is the function or field being evaluated, if known
+	Name   string
+	File   string
+	Line   int
+	Column int
+}
+
+// JsonnetError describes why a Jsonnet evaluation failed. File, Line and
+// Column locate the failure and are zero when go-jsonnet did not report a
+// location; Stack lists the call frames for runtime errors, innermost first.
+type JsonnetError struct {
+	Kind    JsonnetErrorKind
+	Message string
+	File    string
+	Line    int
+	Column  int
+	Stack   []JsonnetFrame
+	// Formatted is go-jsonnet's own multi-line rendering of the error,
+	// including the offending code and stack trace.
+	Formatted string
+	err       error
+}
+
+func (e *JsonnetError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("jsonnet %s error: %s", e.Kind, e.Message)
+	}
+	return fmt.Sprintf("jsonnet %s error at %s:%d:%d: %s", e.Kind, e.File, e.Line, e.Column, e.Message)
+}
+
+// Unwrap returns the underlying go-jsonnet error.
+func (e *JsonnetError) Unwrap() error {
+	return e.err
+}
+
+// EvalJsonnetFile evaluates the Jsonnet file at path and returns the resulting
+// JSON document. Imports resolve relative to the file first and then against
+// any library paths. Evaluation failures are returned as a *JsonnetError.
+func EvalJsonnetFile(path string, opts ...JsonnetOption) ([]byte, error) {
+	vm, capture := newJsonnetVM(opts...)
+	out, err := vm.EvaluateFile(path)
+	if err != nil {
+		return nil, capture.toError(err)
+	}
+	return []byte(out), nil
+}
+
+// EvalJsonnet evaluates the Jsonnet snippet and returns the resulting JSON
+// document. name is only used in error messages; imports resolve against the
+// working directory and any library paths. Evaluation failures are returned as
+// a *JsonnetError.
+func EvalJsonnet(name string, snippet []byte, opts ...JsonnetOption) ([]byte, error) {
+	vm, capture := newJsonnetVM(opts...)
+	out, err := vm.EvaluateAnonymousSnippet(name, string(snippet))
+	if err != nil {
+		return nil, capture.toError(err)
+	}
+	return []byte(out), nil
+}
+
+// newJsonnetVM builds a VM from opts. go-jsonnet flattens errors into strings
+// before returning them, so the VM's formatter is wrapped to keep hold of the
+// original error for toError.
+func newJsonnetVM(opts ...JsonnetOption) (*jsonnet.VM, *capturingFormatter) {
+	conf := defaultJsonnetConf()
+	for _, opt := range opts {
+		opt(conf)
+	}
+	vm := jsonnet.MakeVM()
+	for k, v := range conf.ExtVars {
+		vm.ExtVar(k, v)
+	}
+	for k, v := range conf.ExtCode {
+		vm.ExtCode(k, v)
+	}
+	for k, v := range conf.TLAVars {
+		vm.TLAVar(k, v)
+	}
+	for k, v := range conf.TLACode {
+		vm.TLACode(k, v)
+	}
+	vm.Importer(&jsonnet.FileImporter{JPaths: conf.LibPaths})
+	capture := &capturingFormatter{ErrorFormatter: vm.ErrorFormatter}
+	vm.ErrorFormatter = capture
+	return vm, capture
+}
+
+// capturingFormatter records the last error go-jsonnet formats so it can be
+// turned into a *JsonnetError.
+type capturingFormatter struct {
+	jsonnet.ErrorFormatter
+	last error
+}
+
+func (f *capturingFormatter) Format(err error) string {
+	f.last = err
+	return f.ErrorFormatter.Format(err)
+}
+
+// toError converts the captured go-jsonnet error into a *JsonnetError. formatted
+// is the flattened error the VM returned.
+func (f *capturingFormatter) toError(formatted error) error {
+	e := &JsonnetError{
+		Kind:      JsonnetInternalError,
+		Message:   formatted.Error(),
+		Formatted: strings.TrimSpace(formatted.Error()),
+		err:       formatted,
+	}
+	var rt jsonnet.RuntimeError
+	// static errors live in an internal go-jsonnet package; match them by the
+	// method they expose instead.
+	var st interface{ Loc() ast.LocationRange }
+	switch {
+	case f.last == nil:
+	case errors.As(f.last, &rt):
+		e.Kind = JsonnetRuntimeError
+		e.Message = rt.Msg
+		e.err = rt
+		// go-jsonnet orders the trace outermost first; reverse it so the
+		// failing expression comes first.
+		for i := len(rt.StackTrace) - 1; i >= 0; i-- {
+			tf := rt.StackTrace[i]
+			frame := JsonnetFrame{Name: tf.Name}
+			if tf.Loc.IsSet() {
+				frame.File = locFile(tf.Loc)
+				frame.Line, frame.Column = tf.Loc.Begin.Line, tf.Loc.Begin.Column
+			} else if frame.Name == "" {
+				// frames without code, such as "During manifestation", carry
+				// their description in the file name.
+				frame.Name = tf.Loc.FileName
+			}
+			e.Stack = append(e.Stack, frame)
+		}
+		for _, frame := range e.Stack {
+			if frame.Line > 0 {
+				e.File, e.Line, e.Column = frame.File, frame.Line, frame.Column
+				break
+			}
+		}
+	case errors.As(f.last, &st):
+		loc := st.Loc()
+		e.Kind = JsonnetStaticError
+		e.err = f.last
+		// the static error message is prefixed with its location; drop it as
+		// the location has its own fields.
+		e.Message = strings.TrimSpace(strings.TrimPrefix(f.last.Error(), loc.String()))
+		e.File, e.Line, e.Column = locFile(loc), loc.Begin.Line, loc.Begin.Column
+	default:
+		e.Message = f.last.Error()
+		e.err = f.last
+	}
+	return e
+}
+
+func locFile(loc ast.LocationRange) string {
+	if loc.File != nil && loc.File.DiagnosticFileName != "" {
+		return string(loc.File.DiagnosticFileName)
+	}
+	return loc.FileName
+}
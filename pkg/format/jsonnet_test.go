@@ -0,0 +1,125 @@
+package format
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsJsonnetFile(t *testing.T) {
+	assert.True(t, IsJsonnetFile("dash.jsonnet"))
+	assert.True(t, IsJsonnetFile("lib/util.libsonnet"))
+	assert.True(t, IsJsonnetFile("CI.JSONNET"))
+	assert.False(t, IsJsonnetFile("config.json"))
+	assert.False(t, IsJsonnetFile("jsonnet"))
+}
+
+func TestEvalJsonnet(t *testing.T) {
+	tests := []struct {
+		name    string
+		snippet string
+		opts    []JsonnetOption
+		want    string
+	}{
+		{
+			name:    "plain object",
+			snippet: `{ a: 1 + 1, b: [x * 2 for x in [1, 2]] }`,
+			want:    `{"a":2,"b":[2,4]}`,
+		},
+		{
+			name:    "ext vars",
+			snippet: `{ env: std.extVar("env"), replicas: std.extVar("replicas") }`,
+			opts:    []JsonnetOption{WithExtVar("env", "prod"), WithExtCode("replicas", "3")},
+			want:    `{"env":"prod","replicas":3}`,
+		},
+		{
+			name:    "top level arguments",
+			snippet: `function(name, port) { name: name, port: port }`,
+			opts:    []JsonnetOption{WithTLAVar("name", "web"), WithTLACode("port", "8080")},
+			want:    `{"name":"web","port":8080}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvalJsonnet("test.jsonnet", []byte(tt.snippet), tt.opts...)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestEvalJsonnetFileLibraryPaths(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib")
+	require.NoError(t, os.Mkdir(lib, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(lib, "util.libsonnet"), []byte(`{ greet(n): "hi " + n }`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "local.libsonnet"), []byte(`{ x: 1 }`), 0o600))
+	main := filepath.Join(dir, "main.jsonnet")
+	require.NoError(t, os.WriteFile(main, []byte(`
+local util = import "util.libsonnet";
+local local_ = import "local.libsonnet";
+{ msg: util.greet("bob"), x: local_.x }
+`), 0o600))
+
+	// util.libsonnet is only reachable through the library path.
+	_, err := EvalJsonnetFile(main)
+	require.Error(t, err)
+
+	got, err := EvalJsonnetFile(main, WithLibraryPaths(lib))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"msg":"hi bob","x":1}`, string(got))
+
+	m, err := Parse(got)
+	require.NoError(t, err)
+	assert.Equal(t, "hi bob", m["msg"])
+}
+
+func TestEvalJsonnetErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		snippet string
+		kind    JsonnetErrorKind
+		message string
+		line    int
+	}{
+		{
+			name:    "runtime error",
+			snippet: "{\n  a: error 'boom',\n}",
+			kind:    JsonnetRuntimeError,
+			message: "boom",
+			line:    2,
+		},
+		{
+			name:    "static error",
+			snippet: "{\n  a: ,\n}",
+			kind:    JsonnetStaticError,
+			line:    2,
+		},
+		{
+			name:    "missing ext var",
+			snippet: `std.extVar("nope")`,
+			kind:    JsonnetRuntimeError,
+			message: "Undefined external variable: nope",
+			line:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := EvalJsonnet("bad.jsonnet", []byte(tt.snippet))
+			require.Error(t, err)
+			var jerr *JsonnetError
+			require.True(t, errors.As(err, &jerr))
+			assert.Equal(t, tt.kind, jerr.Kind)
+			assert.Equal(t, "bad.jsonnet", jerr.File)
+			assert.Equal(t, tt.line, jerr.Line)
+			assert.NotEmpty(t, jerr.Formatted)
+			if tt.message != "" {
+				assert.Equal(t, tt.message, jerr.Message)
+			}
+		})
+	}
+}
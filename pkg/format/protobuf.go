@@ -0,0 +1,226 @@
+package format
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type protobufConf struct {
+	DescriptorSet []byte
+	MessageType   string
+}
+
+// ProtobufOption configures how binary protobuf is decoded.
+type ProtobufOption func(*protobufConf)
+
+// WithDescriptorSet supplies a serialized FileDescriptorSet, as written by
+// `protoc --descriptor_set_out` or `buf build -o`, describing the message.
+// Without one, messages are decoded raw.
+func WithDescriptorSet(set []byte) ProtobufOption {
+	return func(c *protobufConf) {
+		c.DescriptorSet = set
+	}
+}
+
+// WithMessageType sets the fully qualified name of the message to decode, such
+// as "acme.v1.Order". It may be omitted when the descriptor set declares
+// exactly one message.
+func WithMessageType(name string) ProtobufOption {
+	return func(c *protobufConf) {
+		c.MessageType = strings.TrimPrefix(name, ".")
+	}
+}
+
+// NewProtobuf returns a Format decoding binary protobuf messages. With a
+// descriptor set, fields are named after the .proto definition. Without one,
+// each field is keyed by its number and wire type, such as "1:varint", and
+// repeated fields become arrays.
+func NewProtobuf(opts ...ProtobufOption) (Format, error) {
+	conf := &protobufConf{}
+	for _, opt := range opts {
+		opt(conf)
+	}
+	if conf.DescriptorSet == nil {
+		if conf.MessageType != "" {
+			return nil, errors.New("a message type needs a descriptor set")
+		}
+		return ParseProtobufRaw, nil
+	}
+	md, types, err := resolveMessage(conf.DescriptorSet, conf.MessageType)
+	if err != nil {
+		return nil, err
+	}
+	return func(data []byte) (map[string]any, error) {
+		msg := dynamicpb.NewMessage(md)
+		if err := (proto.UnmarshalOptions{Resolver: types}).Unmarshal(data, msg); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", md.FullName(), err)
+		}
+		b, err := protojson.MarshalOptions{UseProtoNames: true, Resolver: types}.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %s to json: %w", md.FullName(), err)
+		}
+		return ParseJson(b)
+	}, nil
+}
+
+// resolveMessage finds the descriptor of the named message in the serialized
+// FileDescriptorSet set. An empty name selects the only message in the set.
+func resolveMessage(set []byte, name string) (protoreflect.MessageDescriptor, *dynamicpb.Types, error) {
+	var fds descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(set, &fds); err != nil {
+		return nil, nil, fmt.Errorf("failed to read descriptor set: %w", err)
+	}
+	files, err := protodesc.NewFiles(&fds)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load descriptor set: %w", err)
+	}
+	types := dynamicpb.NewTypes(files)
+	if name == "" {
+		var found []protoreflect.MessageDescriptor
+		files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+			for i := range fd.Messages().Len() {
+				found = append(found, fd.Messages().Get(i))
+			}
+			return true
+		})
+		if len(found) != 1 {
+			return nil, nil, fmt.Errorf("descriptor set declares %d messages, a message type is required", len(found))
+		}
+		return found[0], types, nil
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if errors.Is(err, protoregistry.NotFound) {
+		return nil, nil, fmt.Errorf("message type %s not found in descriptor set", name)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find message type %s: %w", name, err)
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is not a message type", name)
+	}
+	return md, types, nil
+}
+
+// ParseProtobufRaw decodes a binary protobuf message without its schema. Each
+// field is keyed by number and wire type ("1:varint", "2:bytes", ...), and a
+// field seen more than once becomes an array. Length-delimited values are
+// shown as text when printable, as a nested message when they decode as one,
+// and as base64 otherwise.
+func ParseProtobufRaw(data []byte) (map[string]any, error) {
+	m, err := decodeRawMessage(data)
+	if err != nil {
+		return nil, fmt.Errorf("data is not protobuf: %w", err)
+	}
+	return m, nil
+}
+
+func decodeRawMessage(b []byte) (map[string]any, error) {
+	m := map[string]any{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		var v any
+		switch typ {
+		case protowire.VarintType:
+			var x uint64
+			x, n = protowire.ConsumeVarint(b)
+			v = strconv.FormatUint(x, 10)
+		case protowire.Fixed32Type:
+			var x uint32
+			x, n = protowire.ConsumeFixed32(b)
+			v = strconv.FormatUint(uint64(x), 10)
+		case protowire.Fixed64Type:
+			var x uint64
+			x, n = protowire.ConsumeFixed64(b)
+			v = strconv.FormatUint(x, 10)
+		case protowire.BytesType:
+			var x []byte
+			x, n = protowire.ConsumeBytes(b)
+			v = decodeRawBytes(x)
+		case protowire.StartGroupType:
+			var x []byte
+			x, n = protowire.ConsumeGroup(num, b)
+			if n >= 0 {
+				group, err := decodeRawMessage(x)
+				if err != nil {
+					return nil, err
+				}
+				v = group
+			}
+		default:
+			return nil, fmt.Errorf("unexpected wire type %d for field %d", typ, num)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		key := fmt.Sprintf("%d:%s", num, wireTypeName(typ))
+		switch prev := m[key].(type) {
+		case nil:
+			m[key] = v
+		case []any:
+			m[key] = append(prev, v)
+		default:
+			m[key] = []any{prev, v}
+		}
+	}
+	return m, nil
+}
+
+// decodeRawBytes picks the most readable form of a length-delimited value.
+func decodeRawBytes(b []byte) any {
+	if printable(b) {
+		return string(b)
+	}
+	if nested, err := decodeRawMessage(b); err == nil && len(nested) > 0 {
+		return nested
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func printable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func wireTypeName(typ protowire.Type) string {
+	switch typ {
+	case protowire.VarintType:
+		return "varint"
+	case protowire.Fixed32Type:
+		return "fixed32"
+	case protowire.Fixed64Type:
+		return "fixed64"
+	case protowire.BytesType:
+		return "bytes"
+	case protowire.StartGroupType:
+		return "group"
+	default:
+		return strconv.Itoa(int(typ))
+	}
+}
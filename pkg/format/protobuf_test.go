@@ -0,0 +1,164 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// testDescriptorSet describes:
+//
+//	package acme.v1;
+//	message Item { string sku = 1; int32 qty = 2; }
+//	message Order { string id = 1; repeated Item items = 2; bool paid = 3; }
+func testDescriptorSet(t *testing.T) *descriptorpb.FileDescriptorSet {
+	t.Helper()
+	field := func(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(num),
+			Type:     typ.Enum(),
+			Label:    label.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	opt := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	return &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    proto.String("acme/v1/order.proto"),
+		Package: proto.String("acme.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Item"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("sku", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, opt, ""),
+					field("qty", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, opt, ""),
+				},
+			},
+			{
+				Name: proto.String("Order"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, opt, ""),
+					field("items", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_LABEL_REPEATED, ".acme.v1.Item"),
+					field("paid", 3, descriptorpb.FieldDescriptorProto_TYPE_BOOL, opt, ""),
+				},
+			},
+		},
+	}}}
+}
+
+// testOrder encodes an Order with two items.
+func testOrder(t *testing.T) []byte {
+	t.Helper()
+	var item1, item2, order []byte
+	item1 = protowire.AppendTag(item1, 1, protowire.BytesType)
+	item1 = protowire.AppendString(item1, "A-1")
+	item1 = protowire.AppendTag(item1, 2, protowire.VarintType)
+	item1 = protowire.AppendVarint(item1, 3)
+	item2 = protowire.AppendTag(item2, 1, protowire.BytesType)
+	item2 = protowire.AppendString(item2, "B-2")
+	order = protowire.AppendTag(order, 1, protowire.BytesType)
+	order = protowire.AppendString(order, "ord-7")
+	order = protowire.AppendTag(order, 2, protowire.BytesType)
+	order = protowire.AppendBytes(order, item1)
+	order = protowire.AppendTag(order, 2, protowire.BytesType)
+	order = protowire.AppendBytes(order, item2)
+	order = protowire.AppendTag(order, 3, protowire.VarintType)
+	order = protowire.AppendVarint(order, 1)
+	return order
+}
+
+func TestNewProtobufWithDescriptor(t *testing.T) {
+	set, err := proto.Marshal(testDescriptorSet(t))
+	require.NoError(t, err)
+
+	parse, err := NewProtobuf(WithDescriptorSet(set), WithMessageType(".acme.v1.Order"))
+	require.NoError(t, err)
+	got, err := parse(testOrder(t))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"id": "ord-7",
+		"items": []any{
+			map[string]any{"sku": "A-1", "qty": 3.0},
+			map[string]any{"sku": "B-2"},
+		},
+		"paid": true,
+	}, got)
+}
+
+func TestNewProtobufErrors(t *testing.T) {
+	set, err := proto.Marshal(testDescriptorSet(t))
+	require.NoError(t, err)
+
+	_, err = NewProtobuf(WithDescriptorSet(set), WithMessageType("acme.v1.Missing"))
+	assert.ErrorContains(t, err, "not found")
+
+	// the set declares two messages, so a type must be named.
+	_, err = NewProtobuf(WithDescriptorSet(set))
+	assert.ErrorContains(t, err, "message type is required")
+
+	_, err = NewProtobuf(WithMessageType("acme.v1.Order"))
+	assert.Error(t, err)
+
+	_, err = NewProtobuf(WithDescriptorSet([]byte("not a descriptor")))
+	assert.Error(t, err)
+
+	parse, err := NewProtobuf(WithDescriptorSet(set), WithMessageType("acme.v1.Order"))
+	require.NoError(t, err)
+	_, err = parse([]byte{0x0a, 0x05, 'a'})
+	assert.Error(t, err)
+}
+
+func TestParseProtobufRaw(t *testing.T) {
+	got, err := ParseProtobufRaw(testOrder(t))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"1:bytes": "ord-7",
+		"2:bytes": []any{
+			map[string]any{"1:bytes": "A-1", "2:varint": "3"},
+			map[string]any{"1:bytes": "B-2"},
+		},
+		"3:varint": "1",
+	}, got)
+
+	// without a descriptor, NewProtobuf falls back to raw decoding.
+	parse, err := NewProtobuf()
+	require.NoError(t, err)
+	got2, err := parse(testOrder(t))
+	require.NoError(t, err)
+	assert.Equal(t, got, got2)
+}
+
+func TestParseProtobufRawWireTypes(t *testing.T) {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.Fixed32Type)
+	b = protowire.AppendFixed32(b, 7)
+	b = protowire.AppendTag(b, 2, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, 9)
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	b = protowire.AppendBytes(b, []byte{0xff, 0x00})
+	b = protowire.AppendTag(b, 4, protowire.StartGroupType)
+	b = protowire.AppendTag(b, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, 5)
+	b = protowire.AppendTag(b, 4, protowire.EndGroupType)
+
+	got, err := ParseProtobufRaw(b)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"1:fixed32": "7",
+		"2:fixed64": "9",
+		"3:bytes":   "/wA=",
+		"4:group":   map[string]any{"1:varint": "5"},
+	}, got)
+
+	_, err = ParseProtobufRaw([]byte{0x08})
+	assert.Error(t, err)
+}
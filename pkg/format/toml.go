@@ -6,15 +6,41 @@ import (
 	"github.com/BurntSushi/toml"
 )
 
+// ParseToml parses a TOML document. Arrays of tables are returned as []any,
+// like every other array, rather than the []map[string]any the decoder gives.
 func ParseToml(data []byte) (map[string]any, error) {
 	var t map[string]any
 	err := toml.Unmarshal(data, &t)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshall yaml: %w", err)
 	}
+	tableArrays(t)
 	return t, nil
 }
 
+// tableArrays returns v with each array of tables under it as []any.
+func tableArrays(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = tableArrays(e)
+		}
+		return v
+	case []map[string]any:
+		arr := make([]any, len(v))
+		for i, e := range v {
+			arr[i] = tableArrays(e)
+		}
+		return arr
+	case []any:
+		for i, e := range v {
+			v[i] = tableArrays(e)
+		}
+		return v
+	}
+	return v
+}
+
 func AsToml(m map[string]any) ([]byte, error) {
 	return toml.Marshal(m)
 }
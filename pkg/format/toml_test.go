@@ -23,3 +23,9 @@ Perfection = [ 6, 28, 496, 8128 ]
 	assert.EqualValues(t, 10, m["children"].(map[string]any)["alpha"])
 	assert.EqualValues(t, 20, m["children"].(map[string]any)["bravo"])
 }
+
+func TestParseTomlArrayOfTables(t *testing.T) {
+	m, err := ParseToml([]byte("[[item]]\nname = \"a\"\n\n[[item]]\nname = \"b\"\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []any{map[string]any{"name": "a"}, map[string]any{"name": "b"}}, m["item"])
+}
@@ -2,17 +2,30 @@ package format
 
 import (
 	"fmt"
+	"strconv"
 
 	yaml "github.com/goccy/go-yaml"
 )
 
+// ParseYaml converts a YAML document to a map[string]any. A document whose
+// top-level value is a sequence is keyed by index, as ParseJson does. A key
+// written more than once keeps its last value, as in JSON; DuplicateKeys
+// finds them.
 func ParseYaml(data []byte) (map[string]any, error) {
 	var y map[string]any
-	err := yaml.Unmarshal(data, &y)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshall yaml: %w", err)
+	err := yaml.UnmarshalWithOptions(data, &y, yaml.AllowDuplicateMapKey())
+	if err == nil {
+		return y, nil
 	}
-	return y, nil
+	var arr []any
+	if yaml.UnmarshalWithOptions(data, &arr, yaml.AllowDuplicateMapKey()) == nil && arr != nil {
+		y = make(map[string]any, len(arr))
+		for i, item := range arr {
+			y[strconv.Itoa(i)] = item
+		}
+		return y, nil
+	}
+	return nil, fmt.Errorf("failed to unmarshall yaml: %w", err)
 }
 
 func AsYaml(m map[string]any) ([]byte, error) {
@@ -24,3 +24,22 @@ bad:
 	assert.Len(t, m["bad"], 1)
 	assert.Equal(t, "moriarty", m["bad"].(map[string]any)["guy"])
 }
+
+func TestParseYamlSequence(t *testing.T) {
+	m, err := ParseYaml([]byte("- a\n- b: 1\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"0": "a", "1": map[string]any{"b": uint64(1)}}, m)
+
+	_, err = ParseYaml([]byte("a: [\n"))
+	assert.Error(t, err)
+}
+
+func TestParseYamlDuplicateKeys(t *testing.T) {
+	m, err := ParseYaml([]byte("a: 1\nb: 2\na: 3\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": uint64(3), "b": uint64(2)}, m, "the last value is kept")
+
+	m, err = Parse([]byte("a: 1\nb: 2\na: 3\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), m["a"])
+}
@@ -19,6 +19,21 @@ type KeyConfig struct {
 	SearchKeys         []string
 	SubmitKeys         []string
 	NextKeys           []string
+	RefreshKeys        []string
+	GoToKeys           []string
+	EditKeys           []string
+	EditValueKeys      []string
+	AddKeys            []string
+	DeleteKeys         []string
+	RenameKeys         []string
+	UndoKeys           []string
+	RedoKeys           []string
+	SaveKeys           []string
+	ShadowedKeys       []string
+	DuplicatesKeys     []string
+	RevealKeys         []string
+	FormatKeys         []string
+	WarningsKeys       []string
 }
 
 func NewConfig(data []byte) (*KeyConfig, error) {
@@ -18,12 +18,27 @@ type KeyMap struct {
 	Submit         key.Binding
 	Next           key.Binding
 	Num            key.Binding
+	Refresh        key.Binding
+	GoTo           key.Binding
+	Edit           key.Binding
+	EditValue      key.Binding
+	Add            key.Binding
+	Delete         key.Binding
+	Rename         key.Binding
+	Undo           key.Binding
+	Redo           key.Binding
+	Save           key.Binding
+	Shadowed       key.Binding
+	Duplicates     key.Binding
+	Reveal         key.Binding
+	Format         key.Binding
+	Warnings       key.Binding
 }
 
 // Len returns the number of keys in the keymap.
 func (KeyMap) Len() int {
 	// get number of keys in the keymap
-	return 12
+	return 27
 }
 
 func NewKeyMap(c *KeyConfig) KeyMap {
@@ -64,6 +79,51 @@ func NewKeyMap(c *KeyConfig) KeyMap {
 	if len(c.NextKeys) != 0 {
 		keys.Next.SetKeys(c.NextKeys...)
 	}
+	if len(c.RefreshKeys) != 0 {
+		keys.Refresh.SetKeys(c.RefreshKeys...)
+	}
+	if len(c.GoToKeys) != 0 {
+		keys.GoTo.SetKeys(c.GoToKeys...)
+	}
+	if len(c.EditKeys) != 0 {
+		keys.Edit.SetKeys(c.EditKeys...)
+	}
+	if len(c.EditValueKeys) != 0 {
+		keys.EditValue.SetKeys(c.EditValueKeys...)
+	}
+	if len(c.AddKeys) != 0 {
+		keys.Add.SetKeys(c.AddKeys...)
+	}
+	if len(c.DeleteKeys) != 0 {
+		keys.Delete.SetKeys(c.DeleteKeys...)
+	}
+	if len(c.RenameKeys) != 0 {
+		keys.Rename.SetKeys(c.RenameKeys...)
+	}
+	if len(c.UndoKeys) != 0 {
+		keys.Undo.SetKeys(c.UndoKeys...)
+	}
+	if len(c.RedoKeys) != 0 {
+		keys.Redo.SetKeys(c.RedoKeys...)
+	}
+	if len(c.SaveKeys) != 0 {
+		keys.Save.SetKeys(c.SaveKeys...)
+	}
+	if len(c.ShadowedKeys) != 0 {
+		keys.Shadowed.SetKeys(c.ShadowedKeys...)
+	}
+	if len(c.DuplicatesKeys) != 0 {
+		keys.Duplicates.SetKeys(c.DuplicatesKeys...)
+	}
+	if len(c.RevealKeys) != 0 {
+		keys.Reveal.SetKeys(c.RevealKeys...)
+	}
+	if len(c.FormatKeys) != 0 {
+		keys.Format.SetKeys(c.FormatKeys...)
+	}
+	if len(c.WarningsKeys) != 0 {
+		keys.Warnings.SetKeys(c.WarningsKeys...)
+	}
 	return keys
 }
 
@@ -122,5 +182,65 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("1", "2", "3", "4", "5", "6", "7", "8", "9", "0"),
 			key.WithHelp("#", "set expanded layers"),
 		),
+		Refresh: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "reload data"),
+		),
+		GoTo: key.NewBinding(
+			key.WithKeys(":"),
+			key.WithHelp(":", "go to path (JSONPath or JSON Pointer)"),
+		),
+		Edit: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "toggle edit mode"),
+		),
+		EditValue: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "change value"),
+		),
+		Add: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "add key or element"),
+		),
+		Delete: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "delete node"),
+		),
+		Rename: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", "rename key"),
+		),
+		Undo: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", "undo"),
+		),
+		Redo: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", "redo"),
+		),
+		Save: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", "write changes to file"),
+		),
+		Shadowed: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "show overridden values"),
+		),
+		Duplicates: key.NewBinding(
+			key.WithKeys("D"),
+			key.WithHelp("D", "find duplicate subtrees"),
+		),
+		Reveal: key.NewBinding(
+			key.WithKeys("v"),
+			key.WithHelp("v", "reveal redacted values"),
+		),
+		Format: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "cycle summary format"),
+		),
+		Warnings: key.NewBinding(
+			key.WithKeys("W"),
+			key.WithHelp("W", "page through warnings"),
+		),
 	}
 }
@@ -8,7 +8,7 @@ import (
 
 func TestDefaultKeyMap(t *testing.T) {
 	km := DefaultKeyMap()
-	assert.Equal(t, 12, km.Len())
+	assert.Equal(t, 27, km.Len())
 	assert.Equal(t, []string{"bottom", "G"}, km.Bottom.Keys())
 	assert.Equal(t, []string{"top", "g"}, km.Top.Keys())
 	assert.Equal(t, []string{"down", "j"}, km.Down.Keys())
@@ -22,10 +22,25 @@ func TestDefaultKeyMap(t *testing.T) {
 	assert.Equal(t, []string{"enter"}, km.Submit.Keys())
 	assert.Equal(t, []string{"n"}, km.Next.Keys())
 	assert.Equal(t, []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "0"}, km.Num.Keys())
+	assert.Equal(t, []string{"r"}, km.Refresh.Keys())
+	assert.Equal(t, []string{":"}, km.GoTo.Keys())
+	assert.Equal(t, []string{"e"}, km.Edit.Keys())
+	assert.Equal(t, []string{"c"}, km.EditValue.Keys())
+	assert.Equal(t, []string{"a"}, km.Add.Keys())
+	assert.Equal(t, []string{"d"}, km.Delete.Keys())
+	assert.Equal(t, []string{"R"}, km.Rename.Keys())
+	assert.Equal(t, []string{"u"}, km.Undo.Keys())
+	assert.Equal(t, []string{"ctrl+r"}, km.Redo.Keys())
+	assert.Equal(t, []string{"w"}, km.Save.Keys())
+	assert.Equal(t, []string{"s"}, km.Shadowed.Keys())
+	assert.Equal(t, []string{"D"}, km.Duplicates.Keys())
+	assert.Equal(t, []string{"v"}, km.Reveal.Keys())
+	assert.Equal(t, []string{"f"}, km.Format.Keys())
+	assert.Equal(t, []string{"W"}, km.Warnings.Keys())
 }
 
 func TestLen(t *testing.T) {
-	assert.Equal(t, 12, (KeyMap{}).Len())
+	assert.Equal(t, 27, (KeyMap{}).Len())
 }
 
 func TestNewKeyMapDefaults(t *testing.T) {
@@ -47,6 +62,21 @@ func TestNewKeyMapCustom(t *testing.T) {
 		SearchKeys:         []string{"s"},
 		SubmitKeys:         []string{"return"},
 		NextKeys:           []string{"m"},
+		RefreshKeys:        []string{"ctrl+r"},
+		GoToKeys:           []string{"ctrl+g"},
+		EditKeys:           []string{"i"},
+		EditValueKeys:      []string{"="},
+		AddKeys:            []string{"+"},
+		DeleteKeys:         []string{"-"},
+		RenameKeys:         []string{"F2"},
+		UndoKeys:           []string{"ctrl+z"},
+		RedoKeys:           []string{"ctrl+y"},
+		SaveKeys:           []string{"ctrl+s"},
+		ShadowedKeys:       []string{"ctrl+o"},
+		DuplicatesKeys:     []string{"ctrl+d"},
+		RevealKeys:         []string{"ctrl+v"},
+		FormatKeys:         []string{"ctrl+f"},
+		WarningsKeys:       []string{"ctrl+w"},
 	}
 	km := NewKeyMap(c)
 	assert.Equal(t, []string{"ctrl+e"}, km.Bottom.Keys())
@@ -61,6 +91,21 @@ func TestNewKeyMapCustom(t *testing.T) {
 	assert.Equal(t, []string{"s"}, km.Search.Keys())
 	assert.Equal(t, []string{"return"}, km.Submit.Keys())
 	assert.Equal(t, []string{"m"}, km.Next.Keys())
+	assert.Equal(t, []string{"ctrl+r"}, km.Refresh.Keys())
+	assert.Equal(t, []string{"ctrl+g"}, km.GoTo.Keys())
+	assert.Equal(t, []string{"i"}, km.Edit.Keys())
+	assert.Equal(t, []string{"="}, km.EditValue.Keys())
+	assert.Equal(t, []string{"+"}, km.Add.Keys())
+	assert.Equal(t, []string{"-"}, km.Delete.Keys())
+	assert.Equal(t, []string{"F2"}, km.Rename.Keys())
+	assert.Equal(t, []string{"ctrl+z"}, km.Undo.Keys())
+	assert.Equal(t, []string{"ctrl+y"}, km.Redo.Keys())
+	assert.Equal(t, []string{"ctrl+s"}, km.Save.Keys())
+	assert.Equal(t, []string{"ctrl+o"}, km.Shadowed.Keys())
+	assert.Equal(t, []string{"ctrl+d"}, km.Duplicates.Keys())
+	assert.Equal(t, []string{"ctrl+v"}, km.Reveal.Keys())
+	assert.Equal(t, []string{"ctrl+f"}, km.Format.Keys())
+	assert.Equal(t, []string{"ctrl+w"}, km.Warnings.Keys())
 	// fields not overridden fall back to defaults
 	assert.Equal(t, []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "0"}, km.Num.Keys())
 }
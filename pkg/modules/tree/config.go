@@ -1,5 +1,7 @@
 package tree
 
+import "github.com/crosleyzack/wndr/pkg/nodes"
+
 type TreeConfig struct {
 	ExpandedShape           string
 	ExpandableShape         string
@@ -7,6 +9,32 @@ type TreeConfig struct {
 	SpacesPerLayer          int
 	HideSummaryWhenExpanded bool
 	SpacesAfterKey          int
+	// PathSyntax is the name of the syntax copied paths are written in, one
+	// of nodes.GetPathSyntaxes
+	PathSyntax string
+	// Summaries are the templates summarizing collapsed objects and arrays
+	// at matching paths, tried in order
+	Summaries []SummaryConfig
+}
+
+// SummaryConfig summarizes the objects and arrays at the paths matching the
+// glob Path with Template, as parsed by nodes.ParseSummaryRule.
+type SummaryConfig struct {
+	Path     string
+	Template string
+}
+
+// SummaryRules parses the Summaries of c.
+func (c *TreeConfig) SummaryRules() ([]nodes.SummaryRule, error) {
+	rules := make([]nodes.SummaryRule, 0, len(c.Summaries))
+	for _, s := range c.Summaries {
+		r, err := nodes.ParseSummaryRule(s.Path, s.Template)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
 }
 
 type TreeFormat struct {
@@ -18,6 +46,18 @@ type TreeFormat struct {
 	SpacesPerLayer          int
 	HideSummaryWhenExpanded bool
 	SpacesAfterKey          int
+	PathSyntax              nodes.PathSyntax
+	// Format names the format collapsed objects and arrays are summarized
+	// in, one of nodes.GetAvailableFormats; the formats can be cycled
+	// through while running. Empty shows the values the nodes were built
+	// with until a format is picked
+	Format string
+	// Summaries summarize the objects and arrays at the paths they match in
+	// place of Format
+	Summaries []nodes.SummaryRule
+	// Repr rebuilds node values after an edit; nil uses
+	// nodes.LeafValuesOnly
+	Repr nodes.ReprNode
 }
 
 func NewFormat(c *TreeConfig) *TreeFormat {
@@ -40,6 +80,9 @@ func NewFormat(c *TreeConfig) *TreeFormat {
 	if c.SpacesAfterKey > 0 {
 		format.SpacesAfterKey = c.SpacesAfterKey
 	}
+	if syntax, err := nodes.ParsePathSyntax(c.PathSyntax); err == nil {
+		format.PathSyntax = syntax
+	}
 	return format
 }
 
@@ -53,5 +96,6 @@ func DefaultFormat() *TreeFormat {
 		SpacesPerLayer:          2,
 		HideSummaryWhenExpanded: false,
 		SpacesAfterKey:          8,
+		PathSyntax:              nodes.DotSyntax,
 	}
 }
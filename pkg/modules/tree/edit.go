@@ -0,0 +1,226 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/crosleyzack/wndr/pkg/nodes"
+)
+
+// SetEditing turns edit mode on or off. Edits are only made in edit mode.
+func (m *Model) SetEditing(editing bool) {
+	m.editing = editing
+}
+
+// Editing reports whether the tree is in edit mode.
+func (m *Model) Editing() bool {
+	return m.editing
+}
+
+// Edited reports whether the tree differs from when it was loaded or last
+// marked saved.
+func (m *Model) Edited() bool {
+	return m.history != nil && m.history.Len() != m.savedAt
+}
+
+// MarkSaved records the tree as it is now as saved, so Edited reports false
+// until the next edit.
+func (m *Model) MarkSaved() {
+	if m.history != nil {
+		m.savedAt = m.history.Len()
+	}
+}
+
+// CurrentNode returns the node under the cursor, or nil before the tree is
+// first rendered.
+func (m *Model) CurrentNode() *nodes.Node {
+	return m.currentNode
+}
+
+// SetValue changes the value of the leaf under the cursor.
+func (m *Model) SetValue(value string) error {
+	n, err := m.editTarget()
+	if err != nil {
+		return err
+	}
+	if !nodes.IsLeaf(n) {
+		return fmt.Errorf("%s is not a leaf", m.NodePath(n))
+	}
+	if v, ok := emptyContainer(value); ok {
+		return m.do(nodes.ReplaceOp{Path: nodes.PathTo(n), Node: nodes.NewNode(n.Key, v, 0, 0, m.repr)})
+	}
+	return m.do(nodes.SetOp{Path: nodes.PathTo(n), Value: value})
+}
+
+// AddsToArray reports whether Add will add an array element, which takes no
+// key, rather than an object key.
+func (m *Model) AddsToArray() bool {
+	if m.currentNode == nil {
+		return false
+	}
+	_, ok := nodes.ArrayLen(m.addParent())
+	return ok
+}
+
+// Add adds a leaf holding value. When the node under the cursor is an object
+// or array the leaf is added to it, otherwise next to it. key names the leaf
+// in an object and is ignored in an array, where the leaf is appended, or
+// placed after the cursor when added next to it.
+func (m *Model) Add(key, value string) error {
+	n, err := m.editTarget()
+	if err != nil {
+		return err
+	}
+	parent := m.addParent()
+	parentPath := nodes.PathTo(parent)
+	var added nodes.Path
+	if size, ok := nodes.ArrayLen(parent); ok {
+		index := size
+		if parent != n {
+			index, _ = strconv.Atoi(n.Key)
+			index++
+		}
+		var v any = value
+		if empty, ok := emptyContainer(value); ok {
+			v = empty
+		}
+		leaf := nodes.NewNode("", v, 0, 0, m.repr)
+		if err := m.do(nodes.InsertArrayElementOp{Path: parentPath, Index: index, Node: leaf}); err != nil {
+			return err
+		}
+		added = append(append(nodes.Path{}, parentPath...), nodes.PathElem{Key: strconv.Itoa(index), IsIndex: true})
+	} else {
+		if key == "" {
+			return errors.New("a key is needed to add to an object")
+		}
+		added = append(append(nodes.Path{}, parentPath...), nodes.PathElem{Key: key})
+		if added.Resolve(m.Root) != nil {
+			return fmt.Errorf("%s already exists", added.Format(m.pathSyntax))
+		}
+		op := nodes.Op(nodes.SetOp{Path: added, Value: value})
+		if v, ok := emptyContainer(value); ok {
+			op = nodes.ReplaceOp{Path: added, Node: nodes.NewNode(key, v, 0, 0, m.repr)}
+		}
+		if err := m.do(op); err != nil {
+			return err
+		}
+	}
+	if n := added.Resolve(m.Root); n != nil {
+		m.reveal(n)
+		m.currentNode = n
+	}
+	return nil
+}
+
+// Delete removes the node under the cursor and everything below it.
+func (m *Model) Delete() error {
+	n, err := m.editTarget()
+	if err != nil {
+		return err
+	}
+	if err := m.do(nodes.DeleteOp{Path: nodes.PathTo(n)}); err != nil {
+		return err
+	}
+	m.syncCursor()
+	return nil
+}
+
+// Rename changes the key of the node under the cursor.
+func (m *Model) Rename(key string) error {
+	n, err := m.editTarget()
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return errors.New("a key cannot be empty")
+	}
+	return m.do(nodes.RenameOp{Path: nodes.PathTo(n), Key: key})
+}
+
+// Undo reverts the last edit, returning false when there is none.
+func (m *Model) Undo() (bool, error) {
+	if m.history == nil {
+		return false, nil
+	}
+	ok, err := m.history.Undo()
+	m.summaries = nil
+	m.syncCursor()
+	return ok, err
+}
+
+// Redo reapplies the last undone edit, returning false when there is none.
+func (m *Model) Redo() (bool, error) {
+	if m.history == nil {
+		return false, nil
+	}
+	ok, err := m.history.Redo()
+	m.summaries = nil
+	m.syncCursor()
+	return ok, err
+}
+
+// editTarget returns the node under the cursor when an edit can be made.
+func (m *Model) editTarget() (*nodes.Node, error) {
+	if !m.editing {
+		return nil, errors.New("not in edit mode")
+	}
+	if m.currentNode == nil || nodes.IsRoot(m.currentNode) {
+		return nil, errors.New("no node selected")
+	}
+	return m.currentNode, nil
+}
+
+// addParent returns the node Add puts a new leaf in: the node under the cursor
+// when it can hold children, otherwise its parent.
+func (m *Model) addParent() *nodes.Node {
+	n := m.currentNode
+	if !nodes.IsLeaf(n) || n.Kind != nodes.Scalar {
+		return n
+	}
+	return n.Parent
+}
+
+// do applies op and records it for undo.
+func (m *Model) do(op nodes.Op) error {
+	if m.history == nil {
+		m.history = nodes.NewHistory(m.Root, m.repr)
+	}
+	if err := m.history.Do(op); err != nil {
+		return err
+	}
+	m.summaries = nil
+	if m.savedAt >= m.history.Len() {
+		// the saved tree was undone and can no longer be redone
+		m.savedAt = -1
+	}
+	return nil
+}
+
+// syncCursor keeps the cursor on the tree after nodes were removed, and
+// points currentNode at the node now under it.
+func (m *Model) syncCursor() {
+	count := m.NumberOfNodes()
+	m.cursor = min(m.cursor, max(count-1, 0))
+	m.currentNode = nil
+	row := 0
+	for n := range nodes.PreOrder(m.Root) {
+		if row == m.cursor {
+			m.currentNode = n
+			break
+		}
+		row++
+	}
+}
+
+// emptyContainer returns the empty object or array a value typed as {} or []
+// adds, so children can then be added to it.
+func emptyContainer(value string) (any, bool) {
+	switch value {
+	case "{}":
+		return map[string]any{}, true
+	case "[]":
+		return []any{}, true
+	}
+	return nil, false
+}
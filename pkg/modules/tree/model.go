@@ -2,7 +2,7 @@
 package tree
 
 import (
-	"fmt"
+	"slices"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/crosleyzack/wndr/pkg/keys"
@@ -30,13 +30,64 @@ type Model struct {
 	currentNode             *nodes.Node
 	spacesAfterKey          int
 	hideSummaryWhenExpanded bool
+	pathSyntax              nodes.PathSyntax
+	// changed holds the nodes marked as changed by the last SetRoot
+	changed map[*nodes.Node]bool
+	// format names the summary format, one of nodes.GetAvailableFormats, or
+	// is empty while the values the nodes were built with are shown
+	format string
+	// rules summarize the paths they match in place of format
+	rules []nodes.SummaryRule
+	// summarize summarizes collapsed objects and arrays in format; nil to
+	// show their values
+	summarize nodes.ReprNode
+	// repr rebuilds the values of nodes above an edit
+	repr nodes.ReprNode
+	// summaries caches the summaries built with summarize as nodes are
+	// shown; cleared when the tree or the format changes
+	summaries map[*nodes.Node]summary
+	// editing is set in edit mode
+	editing bool
+	// history records edits for undo and redo; nil until the first edit
+	history *nodes.History
+	// savedAt is the history length when the tree was last saved, or -1
+	// when that state can no longer be reached by undo or redo
+	savedAt int
+	// annotate gives the note shown after each value; nil for none
+	annotate Annotator
+	// detailed is passed to annotate, toggled by ToggleDetails
+	detailed bool
+	// redact gives the values shown in place of sensitive ones; nil for
+	// none
+	redact Redactor
+	// revealed shows the values redact would hide, toggled by ToggleRevealed
+	revealed bool
+}
+
+// Annotator returns a note to show after the value of n, such as where the
+// value came from, or "" for none. detailed is set while details are toggled
+// on, for a longer note, and repr summarizes objects and arrays as the tree
+// does.
+type Annotator func(n *nodes.Node, detailed bool, repr nodes.ReprNode) string
+
+// Redactor returns the value to show for n, summarized with repr when it is
+// an object or array, with any sensitive values in it masked, and whether
+// anything was masked.
+type Redactor func(n *nodes.Node, repr nodes.ReprNode) (string, bool)
+
+// summary is what is shown for an object or array.
+type summary struct {
+	value string
+	// masked is value with the sensitive values in it masked
+	masked string
+	hidden bool
 }
 
 var _ tea.Model = &Model{}
 
 // New creates a new Model for the tree
 func New(format *TreeFormat, keys keys.KeyMap, style styles.Style, root *nodes.Node) *Model {
-	return &Model{
+	m := &Model{
 		KeyMap:                  keys,
 		Styles:                  style,
 		Root:                    root,
@@ -48,22 +99,155 @@ func New(format *TreeFormat, keys keys.KeyMap, style styles.Style, root *nodes.N
 		SpacesPerLayer:          format.SpacesPerLayer,
 		hideSummaryWhenExpanded: format.HideSummaryWhenExpanded,
 		spacesAfterKey:          format.SpacesAfterKey,
+		pathSyntax:              format.PathSyntax,
+		rules:                   format.Summaries,
+		repr:                    format.Repr,
 		searchResults:           nil,
 		searchNext:              nil,
 		searchStop:              nil,
 		currentNode:             nil,
 	}
+	if m.repr == nil {
+		m.repr = nodes.LeafValuesOnly
+	}
+	m.SetFormat(format.Format)
+	return m
+}
+
+// Format returns the name of the format objects and arrays are summarized in,
+// or "" while the values they were built with are shown.
+func (m *Model) Format() string {
+	return m.format
+}
+
+// SetFormat summarizes objects and arrays in the named format, one of
+// nodes.GetAvailableFormats, with the Summaries of the TreeFormat taking
+// precedence at the paths they match. An empty name shows the values the
+// nodes were built with, or rebuilt with by the Repr of the TreeFormat after
+// an edit, where no summary matches.
+func (m *Model) SetFormat(name string) {
+	m.format = name
+	m.summarize = nil
+	if name != "" {
+		m.summarize = nodes.GetRepr(name)
+	}
+	if len(m.rules) > 0 {
+		fallback := m.summarize
+		if fallback == nil {
+			fallback = func(n *nodes.Node) string { return n.Value }
+		}
+		m.summarize = nodes.TemplateRepr(m.rules, fallback)
+	}
+	m.summaries = nil
+}
+
+// NextFormat switches to the format after the current one in
+// nodes.GetAvailableFormats, wrapping around, and returns its name.
+func (m *Model) NextFormat() string {
+	formats := nodes.GetAvailableFormats()
+	i := slices.Index(formats, m.format)
+	m.SetFormat(formats[(i+1)%len(formats)])
+	return m.format
+}
+
+// SetAnnotator shows the note given by a after each value.
+func (m *Model) SetAnnotator(a Annotator) {
+	m.annotate = a
+}
+
+// SetRedactor shows the values given by r in place of the node values, and
+// copies them in place of the node values too.
+func (m *Model) SetRedactor(r Redactor) {
+	m.redact = r
+	m.summaries = nil
+}
+
+// ToggleRevealed switches between showing the values hidden by the redactor
+// and masking them, returning whether they are now shown. Copied values stay
+// masked.
+func (m *Model) ToggleRevealed() bool {
+	m.revealed = !m.revealed
+	return m.revealed
+}
+
+// Hidden reports whether the value of n is masked on screen.
+func (m *Model) Hidden(n *nodes.Node) bool {
+	if m.redact == nil || m.revealed || n == nil {
+		return false
+	}
+	if !nodes.IsLeaf(n) {
+		return m.summaryOf(n).hidden
+	}
+	_, hidden := m.redact(n, m.summaryRepr())
+	return hidden
+}
+
+// maskedValue returns the value of n, or the summary of an object or array,
+// with any sensitive values masked.
+func (m *Model) maskedValue(n *nodes.Node) string {
+	if !nodes.IsLeaf(n) {
+		return m.summaryOf(n).masked
+	}
+	if m.redact == nil {
+		return n.Value
+	}
+	v, _ := m.redact(n, m.summaryRepr())
+	return v
+}
+
+// shownValue returns the value shown for n: masked unless revealed.
+func (m *Model) shownValue(n *nodes.Node) string {
+	if !m.revealed {
+		return m.maskedValue(n)
+	}
+	if !nodes.IsLeaf(n) {
+		return m.summaryOf(n).value
+	}
+	return n.Value
+}
+
+// summaryOf returns the summary of n, an object or array, in the current
+// format. Summaries are only built for the nodes shown, when first shown, so
+// the format can be changed without rebuilding the tree.
+func (m *Model) summaryOf(n *nodes.Node) summary {
+	if s, ok := m.summaries[n]; ok {
+		return s
+	}
+	s := summary{value: n.Value}
+	if m.summarize != nil {
+		s.value = m.summarize(n)
+	}
+	s.masked = s.value
+	if m.redact != nil {
+		s.masked, s.hidden = m.redact(n, m.summaryRepr())
+	}
+	if m.summaries == nil {
+		m.summaries = map[*nodes.Node]summary{}
+	}
+	m.summaries[n] = s
+	return s
+}
+
+// summaryRepr returns the repr objects and arrays are summarized with: in
+// the format, or as their values are rebuilt.
+func (m *Model) summaryRepr() nodes.ReprNode {
+	if m.summarize != nil {
+		return m.summarize
+	}
+	return m.repr
+}
+
+// ToggleDetails switches the notes shown after values between their short and
+// detailed forms.
+func (m *Model) ToggleDetails() {
+	m.detailed = !m.detailed
 }
 
 // NumberOfNodes returns the number of nodes in the tree
 func (m *Model) NumberOfNodes() int {
 	count := 0
-	err := nodes.DFS(m.Root, func(node *nodes.Node, _ int) error {
+	for range nodes.PreOrder(m.Root) {
 		count++
-		return nil
-	})
-	if err != nil {
-		panic(fmt.Sprintf("error counting nodes: %v", err))
 	}
 	return count
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/crosleyzack/wndr/pkg/nodes"
 	"github.com/crosleyzack/wndr/pkg/styles"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDefaultFormat(t *testing.T) {
@@ -21,6 +22,18 @@ func TestDefaultFormat(t *testing.T) {
 	assert.False(t, f.HideSummaryWhenExpanded)
 }
 
+func TestSummaryRules(t *testing.T) {
+	c := TreeConfig{Summaries: []SummaryConfig{{Path: "items[*]", Template: "{{.name}}"}}}
+	rules, err := c.SummaryRules()
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, nodes.Glob{"items", "*"}, rules[0].Path)
+
+	c.Summaries = append(c.Summaries, SummaryConfig{Path: "a", Template: "{{"})
+	_, err = c.SummaryRules()
+	assert.Error(t, err)
+}
+
 func TestNewFormatDefaults(t *testing.T) {
 	f := NewFormat(&TreeConfig{})
 	assert.Equal(t, DefaultFormat(), f)
@@ -34,6 +47,7 @@ func TestNewFormatOverrides(t *testing.T) {
 		SpacesPerLayer:          3,
 		HideSummaryWhenExpanded: true,
 		SpacesAfterKey:          2,
+		PathSyntax:              "jq",
 	})
 	assert.Equal(t, "+>", f.ExpandableShape)
 	assert.Equal(t, "--", f.LeafShape)
@@ -41,6 +55,7 @@ func TestNewFormatOverrides(t *testing.T) {
 	assert.Equal(t, 3, f.SpacesPerLayer)
 	assert.True(t, f.HideSummaryWhenExpanded)
 	assert.Equal(t, 2, f.SpacesAfterKey)
+	assert.Equal(t, nodes.JqSyntax, f.PathSyntax)
 	// non-overridden fields keep defaults
 	assert.Equal(t, 80, f.Width)
 	assert.Equal(t, 20, f.Height)
@@ -50,6 +65,20 @@ func TestNewFormatIgnoresZeroValues(t *testing.T) {
 	f := NewFormat(&TreeConfig{})
 	assert.Equal(t, 80, f.Width)
 	assert.Equal(t, 20, f.Height)
+	assert.Equal(t, nodes.DotSyntax, f.PathSyntax)
+}
+
+func TestNodePath(t *testing.T) {
+	root := nodes.New(map[string]any{
+		"spec": map[string]any{"containers": []any{map[string]any{"image.name": "app"}}},
+	}, 0, nodes.LeafValuesOnly)
+	n := nodes.Child(nodes.Child(nodes.Child(nodes.Child(root, "spec"), "containers"), "0"), "image.name")
+
+	m := New(DefaultFormat(), keys.DefaultKeyMap(), styles.DefaultStyles(), root)
+	assert.Equal(t, `spec.containers[0].image\.name`, m.NodePath(n))
+
+	m = New(NewFormat(&TreeConfig{PathSyntax: "pointer"}), keys.DefaultKeyMap(), styles.DefaultStyles(), root)
+	assert.Equal(t, "/spec/containers/0/image.name", m.NodePath(n))
 }
 
 func TestNew(t *testing.T) {
@@ -104,3 +133,300 @@ func TestInit(t *testing.T) {
 		t.Fatal("Init() returned nil command")
 	}
 }
+
+func TestSetRoot(t *testing.T) {
+	old := nodes.New(map[string]any{
+		"a": map[string]any{
+			"a1": map[string]any{"x": "1"},
+			"a2": "2",
+		},
+		"b": map[string]any{"b1": "3"},
+	}, 0, nodes.LeafValuesOnly)
+	m := New(DefaultFormat(), keys.DefaultKeyMap(), styles.DefaultStyles(), old)
+	// expand a and a/a1, then put the cursor on a/a1/x
+	nodes.Child(old, "a").Expand = true
+	nodes.Child(nodes.Child(old, "a"), "a1").Expand = true
+	m.currentNode, _ = nodes.GetNodeFromPath(old, []string{"a", "a1", "x"})
+	m.cursorTo(m.currentNode)
+	assert.Equal(t, 2, m.cursor)
+
+	// the reloaded tree gains a key before the cursor and changes a value.
+	fresh := nodes.New(map[string]any{
+		"0": "new",
+		"a": map[string]any{
+			"a1": map[string]any{"x": "changed"},
+			"a2": "2",
+		},
+		"b": map[string]any{"b1": "3"},
+	}, 0, nodes.LeafValuesOnly)
+	assert.Equal(t, 2, m.SetRoot(fresh))
+	assert.Same(t, fresh, m.Root)
+	assert.True(t, nodes.Child(fresh, "a").Expand)
+	assert.True(t, nodes.Child(nodes.Child(fresh, "a"), "a1").Expand)
+	assert.False(t, nodes.Child(fresh, "b").Expand)
+	// the cursor follows a/a1/x down one row
+	assert.Equal(t, 3, m.cursor)
+	assert.Equal(t, "changed", m.currentNode.Value)
+	assert.True(t, m.changed[m.currentNode])
+	assert.True(t, m.changed[nodes.Child(fresh, "0")])
+	m.ClearChanged()
+	assert.Empty(t, m.changed)
+
+	// when the path disappears the cursor moves to its closest ancestor
+	gone := nodes.New(map[string]any{
+		"a": map[string]any{"a2": "2"},
+	}, 0, nodes.LeafValuesOnly)
+	assert.Equal(t, 0, m.SetRoot(gone))
+	assert.Equal(t, 0, m.cursor)
+	assert.Equal(t, "a", m.currentNode.Key)
+	assert.Nil(t, m.searchNext)
+	assert.Nil(t, m.searchResults)
+}
+
+func TestSetRootMarksCollapsedAncestor(t *testing.T) {
+	old := nodes.New(map[string]any{
+		"a": map[string]any{"b": map[string]any{"c": "1"}},
+	}, 0, nodes.LeafValuesOnly)
+	m := New(DefaultFormat(), keys.DefaultKeyMap(), styles.DefaultStyles(), old)
+	nodes.Child(old, "a").Expand = true
+
+	fresh := nodes.New(map[string]any{
+		"a": map[string]any{"b": map[string]any{"c": "2"}},
+	}, 0, nodes.LeafValuesOnly)
+	// a/b is collapsed, so the change to a/b/c shows on a/b
+	assert.Equal(t, 1, m.SetRoot(fresh))
+	b, _ := nodes.GetNodeFromPath(fresh, []string{"a", "b"})
+	assert.True(t, m.changed[b])
+}
+
+func TestGoTo(t *testing.T) {
+	root := nodes.New(map[string]any{
+		"a": map[string]any{"x": "1", "y": "2"},
+		"b": "3",
+	}, 0, nodes.LeafValuesOnly)
+	m := New(DefaultFormat(), keys.DefaultKeyMap(), styles.DefaultStyles(), root)
+	a := nodes.Child(root, "a")
+	y := nodes.Child(a, "y")
+
+	// the root alone is not somewhere to go
+	assert.False(t, m.GoTo([]*nodes.Node{root}))
+	assert.False(t, m.GoTo(nil))
+	assert.Equal(t, 0, m.cursor)
+
+	// the collapsed parent is expanded to reveal the match
+	assert.True(t, m.GoTo([]*nodes.Node{root, y, nodes.Child(root, "b")}))
+	assert.True(t, a.Expand)
+	assert.Same(t, y, m.currentNode)
+	assert.Equal(t, 2, m.cursor)
+
+	// the remaining matches are cycled like search results
+	m.NextMatchingNode()
+	assert.Equal(t, 3, m.cursor)
+	m.NextMatchingNode()
+	assert.Equal(t, 2, m.cursor)
+}
+
+func TestFindDuplicates(t *testing.T) {
+	root := nodes.New(map[string]any{
+		"a": map[string]any{"x": "1"},
+		"b": map[string]any{"c": map[string]any{"x": "1"}},
+	}, 0, nodes.LeafValuesOnly)
+	m := New(DefaultFormat(), keys.DefaultKeyMap(), styles.DefaultStyles(), root)
+	groups, found := m.FindDuplicates()
+	assert.Equal(t, 1, groups)
+	assert.Equal(t, 2, found)
+	assert.Same(t, nodes.Child(root, "a"), m.currentNode)
+
+	m.NextMatchingNode()
+	assert.Same(t, nodes.Child(nodes.Child(root, "b"), "c"), m.currentNode)
+	assert.True(t, nodes.Child(root, "b").Expand)
+
+	m = New(DefaultFormat(), keys.DefaultKeyMap(), styles.DefaultStyles(), nodes.New(map[string]any{"a": "1", "b": "1"}, 0, nodes.LeafValuesOnly))
+	groups, _ = m.FindDuplicates()
+	assert.Zero(t, groups)
+}
+
+func TestEdit(t *testing.T) {
+	root := nodes.New(map[string]any{
+		"a":     map[string]any{"x": "1"},
+		"ports": []any{"80", "443"},
+	}, 0, nodes.LeafValuesOnly)
+	m := New(DefaultFormat(), keys.DefaultKeyMap(), styles.DefaultStyles(), root)
+	a := nodes.Child(root, "a")
+	require.True(t, m.GoTo([]*nodes.Node{nodes.Child(a, "x")}))
+
+	// edits are refused outside edit mode
+	assert.Error(t, m.SetValue("2"))
+	m.SetEditing(true)
+	assert.False(t, m.Edited())
+
+	require.NoError(t, m.SetValue("2"))
+	assert.Equal(t, "2", nodes.Child(a, "x").Value)
+	assert.True(t, m.Edited())
+
+	// next to a leaf in an object, a key is needed and must be new
+	assert.False(t, m.AddsToArray())
+	assert.Error(t, m.Add("", "v"))
+	assert.Error(t, m.Add("x", "v"))
+	require.NoError(t, m.Add("y", "3"))
+	assert.Same(t, nodes.Child(a, "y"), m.CurrentNode())
+
+	// in an array, the element goes after the cursor
+	ports := nodes.Child(root, "ports")
+	require.True(t, m.GoTo([]*nodes.Node{nodes.Child(ports, "0")}))
+	assert.True(t, m.AddsToArray())
+	require.NoError(t, m.Add("", "22"))
+	assert.Equal(t, []any{"80", "22", "443"}, nodes.ToValue(ports))
+
+	require.NoError(t, m.Delete())
+	assert.Equal(t, []any{"80", "443"}, nodes.ToValue(ports))
+	assert.NotNil(t, m.CurrentNode())
+
+	require.True(t, m.GoTo([]*nodes.Node{a}))
+	require.NoError(t, m.Rename("b"))
+	assert.Nil(t, nodes.Child(root, "a"))
+	assert.Equal(t, map[string]any{"x": "2", "y": "3"}, nodes.ToValue(nodes.Child(root, "b")))
+
+	m.MarkSaved()
+	assert.False(t, m.Edited())
+	for range 5 {
+		ok, err := m.Undo()
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+	ok, err := m.Undo()
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.True(t, m.Edited())
+	assert.Equal(t, map[string]any{"x": "1"}, nodes.ToValue(nodes.Child(root, "a")))
+
+	ok, err = m.Redo()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "2", nodes.Child(nodes.Child(root, "a"), "x").Value)
+
+	// {} typed as a value adds an empty object, which takes children
+	require.True(t, m.GoTo([]*nodes.Node{nodes.Child(root, "a")}))
+	require.NoError(t, m.Add("env", "{}"))
+	env := nodes.Child(nodes.Child(root, "a"), "env")
+	assert.Equal(t, nodes.Object, env.Kind)
+	assert.False(t, m.AddsToArray())
+	require.NoError(t, m.Add("A", "1"))
+	assert.Equal(t, map[string]any{"A": "1"}, nodes.ToValue(env))
+}
+
+func TestAnnotator(t *testing.T) {
+	root := nodes.New(map[string]any{"a": "1"}, 1, nodes.LeafValuesOnly)
+	m := New(DefaultFormat(), keys.DefaultKeyMap(), styles.DefaultStyles(), root)
+	m.Width, m.Height = 40, 5
+	m.SetAnnotator(func(n *nodes.Node, detailed bool, _ nodes.ReprNode) string {
+		if detailed {
+			return "from a file, overriding a very long list of values"
+		}
+		return "from a file"
+	})
+	assert.Contains(t, m.View(), "1 from a file")
+
+	m.ToggleDetails()
+	view := m.View()
+	assert.Contains(t, view, "from a file, overriding")
+	assert.Contains(t, view, "…", "the note is cut to the width")
+	assert.NotContains(t, view, "values")
+}
+
+func TestRedactor(t *testing.T) {
+	root := nodes.New(map[string]any{"password": "hunter2", "user": "admin"}, 1, nodes.LeafValuesOnly)
+	m := New(DefaultFormat(), keys.DefaultKeyMap(), styles.DefaultStyles(), root)
+	m.Width, m.Height = 40, 5
+	password := nodes.Child(root, "password")
+	m.SetRedactor(func(n *nodes.Node, _ nodes.ReprNode) (string, bool) {
+		if n == password {
+			return "***", true
+		}
+		return n.Value, false
+	})
+	view := m.View()
+	assert.Contains(t, view, "***")
+	assert.NotContains(t, view, "hunter2")
+	assert.True(t, m.Hidden(password))
+	assert.False(t, m.Hidden(nodes.Child(root, "user")))
+
+	assert.True(t, m.ToggleRevealed())
+	assert.Contains(t, m.View(), "hunter2")
+	assert.False(t, m.Hidden(password))
+	// copies stay masked
+	assert.Equal(t, "***", m.maskedValue(password))
+
+	assert.False(t, m.ToggleRevealed())
+	assert.NotContains(t, m.View(), "hunter2")
+}
+
+func TestSearchDocumentOrder(t *testing.T) {
+	root := nodes.New(map[string]any{
+		"a": map[string]any{"x": "match", "y": "no"},
+		"b": "match",
+	}, 0, nodes.LeafValuesOnly)
+	m := New(DefaultFormat(), keys.DefaultKeyMap(), styles.DefaultStyles(), root)
+	a := nodes.Child(root, "a")
+
+	assert.NoError(t, m.GetMatchingNodes("match"))
+	m.NextMatchingNode()
+	assert.Same(t, nodes.Child(a, "x"), m.currentNode)
+	assert.True(t, a.Expand)
+	assert.Equal(t, 1, m.cursor)
+	m.NextMatchingNode()
+	assert.Same(t, nodes.Child(root, "b"), m.currentNode)
+	assert.Equal(t, 3, m.cursor)
+	// the results are cycled once the search is done
+	m.NextMatchingNode()
+	assert.Same(t, nodes.Child(a, "x"), m.currentNode)
+}
+
+func TestFormat(t *testing.T) {
+	root := nodes.New(map[string]any{"db": map[string]any{"user": "admin", "port": 5432}}, 0, nodes.EmptyRepr)
+	format := DefaultFormat()
+	format.Width, format.Height = 60, 5
+	format.Format = nodes.LeafValuesOnlyRepr
+	m := New(format, keys.DefaultKeyMap(), styles.DefaultStyles(), root)
+	assert.Equal(t, nodes.LeafValuesOnlyRepr, m.Format())
+	assert.Contains(t, m.View(), "5432 admin", "summaries are built when shown")
+	assert.Empty(t, nodes.Child(root, "db").Value)
+
+	assert.Equal(t, nodes.DirectChildrenKeysRepr, m.NextFormat())
+	assert.Contains(t, m.View(), "port user")
+	m.SetFormat(nodes.KeyCountAndTypesRepr)
+	assert.Equal(t, nodes.LeafValuesWithBracketsRepr, m.NextFormat(), "the formats wrap around")
+
+	// an edit rebuilds the summaries shown
+	m.SetEditing(true)
+	m.SetFormat(nodes.LeafValuesOnlyRepr)
+	m.View()
+	m.cursorTo(nodes.Child(root, "db"))
+	require.NoError(t, m.Add("host", "localhost"))
+	m.cursor = 0
+	assert.Contains(t, m.View(), "localhost 5432 admin")
+
+	rule, err := nodes.ParseSummaryRule("db", "{{.user}}@{{.host}}")
+	require.NoError(t, err)
+	format.Summaries = []nodes.SummaryRule{rule}
+	m = New(format, keys.DefaultKeyMap(), styles.DefaultStyles(), root)
+	assert.Contains(t, m.View(), "admin@localhost")
+	m.NextFormat()
+	assert.Contains(t, m.View(), "admin@localhost", "templates apply in every format")
+
+	// without a format the values the tree was built with are shown, and
+	// rebuilt with Repr after an edit
+	root = nodes.New(map[string]any{"db": map[string]any{"user": "admin", "port": 5432}}, 0, nodes.KeyCountOnly)
+	format = DefaultFormat()
+	format.Width, format.Height = 60, 5
+	format.Repr = nodes.KeyCountOnly
+	m = New(format, keys.DefaultKeyMap(), styles.DefaultStyles(), root)
+	assert.Empty(t, m.Format())
+	assert.Contains(t, m.View(), "(2 keys)")
+	m.SetEditing(true)
+	m.cursorTo(nodes.Child(root, "db"))
+	require.NoError(t, m.Add("host", "localhost"))
+	m.cursor = 0
+	assert.Contains(t, m.View(), "(3 keys)")
+	assert.Equal(t, nodes.GetAvailableFormats()[0], m.NextFormat())
+}
@@ -1,7 +1,6 @@
 package tree
 
 import (
-	"errors"
 	"fmt"
 	"iter"
 	"regexp"
@@ -14,10 +13,6 @@ import (
 	"github.com/tiagomelo/go-clipboard/clipboard"
 )
 
-const (
-	PathSeparator = "."
-)
-
 // Update the JSON component
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if m == nil {
@@ -80,16 +75,8 @@ func (m *Model) InvertCollaped() {
 
 // ExpandCollapseAll set the expand flag on every node
 func (m *Model) ExpandCollapseAll(n *nodes.Node, expand bool) {
-	err := nodes.DFS(
-		n,
-		func(n *nodes.Node, _ int) error {
-			n.Expand = expand
-			return nil
-		},
-		nodes.WithNextNodes(nodes.AllChildren),
-	)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to expand-collapse all: %v", err))
+	for n := range nodes.PreOrder(n, nodes.WithNextNodes(nodes.AllChildren)) {
+		n.Expand = expand
 	}
 }
 
@@ -100,19 +87,30 @@ func (m *Model) GetMatchingNodes(searchTerm string) error {
 		m.searchStop()
 	}
 	m.searchResults = make([]*nodes.Node, 0)
-	m.searchNext, m.searchStop = iter.Pull(nodes.DFSIter(m.Root, func(node *nodes.Node) bool {
-		if out, err := regexp.Match(searchTerm, []byte(node.Key)); err == nil && out {
-			return true
-		}
-		// only match values for leaf nodes
-		if nodes.IsLeaf(node) {
-			if out, err := regexp.Match(searchTerm, []byte(node.Value)); err == nil && out {
-				return true
+	m.searchNext, m.searchStop = iter.Pull(matchingNodes(m.Root, searchTerm))
+	return nil
+}
+
+// matchingNodes returns an iterator over the nodes under root, in document
+// order, whose key or, for leaves, value matches searchTerm.
+func matchingNodes(root *nodes.Node, searchTerm string) iter.Seq[*nodes.Node] {
+	return func(yield func(*nodes.Node) bool) {
+		for node := range nodes.PreOrder(root, nodes.WithNextNodes(nodes.AllChildren)) {
+			match := false
+			if out, err := regexp.Match(searchTerm, []byte(node.Key)); err == nil && out {
+				match = true
+			}
+			// only match values for leaf nodes
+			if !match && nodes.IsLeaf(node) {
+				if out, err := regexp.Match(searchTerm, []byte(node.Value)); err == nil && out {
+					match = true
+				}
+			}
+			if match && !yield(node) {
+				return
 			}
 		}
-		return false
-	}, nodes.WithNextNodes(nodes.AllChildren)))
-	return nil
+	}
 }
 
 // nextNodeFromResults get next item from stored results
@@ -147,26 +145,161 @@ func (m *Model) NextMatchingNode() {
 		// we couldn't get another node, just do nothing
 		return
 	}
-	// expand parents
-	for n := m.currentNode; n != nil; n = n.Parent {
-		n.Expand = true
+	m.reveal(m.currentNode)
+}
+
+// GoTo moves the cursor to the first of matches, such as the result of a path
+// query, and keeps the rest as search results so the next key cycles through
+// them. The root is skipped as it is never displayed. It returns false, leaving
+// the cursor in place, when there is nothing to go to.
+func (m *Model) GoTo(matches []*nodes.Node) bool {
+	results := make([]*nodes.Node, 0, len(matches))
+	for _, n := range matches {
+		if !nodes.IsRoot(n) {
+			results = append(results, n)
+		}
+	}
+	if len(results) == 0 {
+		return false
 	}
-	// get cursor position of current node
+	if m.searchStop != nil {
+		m.searchStop()
+	}
+	m.searchNext, m.searchStop, m.searchResults = nil, nil, results
+	m.nextNodeFromResults()
+	m.reveal(m.currentNode)
+	return true
+}
+
+// FindDuplicates moves the cursor to the first of the subtrees that hold the
+// same data as another, keeping the rest, group after group, for the next key
+// to cycle through. It returns the number of groups and of subtrees found.
+func (m *Model) FindDuplicates() (int, int) {
+	groups := nodes.Duplicates(m.Root)
+	var found []*nodes.Node
+	for _, g := range groups {
+		found = append(found, g...)
+	}
+	m.GoTo(found)
+	return len(groups), len(found)
+}
+
+// reveal expands the ancestors of n and moves the cursor to it.
+func (m *Model) reveal(n *nodes.Node) {
+	for p := n; p != nil; p = p.Parent {
+		p.Expand = true
+	}
+	m.cursorTo(n)
+}
+
+// cursorTo moves the cursor to the row displaying n. n must be visible, that is
+// all of its ancestors expanded.
+func (m *Model) cursorTo(n *nodes.Node) {
 	count := 0
-	nodes.DFS(m.Root, func(node *nodes.Node, layer int) error {
-		if node.Equal(m.currentNode) {
+	for node := range nodes.PreOrder(m.Root) {
+		if node.Equal(n) {
 			m.cursor = count
-			return errors.New("break out")
+			return
 		}
 		count++
-		return nil
-	})
+	}
 }
 
-// CopyNodePath find path to node and copies it to clipboard
+// SetRoot replaces the displayed tree with root, as when the data is reloaded.
+// Nodes at a path that existed in the old tree keep its expanded state, and
+// the cursor stays on the same path, or on its closest surviving ancestor. Any
+// search in progress is dropped as its results belong to the old tree.
+//
+// Any edit history is dropped, so unsaved edits are lost.
+//
+// Leaves whose value changed and nodes at new paths are marked as changed and
+// rendered with the Changed style until ClearChanged is called; a change
+// hidden inside a collapsed node marks that node instead. SetRoot returns the
+// number of changed nodes.
+func (m *Model) SetRoot(root *nodes.Node) int {
+	var current []string
+	if m.currentNode != nil {
+		current = nodes.GetPathToNode(m.currentNode)
+	}
+	type state struct {
+		expand bool
+		value  string
+		leaf   bool
+	}
+	old := map[string]state{}
+	if m.Root != nil {
+		for n := range nodes.PreOrder(m.Root, nodes.WithNextNodes(nodes.AllChildren)) {
+			old[pathKey(nodes.GetPathToNode(n))] = state{expand: n.Expand, value: n.Value, leaf: nodes.IsLeaf(n)}
+		}
+	}
+	var changed []*nodes.Node
+	for n := range nodes.PreOrder(root, nodes.WithNextNodes(nodes.AllChildren)) {
+		prev, ok := old[pathKey(nodes.GetPathToNode(n))]
+		switch {
+		case !ok:
+			changed = append(changed, n)
+		case nodes.IsLeaf(n) && (!prev.leaf || prev.value != n.Value):
+			changed = append(changed, n)
+		}
+		if ok {
+			n.Expand = prev.expand
+		}
+	}
+	m.changed = make(map[*nodes.Node]bool, len(changed))
+	// nothing is new on the first tree shown
+	if m.Root != nil {
+		for _, n := range changed {
+			m.changed[visibleAncestor(n)] = true
+		}
+	}
+	if m.searchStop != nil {
+		m.searchStop()
+	}
+	m.searchNext, m.searchStop, m.searchResults = nil, nil, nil
+	m.Root = root
+	m.summaries = nil
+	// edits belong to the old tree
+	m.history, m.savedAt = nil, 0
+	m.currentNode = nil
+	if current != nil {
+		if n, _ := nodes.GetNodeFromPath(root, current); n != nil && !nodes.IsRoot(n) {
+			m.currentNode = n
+			m.cursorTo(n)
+		} else {
+			m.cursor = min(m.cursor, max(m.NumberOfNodes()-1, 0))
+		}
+	}
+	return len(m.changed)
+}
+
+// ClearChanged removes the changed marks set by SetRoot.
+func (m *Model) ClearChanged() {
+	m.changed = nil
+}
+
+// visibleAncestor returns the node displayed in place of n: n itself when
+// every ancestor is expanded, otherwise its outermost collapsed ancestor.
+func visibleAncestor(n *nodes.Node) *nodes.Node {
+	visible := n
+	for p := n.Parent; p != nil && !nodes.IsRoot(p); p = p.Parent {
+		if !p.Expand {
+			visible = p
+		}
+	}
+	return visible
+}
+
+// pathKey joins a node path into a map key, separating the parts with a NUL
+// byte so ["a", "b"] and ["ab"] do not share a key.
+func pathKey(path []string) string {
+	return strings.Join(path, "\x00")
+}
+
+// CopyNodePath find path to node and copies it to clipboard, written in the
+// configured path syntax
 func (m *Model) CopyNodePath() error {
 	// TODO: if value is empty and has children, get string json
-	s := strings.Join(nodes.GetPathToNode(m.currentNode), PathSeparator) + " = " + m.currentNode.Value
+	s := m.NodePath(m.currentNode) + " = " + m.maskedValue(m.currentNode)
 	c := clipboard.New()
 	if err := c.CopyText(s); err != nil {
 		return fmt.Errorf("failed to copy %s to clipboard: %w", s, err)
@@ -174,14 +307,21 @@ func (m *Model) CopyNodePath() error {
 	return nil
 }
 
-// SetLayersExpanded expands the tree to N layers shown, the rest being collapsed
+// NodePath returns the path to n written in the configured path syntax
+func (m *Model) NodePath(n *nodes.Node) string {
+	return m.FormatPath(nodes.PathTo(n))
+}
+
+// FormatPath returns p written in the configured path syntax
+func (m *Model) FormatPath(p nodes.Path) string {
+	return p.Format(m.pathSyntax)
+}
+
+// SetLayersExpanded expands the tree to N layers shown, the rest being collapsed.
+// It returns no error; the error is kept for compatibility.
 func (m *Model) SetLayersExpanded(num int) error {
-	return nodes.DFS(m.Root, func(node *nodes.Node, layer int) error {
-		if layer < num {
-			node.Expand = true
-		} else {
-			node.Expand = false
-		}
-		return nil
-	})
+	for node, layer := range nodes.PreOrder(m.Root) {
+		node.Expand = layer < num
+	}
+	return nil
 }
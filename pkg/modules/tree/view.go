@@ -1,7 +1,6 @@
 package tree
 
 import (
-	"fmt"
 	"strings"
 	"unicode/utf8"
 
@@ -35,35 +34,25 @@ func (m *Model) View() string {
 	if m == nil || m.Root == nil {
 		return "no data"
 	}
-	treeContent, err := m.renderTree()
-	if err != nil {
-		return fmt.Sprintf("An error occurred: %v", err)
-	}
-	return treeContent
+	return m.renderTree()
 }
 
 // renderTree renders the json tree in the component
-func (m *Model) renderTree() (string, error) {
+func (m *Model) renderTree() string {
 	var b strings.Builder
-	count := 0
+	idx := 0
 	minRow, maxRow := m.getDisplayRange(m.NumberOfNodes())
-	f := func(node *nodes.Node, layer int) error {
-		// Generate the correct index for the node
-		idx := count
-		count++
-		if idx < minRow || idx > maxRow {
-			return nil // Skip nodes outside the display range
+	for node, layer := range nodes.PreOrder(m.Root) {
+		if idx > maxRow {
+			break
 		}
-		str := m.getLine(node, layer, idx)
-		if len(str) > 0 {
-			b.WriteString(str)
+		// Skip nodes above the display range
+		if idx >= minRow {
+			b.WriteString(m.getLine(node, layer, idx))
 		}
-		return nil
-	}
-	if err := nodes.DFS(m.Root, f); err != nil {
-		return "", fmt.Errorf("Failed to render tree: %w", err)
+		idx++
 	}
-	return lipgloss.NewStyle().Height(m.Height).Width(m.Width).Render(b.String()), nil
+	return lipgloss.NewStyle().Height(m.Height).Width(m.Width).Render(b.String())
 }
 
 // getDisplayRange returns the range of rows that should be displayed
@@ -127,7 +116,7 @@ func (m *Model) getLine(node *nodes.Node, layer int, index int) string {
 // nodeRenderer render the node itself in the remaining space after line shape and spacing
 func (m *Model) nodeRenderer(node *nodes.Node, index, availableChars int) string {
 	keyStr := replaceAll(node.Key, "\n\r", " ")
-	valueStr := replaceAll(node.Value, "\n\r", " ")
+	valueStr := replaceAll(m.shownValue(node), "\n\r", " ")
 	keyWidth := utf8.RuneCountInString(keyStr)
 	spacesNeeded := siblingMaxKeyWidth(node, m.Root) + m.spacesAfterKey - keyWidth
 	if spacesNeeded < 0 {
@@ -154,6 +143,9 @@ func (m *Model) nodeRenderer(node *nodes.Node, index, availableChars int) string
 		m.currentNode = node
 		keyStyle = m.Styles.Selected.Inherit(baseStyle)
 		valueStyle = m.Styles.Selected.Inherit(baseStyle)
+	case m.changed[node]:
+		keyStyle = m.Styles.Changed.Inherit(baseStyle)
+		valueStyle = m.Styles.Changed.Inherit(baseStyle)
 	default:
 		keyStyle = m.Styles.Unselected.Inherit(baseStyle)
 		valueStyle = m.Styles.Unselected.Inherit(baseStyle)
@@ -162,6 +154,22 @@ func (m *Model) nodeRenderer(node *nodes.Node, index, availableChars int) string
 	if !node.Expand || !m.hideSummaryWhenExpanded {
 		str += baseStyle.Render(strings.Repeat(" ", spacesNeeded))
 		str += valueStyle.Render(valueStr)
+		availableChars -= utf8.RuneCountInString(valueStr)
+	}
+	// the note gets whatever room the value leaves
+	if m.annotate != nil && availableChars > 2 {
+		if note := replaceAll(m.annotate(node, m.detailed, m.summaryRepr()), "\n\r", " "); note != "" {
+			str += baseStyle.Render(" ") + m.Styles.Help.Inherit(baseStyle).Render(truncate(note, availableChars-1))
+		}
 	}
 	return str
 }
+
+// truncate shortens s to at most n runes, ending it with an ellipsis when cut
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,96 @@
+package nodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// benchSizes are the sizes of the generated JSON documents the benchmarks
+// build trees from. Run the largest alone with -bench 'New/100MB' -benchtime 3x.
+var benchSizes = []int{1 << 20, 10 << 20, 100 << 20}
+
+var benchDocs sync.Map
+
+// benchDoc returns a JSON document of about size bytes, shaped like a list of
+// Kubernetes objects: arrays of objects repeating the same keys.
+func benchDoc(size int) []byte {
+	if doc, ok := benchDocs.Load(size); ok {
+		return doc.([]byte)
+	}
+	var sb strings.Builder
+	sb.WriteString(`{"apiVersion": "v1", "items": [`)
+	for i := 0; sb.Len() < size; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `{"metadata": {"name": "app-%d", "namespace": "ns-%d", "labels": {"app": "app-%d", "tier": "backend"}},`+
+			` "spec": {"replicas": %d, "paused": false, "containers": [{"name": "app", "image": "registry/app:1.%d",`+
+			` "ports": [80, 443], "resources": {"cpu": "500m", "memory": "1Gi"}}]}}`, i, i%50, i, i%5, i%100)
+	}
+	sb.WriteString("]}")
+	doc := []byte(sb.String())
+	benchDocs.Store(size, doc)
+	return doc
+}
+
+// decode decodes a document returned by benchDoc.
+func decode(b *testing.B, doc []byte) map[string]any {
+	b.Helper()
+	var m map[string]any
+	if err := json.Unmarshal(doc, &m); err != nil {
+		b.Fatal(err)
+	}
+	return m
+}
+
+// BenchmarkNew builds a tree from a decoded document. heap-B/input-B is the
+// memory the tree holds, once the decoded document is dropped, per byte of
+// JSON.
+func BenchmarkNew(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("%dMB", size>>20), func(b *testing.B) {
+			data := benchDoc(size)
+			b.ReportAllocs()
+			b.ResetTimer()
+			var held uint64
+			for range b.N {
+				b.StopTimer()
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				doc := decode(b, data)
+				b.StartTimer()
+				root := New(doc, 0, LeafValuesOnly)
+				// doc is no longer used, so it is collected here
+				b.StopTimer()
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				held += after.HeapAlloc - before.HeapAlloc
+				runtime.KeepAlive(root)
+				b.StartTimer()
+			}
+			b.ReportMetric(float64(held)/float64(b.N)/float64(size), "heap-B/input-B")
+		})
+	}
+}
+
+// BenchmarkWalk visits every node of a built tree.
+func BenchmarkWalk(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("%dMB", size>>20), func(b *testing.B) {
+			root := New(decode(b, benchDoc(size)), 0, LeafValuesOnly)
+			b.ResetTimer()
+			for range b.N {
+				count := 0
+				DFS(root, func(*Node, int) error {
+					count++
+					return nil
+				}, WithNextNodes(AllChildren))
+			}
+		})
+	}
+}
@@ -0,0 +1,436 @@
+package nodes
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/crosleyzack/wndr/pkg/omap"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound is returned when a path does not lead to a node
+	ErrNotFound = errors.New("no node at path")
+	// ErrExists is returned when a node is added where one already is
+	ErrExists = errors.New("a node already exists at path")
+	// ErrInvalidEdit is returned for edits the tree cannot hold, such as
+	// renaming an array element or adding a child to a scalar
+	ErrInvalidEdit = errors.New("invalid edit")
+)
+
+// Op is a reversible change to a tree. Ops address nodes by Path rather than
+// by pointer, so an Op stays valid after the nodes it touched are replaced.
+type Op interface {
+	// Apply makes the change to the tree under root and returns the Op that
+	// undoes it. The summaries of changed nodes and their ancestors are
+	// rebuilt with repr, or LeafValuesOnly when repr is nil. When Apply fails
+	// the tree is left unchanged.
+	Apply(root *Node, repr ReprNode) (Op, error)
+	fmt.Stringer
+}
+
+// SetOp sets the value of the leaf at Path, adding the leaf when its parent
+// exists but it does not. A new array element may only be added at the end.
+type SetOp struct {
+	Path  Path
+	Value string
+}
+
+func (o SetOp) Apply(root *Node, repr ReprNode) (Op, error) {
+	if n := o.Path.Resolve(root); n != nil {
+		if IsRoot(n) {
+			return nil, fmt.Errorf("%w: cannot set the root", ErrInvalidEdit)
+		}
+		if !IsLeaf(n) {
+			return nil, fmt.Errorf("%w: %s is not a leaf", ErrInvalidEdit, o.Path)
+		}
+		if n.Kind != Scalar {
+			// an empty object or array is replaced by a scalar, which
+			// replacing it back undoes
+			return ReplaceOp{Path: o.Path, Node: &Node{ID: uuid.New(), Value: o.Value}}.Apply(root, repr)
+		}
+		old := n.Value
+		n.Value = o.Value
+		Invalidate(n)
+		refresh(n.Parent, repr)
+		return SetOp{Path: o.Path, Value: old}, nil
+	}
+	placed, err := place(root, o.Path, &Node{ID: uuid.New(), Value: o.Value}, true, repr)
+	if err != nil {
+		return nil, err
+	}
+	return DeleteOp{Path: placed}, nil
+}
+
+func (o SetOp) String() string {
+	return fmt.Sprintf("set %s = %s", o.Path, o.Value)
+}
+
+// DeleteOp removes the node at Path and everything below it. Later elements
+// of an array move down to fill the gap.
+type DeleteOp struct {
+	Path Path
+}
+
+func (o DeleteOp) Apply(root *Node, repr ReprNode) (Op, error) {
+	n, err := lookup(root, o.Path)
+	if err != nil {
+		return nil, err
+	}
+	parent := n.Parent
+	parentPath := o.Path[:len(o.Path)-1]
+	index, inArray := remove(n)
+	refresh(parent, repr)
+	if inArray {
+		return InsertArrayElementOp{Path: parentPath, Index: index, Node: n}, nil
+	}
+	return ReplaceOp{Path: o.Path, Node: n}, nil
+}
+
+func (o DeleteOp) String() string {
+	return fmt.Sprintf("delete %s", o.Path)
+}
+
+// RenameOp changes the key of the node at Path to Key. Array elements cannot
+// be renamed, and Key must not already be used by a sibling.
+type RenameOp struct {
+	Path Path
+	Key  string
+}
+
+func (o RenameOp) Apply(root *Node, repr ReprNode) (Op, error) {
+	n, err := lookup(root, o.Path)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := ArrayLen(n.Parent); ok {
+		return nil, fmt.Errorf("%w: cannot rename array element %s", ErrInvalidEdit, o.Path)
+	}
+	old := n.Key
+	renamed := append(append(Path{}, o.Path[:len(o.Path)-1]...), PathElem{Key: o.Key})
+	if o.Key == old {
+		return RenameOp{Path: renamed, Key: old}, nil
+	}
+	if Child(n.Parent, o.Key) != nil {
+		return nil, fmt.Errorf("%w: %s", ErrExists, renamed)
+	}
+	rekey(n, o.Key)
+	refresh(n.Parent, repr)
+	return RenameOp{Path: renamed, Key: old}, nil
+}
+
+func (o RenameOp) String() string {
+	return fmt.Sprintf("rename %s to %s", o.Path, o.Key)
+}
+
+// MoveOp moves the node at From to To. As in JSON Patch, the node is removed
+// before To is resolved, so indices in To refer to the array without it. A
+// node moved into an array is inserted at To's index; anywhere else To must
+// not already exist.
+type MoveOp struct {
+	From Path
+	To   Path
+}
+
+func (o MoveOp) Apply(root *Node, repr ReprNode) (Op, error) {
+	n, err := lookup(root, o.From)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.To) > len(o.From) && equalKeys(o.To[:len(o.From)], o.From) {
+		return nil, fmt.Errorf("%w: cannot move %s into itself", ErrInvalidEdit, o.From)
+	}
+	undo, err := DeleteOp{Path: o.From}.Apply(root, repr)
+	if err != nil {
+		return nil, err
+	}
+	placed, err := place(root, o.To, n, false, repr)
+	if err != nil {
+		// put the node back where it was
+		if _, undoErr := undo.Apply(root, repr); undoErr != nil {
+			return nil, errors.Join(err, undoErr)
+		}
+		return nil, err
+	}
+	from := o.From
+	if i, ok := undo.(InsertArrayElementOp); ok {
+		from = append(append(Path{}, i.Path...), PathElem{Key: strconv.Itoa(i.Index), IsIndex: true})
+	}
+	return MoveOp{From: placed, To: from}, nil
+}
+
+func (o MoveOp) String() string {
+	return fmt.Sprintf("move %s to %s", o.From, o.To)
+}
+
+// InsertArrayElementOp inserts Node into the array at Path so that it has
+// index Index, moving later elements up. Index may be the length of the array
+// to append. The op takes ownership of Node.
+type InsertArrayElementOp struct {
+	Path  Path
+	Index int
+	Node  *Node
+}
+
+func (o InsertArrayElementOp) Apply(root *Node, repr ReprNode) (Op, error) {
+	if o.Node == nil {
+		return nil, fmt.Errorf("%w: no node to insert", ErrInvalidEdit)
+	}
+	arr := o.Path.Resolve(root)
+	if arr == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, o.Path)
+	}
+	if _, ok := ArrayLen(arr); !ok {
+		return nil, fmt.Errorf("%w: %s is not an array", ErrInvalidEdit, o.Path)
+	}
+	elem := PathElem{Key: strconv.Itoa(o.Index), IsIndex: true}
+	placed, err := place(root, append(append(Path{}, o.Path...), elem), o.Node, false, repr)
+	if err != nil {
+		return nil, err
+	}
+	return DeleteOp{Path: placed}, nil
+}
+
+func (o InsertArrayElementOp) String() string {
+	return fmt.Sprintf("insert into %s at %d", o.Path, o.Index)
+}
+
+// ReplaceOp puts Node at Path in place of the node already there, or adds it
+// when there is none. The op takes ownership of Node.
+type ReplaceOp struct {
+	Path Path
+	Node *Node
+}
+
+func (o ReplaceOp) Apply(root *Node, repr ReprNode) (Op, error) {
+	if len(o.Path) == 0 {
+		return nil, fmt.Errorf("%w: cannot replace the root", ErrInvalidEdit)
+	}
+	if o.Node == nil {
+		return nil, fmt.Errorf("%w: no node to put at %s", ErrInvalidEdit, o.Path)
+	}
+	old := o.Path.Resolve(root)
+	if old == nil {
+		placed, err := place(root, o.Path, o.Node, true, repr)
+		if err != nil {
+			return nil, err
+		}
+		return DeleteOp{Path: placed}, nil
+	}
+	parent := old.Parent
+	parent.Children.Delete(old.Key)
+	old.Parent = nil
+	attach(parent, old.Key, o.Node)
+	refresh(parent, repr)
+	return ReplaceOp{Path: o.Path, Node: old}, nil
+}
+
+func (o ReplaceOp) String() string {
+	return fmt.Sprintf("replace %s", o.Path)
+}
+
+// History applies Ops to a tree and records them so they can be undone and
+// redone.
+type History struct {
+	root *Node
+	repr ReprNode
+	undo []Op
+	redo []Op
+}
+
+// NewHistory returns an empty History editing the tree under root.
+func NewHistory(root *Node, repr ReprNode) *History {
+	return &History{root: root, repr: repr}
+}
+
+// Do applies op, making it the next change to undo. Any undone changes can no
+// longer be redone.
+func (h *History) Do(op Op) error {
+	inverse, err := op.Apply(h.root, h.repr)
+	if err != nil {
+		return err
+	}
+	h.undo = append(h.undo, inverse)
+	h.redo = nil
+	return nil
+}
+
+// Undo reverts the last change, returning false when there is none.
+func (h *History) Undo() (bool, error) {
+	return h.step(&h.undo, &h.redo)
+}
+
+// Redo reapplies the last undone change, returning false when there is none.
+func (h *History) Redo() (bool, error) {
+	return h.step(&h.redo, &h.undo)
+}
+
+// step applies the last op of from and pushes its inverse onto to.
+func (h *History) step(from, to *[]Op) (bool, error) {
+	if len(*from) == 0 {
+		return false, nil
+	}
+	op := (*from)[len(*from)-1]
+	inverse, err := op.Apply(h.root, h.repr)
+	if err != nil {
+		return false, err
+	}
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, inverse)
+	return true, nil
+}
+
+// Len returns the number of changes that can be undone.
+func (h *History) Len() int {
+	return len(h.undo)
+}
+
+// Clone returns a deep copy of n with new IDs. The copy has no parent.
+func Clone(n *Node) *Node {
+	c := &Node{
+		ID:     uuid.New(),
+		Key:    n.Key,
+		Value:  n.Value,
+		Expand: n.Expand,
+		Kind:   n.Kind,
+	}
+	if n.Children.Len() > 0 {
+		c.Children = omap.New[string, *Node]()
+		for _, child := range n.Children.Iter() {
+			addChild(c, Clone(child))
+		}
+	}
+	return c
+}
+
+// lookup returns the node at p, which must not be the root.
+func lookup(root *Node, p Path) (*Node, error) {
+	if len(p) == 0 {
+		return nil, fmt.Errorf("%w: cannot edit the root", ErrInvalidEdit)
+	}
+	n := p.Resolve(root)
+	if n == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, p)
+	}
+	return n, nil
+}
+
+// isContainer reports whether children may be added to n: it is the root, has
+// children, or is an empty object or array.
+func isContainer(n *Node) bool {
+	return IsRoot(n) || !IsLeaf(n) || n.Kind != Scalar
+}
+
+// place adds n at p, whose parent must exist. In an array, n is inserted at
+// p's index, moving later elements up, unless appendOnly is set, in which
+// case the index must be the array length. Elsewhere, p must not exist. It
+// returns p with its last step marked as an index when n went into an array.
+func place(root *Node, p Path, n *Node, appendOnly bool, repr ReprNode) (Path, error) {
+	if len(p) == 0 {
+		return nil, fmt.Errorf("%w: cannot replace the root", ErrInvalidEdit)
+	}
+	parentPath, key := p[:len(p)-1], p[len(p)-1].Key
+	parent := parentPath.Resolve(root)
+	if parent == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, parentPath)
+	}
+	if !isContainer(parent) {
+		return nil, fmt.Errorf("%w: %s is not an object or array", ErrInvalidEdit, parentPath)
+	}
+	placed := append(Path{}, parentPath...)
+	if size, ok := ArrayLen(parent); ok {
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index > size || (appendOnly && index != size) {
+			return nil, fmt.Errorf("%w: %s is not a valid index into %s", ErrInvalidEdit, key, parentPath)
+		}
+		for i := size - 1; i >= index; i-- {
+			rekey(Child(parent, strconv.Itoa(i)), strconv.Itoa(i+1))
+		}
+		key = strconv.Itoa(index)
+		placed = append(placed, PathElem{Key: key, IsIndex: true})
+	} else {
+		if Child(parent, key) != nil {
+			return nil, fmt.Errorf("%w: %s", ErrExists, p)
+		}
+		placed = append(placed, PathElem{Key: key})
+	}
+	attach(parent, key, n)
+	refresh(parent, repr)
+	return placed, nil
+}
+
+// remove detaches n from its parent. When the parent is an array, later
+// elements move down to fill the gap and n's index is returned. A parent left
+// without children becomes an empty object or array. The caller refreshes the
+// parent's summary.
+func remove(n *Node) (int, bool) {
+	parent := n.Parent
+	size, inArray := ArrayLen(parent)
+	if inArray {
+		// an array left empty is still one
+		parent.Kind = Array
+	} else if parent.Kind == Scalar {
+		parent.Kind = Object
+	}
+	parent.Children.Delete(n.Key)
+	Invalidate(parent)
+	n.Parent = nil
+	index := -1
+	if inArray {
+		index, _ = strconv.Atoi(n.Key)
+		for i := index + 1; i < size; i++ {
+			rekey(Child(parent, strconv.Itoa(i)), strconv.Itoa(i-1))
+		}
+	}
+	if IsLeaf(parent) && !IsRoot(parent) {
+		parent.Value = "{}"
+		if parent.Kind == Array {
+			parent.Value = "[]"
+		}
+	}
+	return index, inArray
+}
+
+// attach adds n as the child of parent with the given key.
+func attach(parent *Node, key string, n *Node) {
+	if IsLeaf(parent) {
+		// a leaf may hold a zero value OMap, which does not keep keys sorted
+		parent.Children = omap.New[string, *Node]()
+	}
+	n.Key = key
+	addChild(parent, n)
+}
+
+// rekey changes the key n is stored under in its parent.
+func rekey(n *Node, key string) {
+	Invalidate(n.Parent)
+	n.Parent.Children.Delete(n.Key)
+	n.Key = key
+	n.Parent.Children.Put(key, n)
+}
+
+// refresh rebuilds the summaries of n and its ancestors after a change below
+// them. The sentinel root has no summary.
+func refresh(n *Node, repr ReprNode) {
+	if repr == nil {
+		repr = LeafValuesOnly
+	}
+	for cur := n; cur != nil && !IsRoot(cur); cur = cur.Parent {
+		if !IsLeaf(cur) {
+			cur.Value = repr(cur)
+		}
+	}
+}
+
+func equalKeys(a, b Path) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Key != b[i].Key {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,286 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func editTree() *Node {
+	return New(map[string]any{
+		"name": "app",
+		"spec": map[string]any{
+			"ports": []any{"80", "443", "8080"},
+			"env":   map[string]any{"A": "1"},
+		},
+	}, 0, LeafValuesOnly)
+}
+
+func mustPath(t *testing.T, s string) Path {
+	t.Helper()
+	p, err := ParsePath(s, DotSyntax)
+	require.NoError(t, err)
+	return p
+}
+
+// checkTree asserts that every node's parent pointer and key match where it is
+// stored, and that summaries are up to date.
+func checkTree(t *testing.T, n *Node) {
+	t.Helper()
+	for key, child := range n.Children.Iter() {
+		assert.Equal(t, key, child.Key)
+		assert.Same(t, n, child.Parent, key)
+		checkTree(t, child)
+	}
+	if !IsRoot(n) && !IsLeaf(n) {
+		assert.Equal(t, LeafValuesOnly(n), n.Value, PathTo(n).String())
+	}
+}
+
+func TestOps(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func(t *testing.T) Op
+		want map[string]any
+	}{
+		{
+			name: "set leaf",
+			op:   func(t *testing.T) Op { return SetOp{Path: mustPath(t, "name"), Value: "web"} },
+			want: map[string]any{"name": "web"},
+		},
+		{
+			name: "set new key",
+			op:   func(t *testing.T) Op { return SetOp{Path: mustPath(t, "spec.env.B"), Value: "2"} },
+			want: map[string]any{"spec": map[string]any{"env": map[string]any{"A": "1", "B": "2"}}},
+		},
+		{
+			name: "set appends to array",
+			op:   func(t *testing.T) Op { return SetOp{Path: mustPath(t, "spec.ports[3]"), Value: "9"} },
+			want: map[string]any{"spec": map[string]any{"ports": []any{"80", "443", "8080", "9"}}},
+		},
+		{
+			name: "delete array element",
+			op:   func(t *testing.T) Op { return DeleteOp{Path: mustPath(t, "spec.ports[0]")} },
+			want: map[string]any{"spec": map[string]any{"ports": []any{"443", "8080"}}},
+		},
+		{
+			name: "delete last key",
+			op:   func(t *testing.T) Op { return DeleteOp{Path: mustPath(t, "spec.env.A")} },
+			want: map[string]any{"spec": map[string]any{"env": map[string]any{"A": nil}}},
+		},
+		{
+			name: "rename",
+			op:   func(t *testing.T) Op { return RenameOp{Path: mustPath(t, "spec.env"), Key: "vars"} },
+			want: map[string]any{"spec": map[string]any{"env": nil, "vars": map[string]any{"A": "1"}}},
+		},
+		{
+			name: "move within array",
+			op: func(t *testing.T) Op {
+				return MoveOp{From: mustPath(t, "spec.ports[0]"), To: mustPath(t, "spec.ports[2]")}
+			},
+			want: map[string]any{"spec": map[string]any{"ports": []any{"443", "8080", "80"}}},
+		},
+		{
+			name: "move out of array",
+			op:   func(t *testing.T) Op { return MoveOp{From: mustPath(t, "spec.ports[1]"), To: mustPath(t, "tls")} },
+			want: map[string]any{"tls": "443", "spec": map[string]any{"ports": []any{"80", "8080"}}},
+		},
+		{
+			name: "insert array element",
+			op: func(t *testing.T) Op {
+				return InsertArrayElementOp{Path: mustPath(t, "spec.ports"), Index: 1, Node: NewNode("", "22", 0, 0, LeafValuesOnly)}
+			},
+			want: map[string]any{"spec": map[string]any{"ports": []any{"80", "22", "443", "8080"}}},
+		},
+		{
+			name: "replace subtree",
+			op: func(t *testing.T) Op {
+				return ReplaceOp{Path: mustPath(t, "spec.env"), Node: NewNode("", []any{"x"}, 0, 0, LeafValuesOnly)}
+			},
+			want: map[string]any{"spec": map[string]any{"env": []any{"x"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := editTree()
+			before := ToValue(root)
+			want := merge(ToValue(editTree()).(map[string]any), tt.want)
+
+			undo, err := tt.op(t).Apply(root, LeafValuesOnly)
+			require.NoError(t, err)
+			assert.Equal(t, want, ToValue(root))
+			checkTree(t, root)
+
+			redo, err := undo.Apply(root, LeafValuesOnly)
+			require.NoError(t, err)
+			assert.Equal(t, before, ToValue(root))
+			checkTree(t, root)
+
+			_, err = redo.Apply(root, LeafValuesOnly)
+			require.NoError(t, err)
+			assert.Equal(t, want, ToValue(root))
+			checkTree(t, root)
+		})
+	}
+}
+
+// merge overlays b onto a, replacing rather than merging arrays. A nil value
+// in b deletes the key.
+func merge(a, b map[string]any) map[string]any {
+	for k, v := range b {
+		if v == nil {
+			delete(a, k)
+			continue
+		}
+		am, aok := a[k].(map[string]any)
+		bm, bok := v.(map[string]any)
+		if aok && bok {
+			a[k] = merge(am, bm)
+			continue
+		}
+		a[k] = v
+	}
+	return a
+}
+
+func TestOpErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		op   Op
+		err  error
+	}{
+		{name: "set root", op: SetOp{Path: Path{}, Value: "x"}, err: ErrInvalidEdit},
+		{name: "set object", op: SetOp{Path: Path{{Key: "spec"}}, Value: "x"}, err: ErrInvalidEdit},
+		{name: "set past array end", op: SetOp{Path: Path{{Key: "spec"}, {Key: "ports"}, {Key: "5", IsIndex: true}}}, err: ErrInvalidEdit},
+		{name: "set under scalar", op: SetOp{Path: Path{{Key: "name"}, {Key: "x"}}}, err: ErrInvalidEdit},
+		{name: "set missing parent", op: SetOp{Path: Path{{Key: "nope"}, {Key: "x"}}}, err: ErrNotFound},
+		{name: "delete missing", op: DeleteOp{Path: Path{{Key: "nope"}}}, err: ErrNotFound},
+		{name: "rename array element", op: RenameOp{Path: Path{{Key: "spec"}, {Key: "ports"}, {Key: "0"}}, Key: "x"}, err: ErrInvalidEdit},
+		{name: "rename onto sibling", op: RenameOp{Path: Path{{Key: "spec"}, {Key: "env"}}, Key: "ports"}, err: ErrExists},
+		{name: "move into itself", op: MoveOp{From: Path{{Key: "spec"}}, To: Path{{Key: "spec"}, {Key: "env"}, {Key: "x"}}}, err: ErrInvalidEdit},
+		{name: "move onto existing key", op: MoveOp{From: Path{{Key: "name"}}, To: Path{{Key: "spec"}, {Key: "env"}, {Key: "A"}}}, err: ErrExists},
+		{name: "insert into object", op: InsertArrayElementOp{Path: Path{{Key: "spec"}}, Node: &Node{}}, err: ErrInvalidEdit},
+		{name: "replace root", op: ReplaceOp{Path: Path{}, Node: &Node{}}, err: ErrInvalidEdit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := editTree()
+			before := ToValue(root)
+			_, err := tt.op.Apply(root, LeafValuesOnly)
+			assert.ErrorIs(t, err, tt.err)
+			// a failed op leaves the tree as it was
+			assert.Equal(t, before, ToValue(root))
+			checkTree(t, root)
+		})
+	}
+}
+
+func TestEmptyContainers(t *testing.T) {
+	root := New(map[string]any{"list": []any{}, "obj": map[string]any{}, "text": "[]"}, 0, LeafValuesOnly)
+	list, obj, text := Child(root, "list"), Child(root, "obj"), Child(root, "text")
+	assert.Equal(t, Array, list.Kind)
+	assert.Equal(t, Object, obj.Kind)
+	assert.Equal(t, Scalar, text.Kind)
+
+	// a string reading [] is not an array, however it is shown
+	_, err := InsertArrayElementOp{Path: Path{{Key: "text"}}, Node: &Node{Value: "x"}}.Apply(root, LeafValuesOnly)
+	assert.ErrorIs(t, err, ErrInvalidEdit)
+	_, err = SetOp{Path: Path{{Key: "text"}, {Key: "k"}}, Value: "x"}.Apply(root, LeafValuesOnly)
+	assert.ErrorIs(t, err, ErrInvalidEdit)
+
+	// an emptied array is still one
+	_, err = SetOp{Path: Path{{Key: "list"}, {Key: "0", IsIndex: true}}, Value: "a"}.Apply(root, LeafValuesOnly)
+	require.NoError(t, err)
+	_, err = DeleteOp{Path: Path{{Key: "list"}, {Key: "0", IsIndex: true}}}.Apply(root, LeafValuesOnly)
+	require.NoError(t, err)
+	assert.Equal(t, "[]", list.Value)
+	_, err = InsertArrayElementOp{Path: Path{{Key: "list"}}, Node: &Node{Value: "b"}}.Apply(root, LeafValuesOnly)
+	require.NoError(t, err)
+
+	// setting an empty object makes it a scalar, and undoing it an object again
+	undo, err := SetOp{Path: Path{{Key: "obj"}}, Value: "x"}.Apply(root, LeafValuesOnly)
+	require.NoError(t, err)
+	assert.Equal(t, Scalar, Child(root, "obj").Kind)
+	_, err = undo.Apply(root, LeafValuesOnly)
+	require.NoError(t, err)
+	assert.Equal(t, Object, Child(root, "obj").Kind)
+	checkTree(t, root)
+}
+
+func TestNumericKeys(t *testing.T) {
+	// an object keyed 0 and 1 is not an array
+	root := New(map[string]any{"a": map[string]any{"0": "x", "1": "y"}}, 0, LeafValuesOnly)
+	undo, err := DeleteOp{Path: Path{{Key: "a"}, {Key: "0"}}}.Apply(root, LeafValuesOnly)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": map[string]any{"1": "y"}}, ToValue(root), "the other keys are kept")
+	_, err = undo.Apply(root, LeafValuesOnly)
+	require.NoError(t, err)
+
+	_, err = SetOp{Path: Path{{Key: "a"}, {Key: "foo"}}, Value: "1"}.Apply(root, LeafValuesOnly)
+	require.NoError(t, err)
+	_, err = RenameOp{Path: Path{{Key: "a"}, {Key: "1"}}, Key: "5"}.Apply(root, LeafValuesOnly)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": map[string]any{"0": "x", "5": "y", "foo": "1"}}, ToValue(root))
+	_, err = InsertArrayElementOp{Path: Path{{Key: "a"}}, Node: &Node{Value: "z"}}.Apply(root, LeafValuesOnly)
+	assert.ErrorIs(t, err, ErrInvalidEdit)
+	checkTree(t, root)
+
+	// a document whose top-level value is an array is one
+	root = New(map[string]any{"0": "x", "1": "y"}, 0, LeafValuesOnly)
+	_, err = DeleteOp{Path: Path{{Key: "0", IsIndex: true}}}.Apply(root, LeafValuesOnly)
+	require.NoError(t, err)
+	assert.Equal(t, []any{"y"}, ToValue(root))
+}
+
+func TestHistory(t *testing.T) {
+	root := editTree()
+	original := ToValue(root)
+	h := NewHistory(root, LeafValuesOnly)
+
+	ok, err := h.Undo()
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, h.Do(SetOp{Path: Path{{Key: "name"}}, Value: "web"}))
+	require.NoError(t, h.Do(DeleteOp{Path: Path{{Key: "spec"}, {Key: "ports"}}}))
+	assert.Error(t, h.Do(DeleteOp{Path: Path{{Key: "nope"}}}))
+	assert.Equal(t, 2, h.Len())
+	edited := ToValue(root)
+
+	for range 2 {
+		ok, err := h.Undo()
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+	assert.Equal(t, original, ToValue(root))
+
+	for range 2 {
+		ok, err := h.Redo()
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+	assert.Equal(t, edited, ToValue(root))
+
+	// a new change drops what could be redone
+	_, err = h.Undo()
+	require.NoError(t, err)
+	require.NoError(t, h.Do(SetOp{Path: Path{{Key: "name"}}, Value: "api"}))
+	ok, err = h.Redo()
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestClone(t *testing.T) {
+	root := editTree()
+	spec := Child(root, "spec")
+	c := Clone(spec)
+	assert.Nil(t, c.Parent)
+	assert.NotEqual(t, spec.ID, c.ID)
+	assert.Equal(t, ToValue(spec), ToValue(c))
+	checkTree(t, c)
+
+	_, err := SetOp{Path: Path{{Key: "env"}, {Key: "A"}}, Value: "2"}.Apply(c, LeafValuesOnly)
+	require.NoError(t, err)
+	assert.Equal(t, "1", Child(Child(spec, "env"), "A").Value)
+}
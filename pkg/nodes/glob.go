@@ -0,0 +1,58 @@
+package nodes
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Glob matches paths of keys, as given by GetPathToNode. It is written in the
+// dot syntax: a * matches within one key, ** matches any number of keys, and
+// array indices are matched as keys, so items[*].name and items.*.name are the
+// same glob.
+type Glob []string
+
+// indexGlob matches an array index, or a wildcard in its place, written in
+// brackets.
+var indexGlob = regexp.MustCompile(`\[(\d+|\*)\]`)
+
+// ParseGlob parses text as a Glob.
+func ParseGlob(text string) (Glob, error) {
+	g := Glob(strings.Split(strings.TrimPrefix(indexGlob.ReplaceAllString(text, ".$1"), "."), "."))
+	for _, key := range g {
+		if _, err := path.Match(key, ""); err != nil {
+			return nil, fmt.Errorf("glob %q: %w", text, err)
+		}
+	}
+	return g, nil
+}
+
+// Match reports whether g matches p.
+func (g Glob) Match(p []string) bool {
+	return matchGlob(g, p, false)
+}
+
+// MatchPrefix reports whether g matches p or a path above it.
+func (g Glob) MatchPrefix(p []string) bool {
+	return matchGlob(g, p, true)
+}
+
+func matchGlob(g Glob, p []string, prefix bool) bool {
+	if len(g) == 0 {
+		return prefix || len(p) == 0
+	}
+	if g[0] == "**" {
+		for i := range len(p) + 1 {
+			if matchGlob(g[1:], p[i:], prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(p) == 0 {
+		return false
+	}
+	ok, _ := path.Match(g[0], p[0])
+	return ok && matchGlob(g[1:], p[1:], prefix)
+}
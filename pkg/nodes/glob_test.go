@@ -0,0 +1,40 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlob(t *testing.T) {
+	tests := []struct {
+		glob   string
+		path   []string
+		match  bool
+		prefix bool
+	}{
+		{glob: "a.b", path: []string{"a", "b"}, match: true, prefix: true},
+		{glob: "a.b", path: []string{"a", "b", "c"}, match: false, prefix: true},
+		{glob: "a.b", path: []string{"a"}, match: false, prefix: false},
+		{glob: "items[*].name", path: []string{"items", "3", "name"}, match: true, prefix: true},
+		{glob: "items.*.name", path: []string{"items", "3", "name"}, match: true, prefix: true},
+		{glob: "items[0]", path: []string{"items", "1"}, match: false, prefix: false},
+		{glob: "**.name", path: []string{"a", "b", "name"}, match: true, prefix: true},
+		{glob: "**.name", path: []string{"name"}, match: true, prefix: true},
+		{glob: "a.**", path: []string{"a"}, match: true, prefix: true},
+		{glob: "a.x*", path: []string{"a", "xyz"}, match: true, prefix: true},
+		{glob: ".a", path: []string{"a"}, match: true, prefix: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.glob, func(t *testing.T) {
+			g, err := ParseGlob(tt.glob)
+			require.NoError(t, err)
+			assert.Equal(t, tt.match, g.Match(tt.path), "match %v", tt.path)
+			assert.Equal(t, tt.prefix, g.MatchPrefix(tt.path), "prefix of %v", tt.path)
+		})
+	}
+
+	_, err := ParseGlob("a.[b")
+	assert.Error(t, err)
+}
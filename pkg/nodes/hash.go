@@ -0,0 +1,98 @@
+package nodes
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"slices"
+)
+
+// Hash is a digest of the data held by a subtree.
+type Hash [sha256.Size]byte
+
+// Hash returns a digest of the data under n: the value of a leaf, or the keys
+// of a container's children and their own hashes. n's key, its ID, whether it
+// is expanded and the summaries of containers are left out, so two subtrees
+// holding the same data have the same hash wherever they are. The hash is
+// cached on each node and dropped by the edits in this package; code changing
+// a node directly must call Invalidate.
+func (n *Node) Hash() Hash {
+	if n.hash != nil {
+		return *n.hash
+	}
+	h := sha256.New()
+	if IsLeaf(n) {
+		h.Write([]byte{'v'})
+		writeString(h, n.Value)
+	} else {
+		h.Write([]byte{'c'})
+		for key, child := range n.Children.Iter() {
+			writeString(h, key)
+			sum := child.Hash()
+			h.Write(sum[:])
+		}
+	}
+	n.hash = new(Hash)
+	h.Sum(n.hash[:0])
+	return *n.hash
+}
+
+// writeString writes s to h after its length, so the strings written one after
+// another cannot be split differently to the same bytes.
+func writeString(h hash.Hash, s string) {
+	h.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	h.Write([]byte(s))
+}
+
+// Invalidate drops the cached hashes of n and its ancestors, after n or the
+// nodes below it were changed.
+func Invalidate(n *Node) {
+	for cur := n; cur != nil; cur = cur.Parent {
+		cur.hash = nil
+	}
+}
+
+// DeepEqual reports whether a and b hold the same data, comparing their
+// hashes. Unlike Equal it ignores IDs, and like Hash it ignores the keys of a
+// and b themselves. Two nil nodes are equal.
+func DeepEqual(a, b *Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Hash() == b.Hash()
+}
+
+// Duplicates returns the groups of containers under root that hold the same
+// data, in the order they are first found. Leaves are left out. A container
+// found only inside copies of a duplicated container is left out too, as its
+// copies are already shown by the outer group.
+func Duplicates(root *Node) [][]*Node {
+	count := map[Hash]int{}
+	DFS(root, func(n *Node, _ int) error {
+		if !IsLeaf(n) {
+			count[n.Hash()]++
+		}
+		return nil
+	}, WithNextNodes(AllChildren))
+
+	groups := map[Hash]int{}
+	var out [][]*Node
+	DFS(root, func(n *Node, _ int) error {
+		h := n.Hash()
+		if IsLeaf(n) || count[h] < 2 {
+			return nil
+		}
+		if p := n.Parent; p != nil && !IsRoot(p) && count[p.Hash()] == count[h] {
+			return nil
+		}
+		i, ok := groups[h]
+		if !ok {
+			i = len(out)
+			groups[h] = i
+			out = append(out, nil)
+		}
+		out[i] = append(out[i], n)
+		return nil
+	}, WithNextNodes(AllChildren))
+	return slices.DeleteFunc(out, func(g []*Node) bool { return len(g) < 2 })
+}
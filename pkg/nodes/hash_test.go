@@ -0,0 +1,74 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeepEqual(t *testing.T) {
+	data := map[string]any{"a": map[string]any{"b": "1", "c": []any{"x", "y"}}, "d": "2"}
+	a, b := New(data, 0, LeafValuesOnly), New(data, 2, LeafKeyAndValues)
+	assert.False(t, a.Equal(b))
+	assert.True(t, DeepEqual(a, b), "IDs, expansion and summaries are ignored")
+	assert.True(t, DeepEqual(a, Clone(a)))
+	assert.True(t, DeepEqual(Child(a, "d"), Child(Child(New(map[string]any{"e": map[string]any{"f": "2"}}, 0, EmptyRepr), "e"), "f")), "so are keys")
+	assert.True(t, DeepEqual(nil, nil))
+	assert.False(t, DeepEqual(a, nil))
+
+	for _, other := range []any{
+		map[string]any{"a": map[string]any{"b": "1", "c": []any{"y", "x"}}, "d": "2"},
+		map[string]any{"a": map[string]any{"b": "1", "c": []any{"x", "y"}}, "e": "2"},
+		map[string]any{"a": map[string]any{"b": "1", "c": []any{"x", "y"}}},
+		map[string]any{"a": map[string]any{"b": "1", "c": "x y"}, "d": "2"},
+	} {
+		assert.False(t, DeepEqual(a, New(other.(map[string]any), 0, EmptyRepr)), other)
+	}
+	// keys and values are told apart however they are split
+	assert.NotEqual(t, New(map[string]any{"ab": "c"}, 0, EmptyRepr).Hash(), New(map[string]any{"a": "bc"}, 0, EmptyRepr).Hash())
+}
+
+func TestHashInvalidated(t *testing.T) {
+	a := New(map[string]any{"a": map[string]any{"b": "1"}, "l": []any{"x"}}, 0, EmptyRepr)
+	b := Clone(a)
+	require.True(t, DeepEqual(a, b))
+
+	ops := []Op{
+		SetOp{Path: Path{{Key: "a"}, {Key: "b"}}, Value: "2"},
+		SetOp{Path: Path{{Key: "a"}, {Key: "c"}}, Value: "3"},
+		DeleteOp{Path: Path{{Key: "a"}, {Key: "b"}}},
+		RenameOp{Path: Path{{Key: "a"}}, Key: "z"},
+		InsertArrayElementOp{Path: Path{{Key: "l"}}, Index: 0, Node: &Node{ID: uuid.New(), Value: "w"}},
+	}
+	for _, op := range ops {
+		before := a.Hash()
+		undo, err := op.Apply(a, nil)
+		require.NoError(t, err, op)
+		assert.NotEqual(t, before, a.Hash(), op)
+		assert.False(t, DeepEqual(a, b), op)
+		_, err = undo.Apply(a, nil)
+		require.NoError(t, err, op)
+		assert.Equal(t, before, a.Hash(), op)
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	pod := map[string]any{"image": "app", "ports": []any{"80"}}
+	root := New(map[string]any{
+		"a":     pod,
+		"b":     map[string]any{"image": "app", "ports": []any{"80"}},
+		"c":     map[string]any{"image": "proxy", "ports": []any{"80"}},
+		"d":     "app",
+		"e":     "app",
+		"empty": map[string]any{},
+	}, 0, EmptyRepr)
+	groups := Duplicates(root)
+	require.Len(t, groups, 2)
+	assert.Equal(t, []*Node{Child(root, "a"), Child(root, "b")}, groups[0])
+	// the ports of a and b are covered by their group, but c's copy is not
+	assert.Equal(t, []*Node{Child(Child(root, "a"), "ports"), Child(Child(root, "b"), "ports"), Child(Child(root, "c"), "ports")}, groups[1])
+
+	assert.Empty(t, Duplicates(New(map[string]any{"a": "1", "b": "1"}, 0, EmptyRepr)), "leaves are not reported")
+}
@@ -0,0 +1,706 @@
+package jq
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// builtins maps name/arity to a constructor taking the argument filters.
+var builtins = map[string]func(args []filter) filter{
+	"empty/0": func([]filter) filter {
+		return func(any) ([]any, error) { return nil, nil }
+	},
+	"not/0":           value(func(in any) (any, error) { return !truthy(in), nil }),
+	"type/0":          value(func(in any) (any, error) { return typeName(in), nil }),
+	"length/0":        value(length),
+	"keys/0":          value(keys),
+	"keys_unsorted/0": value(keys),
+	"add/0": value(func(in any) (any, error) {
+		items, err := iterate(in)
+		if err != nil {
+			return nil, err
+		}
+		var sum any
+		for _, v := range items {
+			if sum, err = add(sum, v); err != nil {
+				return nil, err
+			}
+		}
+		return sum, nil
+	}),
+	"sort/0": value(func(in any) (any, error) {
+		arr, err := array(in, "sorted")
+		if err != nil {
+			return nil, err
+		}
+		out := slices.Clone(arr)
+		slices.SortStableFunc(out, compare)
+		return out, nil
+	}),
+	"unique/0": value(func(in any) (any, error) {
+		arr, err := array(in, "sorted")
+		if err != nil {
+			return nil, err
+		}
+		out := slices.Clone(arr)
+		slices.SortStableFunc(out, compare)
+		return slices.CompactFunc(out, func(a, b any) bool { return compare(a, b) == 0 }), nil
+	}),
+	"reverse/0": value(func(in any) (any, error) {
+		if s, ok := in.(string); ok {
+			r := []rune(s)
+			slices.Reverse(r)
+			return string(r), nil
+		}
+		if in == nil {
+			return []any{}, nil
+		}
+		arr, err := array(in, "reversed")
+		if err != nil {
+			return nil, err
+		}
+		out := slices.Clone(arr)
+		slices.Reverse(out)
+		return out, nil
+	}),
+	"first/0": func([]filter) filter { return index(literal(0.0)) },
+	"last/0":  func([]filter) filter { return index(literal(-1.0)) },
+	"min/0":   value(func(in any) (any, error) { return extreme(in, identity, -1) }),
+	"max/0":   value(func(in any) (any, error) { return extreme(in, identity, 1) }),
+	"any/0": value(func(in any) (any, error) {
+		arr, err := array(in, "searched")
+		if err != nil {
+			return nil, err
+		}
+		return slices.ContainsFunc(arr, truthy), nil
+	}),
+	"all/0": value(func(in any) (any, error) {
+		arr, err := array(in, "searched")
+		if err != nil {
+			return nil, err
+		}
+		return !slices.ContainsFunc(arr, func(v any) bool { return !truthy(v) }), nil
+	}),
+	"flatten/0":      value(func(in any) (any, error) { return flatten(in, math.MaxInt) }),
+	"to_entries/0":   value(toEntries),
+	"from_entries/0": value(fromEntries),
+	"tostring/0": value(func(in any) (any, error) {
+		if s, ok := in.(string); ok {
+			return s, nil
+		}
+		return toJSON(in)
+	}),
+	"tojson/0": value(toJSON),
+	"fromjson/0": value(func(in any) (any, error) {
+		s, ok := in.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s cannot be parsed as JSON", typeName(in))
+		}
+		var v any
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			return nil, fmt.Errorf("%q cannot be parsed as JSON: %w", s, err)
+		}
+		return v, nil
+	}),
+	"tonumber/0": value(func(in any) (any, error) {
+		switch v := in.(type) {
+		case float64:
+			return v, nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse %q as a number", v)
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("%s cannot be parsed as a number", typeName(in))
+	}),
+	"ascii_downcase/0": value(stringFunc(strings.ToLower)),
+	"ascii_upcase/0":   value(stringFunc(strings.ToUpper)),
+	"floor/0": value(func(in any) (any, error) {
+		f, ok := in.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s has no floor", typeName(in))
+		}
+		return math.Floor(f), nil
+	}),
+	"recurse/0": func([]filter) filter { return recurse },
+	"values/0": func([]filter) filter {
+		return selectFilter(func(in any) ([]any, error) { return []any{in != nil}, nil })
+	},
+	"nulls/0":    ofType("null"),
+	"booleans/0": ofType("boolean"),
+	"numbers/0":  ofType("number"),
+	"strings/0":  ofType("string"),
+	"arrays/0":   ofType("array"),
+	"objects/0":  ofType("object"),
+	"select/1":   func(args []filter) filter { return selectFilter(args[0]) },
+	"map/1": func(args []filter) filter {
+		return collect(pipe(iterate, args[0]))
+	},
+	"map_values/1": func(args []filter) filter {
+		f := args[0]
+		return valueFilter(func(in any) (any, error) {
+			switch v := in.(type) {
+			case map[string]any:
+				out := make(map[string]any, len(v))
+				for k, e := range v {
+					r, err := f(e)
+					if err != nil {
+						return nil, err
+					}
+					// like jq, an element f outputs nothing for is dropped
+					if len(r) > 0 {
+						out[k] = r[0]
+					}
+				}
+				return out, nil
+			case []any:
+				out := make([]any, 0, len(v))
+				for _, e := range v {
+					r, err := f(e)
+					if err != nil {
+						return nil, err
+					}
+					if len(r) > 0 {
+						out = append(out, r[0])
+					}
+				}
+				return out, nil
+			}
+			return nil, fmt.Errorf("cannot iterate over %s", typeName(in))
+		})
+	},
+	"with_entries/1": func(args []filter) filter {
+		return pipe(pipe(valueFilter(toEntries), collect(pipe(iterate, args[0]))), valueFilter(fromEntries))
+	},
+	"has/1": withArg(func(in, key any) (any, error) {
+		switch v := in.(type) {
+		case map[string]any:
+			if k, ok := key.(string); ok {
+				_, found := v[k]
+				return found, nil
+			}
+		case []any:
+			if k, ok := key.(float64); ok {
+				return k >= 0 && int(k) < len(v), nil
+			}
+		}
+		return nil, fmt.Errorf("cannot check whether %s has a %s key", typeName(in), typeName(key))
+	}),
+	"sort_by/1": func(args []filter) filter {
+		return valueFilter(func(in any) (any, error) { return sortBy(in, args[0]) })
+	},
+	"group_by/1": func(args []filter) filter {
+		return valueFilter(func(in any) (any, error) {
+			return groupBy(in, args[0], func(group []any) any { return group })
+		})
+	},
+	"unique_by/1": func(args []filter) filter {
+		return valueFilter(func(in any) (any, error) {
+			return groupBy(in, args[0], func(group []any) any { return group[0] })
+		})
+	},
+	"min_by/1": func(args []filter) filter {
+		return valueFilter(func(in any) (any, error) { return extreme(in, args[0], -1) })
+	},
+	"max_by/1": func(args []filter) filter {
+		return valueFilter(func(in any) (any, error) { return extreme(in, args[0], 1) })
+	},
+	"first/1": func(args []filter) filter {
+		return func(in any) ([]any, error) {
+			out, err := args[0](in)
+			if err != nil || len(out) == 0 {
+				return nil, err
+			}
+			return out[:1], nil
+		}
+	},
+	"last/1": func(args []filter) filter {
+		return func(in any) ([]any, error) {
+			out, err := args[0](in)
+			if err != nil || len(out) == 0 {
+				return nil, err
+			}
+			return out[len(out)-1:], nil
+		}
+	},
+	"recurse/1": func(args []filter) filter {
+		var rec filter
+		rec = func(in any) ([]any, error) {
+			next, err := optional(args[0])(in)
+			if err != nil {
+				return nil, err
+			}
+			out := []any{in}
+			for _, n := range next {
+				r, err := rec(n)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, r...)
+			}
+			return out, nil
+		}
+		return rec
+	},
+	"flatten/1": withArg(func(in, depth any) (any, error) {
+		d, ok := depth.(float64)
+		if !ok || d < 0 {
+			return nil, fmt.Errorf("flatten depth must not be negative")
+		}
+		return flatten(in, int(d))
+	}),
+	"startswith/1": withArg(stringArg("startswith", func(s, arg string) any { return strings.HasPrefix(s, arg) })),
+	"endswith/1":   withArg(stringArg("endswith", func(s, arg string) any { return strings.HasSuffix(s, arg) })),
+	"ltrimstr/1": withArg(func(in, arg any) (any, error) {
+		s, sok := in.(string)
+		p, pok := arg.(string)
+		if !sok || !pok {
+			return in, nil
+		}
+		return strings.TrimPrefix(s, p), nil
+	}),
+	"rtrimstr/1": withArg(func(in, arg any) (any, error) {
+		s, sok := in.(string)
+		p, pok := arg.(string)
+		if !sok || !pok {
+			return in, nil
+		}
+		return strings.TrimSuffix(s, p), nil
+	}),
+	"split/1": withArg(stringArg("split", func(s, sep string) any { return splitString(s, sep) })),
+	"join/1": withArg(func(in, sep any) (any, error) {
+		arr, err := array(in, "joined")
+		if err != nil {
+			return nil, err
+		}
+		s, ok := sep.(string)
+		if !ok {
+			return nil, fmt.Errorf("join separator must be a string, got %s", typeName(sep))
+		}
+		parts := make([]string, len(arr))
+		for i, e := range arr {
+			switch e := e.(type) {
+			case nil:
+			case string:
+				parts[i] = e
+			case float64, bool:
+				parts[i] = fmt.Sprint(e)
+			default:
+				return nil, fmt.Errorf("cannot join with %s", typeName(e))
+			}
+		}
+		return strings.Join(parts, s), nil
+	}),
+	"test/1": withArg(func(in, pattern any) (any, error) {
+		s, sok := in.(string)
+		p, pok := pattern.(string)
+		if !sok || !pok {
+			return nil, fmt.Errorf("%s cannot be matched, as it is not a string", typeName(in))
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", p, err)
+		}
+		return re.MatchString(s), nil
+	}),
+	"contains/1": withArg(func(in, x any) (any, error) {
+		if typeName(in) != typeName(x) {
+			return nil, fmt.Errorf("%s and %s cannot have their containment checked", typeName(in), typeName(x))
+		}
+		return contains(in, x), nil
+	}),
+	"range/1": func(args []filter) filter {
+		return rangeFilter(literal(0.0), args[0])
+	},
+	"range/2": func(args []filter) filter {
+		return rangeFilter(args[0], args[1])
+	},
+	"limit/2": func(args []filter) filter {
+		return func(in any) ([]any, error) {
+			ns, err := args[0](in)
+			if err != nil {
+				return nil, err
+			}
+			out, err := args[1](in)
+			if err != nil {
+				return nil, err
+			}
+			var res []any
+			for _, n := range ns {
+				f, ok := n.(float64)
+				if !ok {
+					return nil, fmt.Errorf("limit must be a number, got %s", typeName(n))
+				}
+				res = append(res, out[:min(max(int(f), 0), len(out))]...)
+			}
+			return res, nil
+		}
+	},
+}
+
+// Builtins returns the name/arity of every supported builtin, such as
+// "select/1", in sorted order.
+func Builtins() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func builtin(name string, args []filter) (filter, error) {
+	ctor, ok := builtins[fmt.Sprintf("%s/%d", name, len(args))]
+	if !ok {
+		return nil, fmt.Errorf("%s/%d is not defined", name, len(args))
+	}
+	return ctor(args), nil
+}
+
+// value adapts a function of the input to a builtin with one output.
+func value(fn func(in any) (any, error)) func([]filter) filter {
+	return func([]filter) filter {
+		return valueFilter(fn)
+	}
+}
+
+// valueFilter adapts a function of the input to a filter with one output.
+func valueFilter(fn func(in any) (any, error)) filter {
+	return func(in any) ([]any, error) {
+		v, err := fn(in)
+		if err != nil {
+			return nil, err
+		}
+		return []any{v}, nil
+	}
+}
+
+// withArg adapts a function of the input and an argument value to a builtin
+// called once per output of the argument filter.
+func withArg(fn func(in, arg any) (any, error)) func([]filter) filter {
+	return func(args []filter) filter {
+		return func(in any) ([]any, error) {
+			argv, err := args[0](in)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]any, 0, len(argv))
+			for _, a := range argv {
+				v, err := fn(in, a)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, v)
+			}
+			return out, nil
+		}
+	}
+}
+
+func stringFunc(fn func(string) string) func(any) (any, error) {
+	return func(in any) (any, error) {
+		s, ok := in.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a string", typeName(in))
+		}
+		return fn(s), nil
+	}
+}
+
+func stringArg(name string, fn func(s, arg string) any) func(in, arg any) (any, error) {
+	return func(in, arg any) (any, error) {
+		s, sok := in.(string)
+		a, aok := arg.(string)
+		if !sok || !aok {
+			return nil, fmt.Errorf("%s inputs must be strings", name)
+		}
+		return fn(s, a), nil
+	}
+}
+
+func selectFilter(cond filter) filter {
+	return func(in any) ([]any, error) {
+		conds, err := cond(in)
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, c := range conds {
+			if truthy(c) {
+				out = append(out, in)
+			}
+		}
+		return out, nil
+	}
+}
+
+func ofType(name string) func([]filter) filter {
+	return func([]filter) filter {
+		return selectFilter(func(in any) ([]any, error) {
+			return []any{typeName(in) == name}, nil
+		})
+	}
+}
+
+func array(in any, verb string) ([]any, error) {
+	arr, ok := in.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s cannot be %s, as it is not an array", typeName(in), verb)
+	}
+	return arr, nil
+}
+
+func length(in any) (any, error) {
+	switch v := in.(type) {
+	case nil:
+		return 0.0, nil
+	case float64:
+		return math.Abs(v), nil
+	case string:
+		return float64(utf8.RuneCountInString(v)), nil
+	case []any:
+		return float64(len(v)), nil
+	case map[string]any:
+		return float64(len(v)), nil
+	}
+	return nil, fmt.Errorf("%s has no length", typeName(in))
+}
+
+func keys(in any) (any, error) {
+	switch v := in.(type) {
+	case map[string]any:
+		out := make([]any, 0, len(v))
+		for _, k := range sortedKeys(v) {
+			out = append(out, k)
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i := range v {
+			out[i] = float64(i)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("%s has no keys", typeName(in))
+}
+
+func toEntries(in any) (any, error) {
+	m, ok := in.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s has no keys", typeName(in))
+	}
+	out := make([]any, 0, len(m))
+	for _, k := range sortedKeys(m) {
+		out = append(out, map[string]any{"key": k, "value": m[k]})
+	}
+	return out, nil
+}
+
+func fromEntries(in any) (any, error) {
+	arr, err := array(in, "converted to an object")
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]any, len(arr))
+	for _, e := range arr {
+		entry, ok := e.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("cannot use %s as an object entry", typeName(e))
+		}
+		var key any
+		for _, name := range []string{"key", "k", "name", "Name", "Key", "K"} {
+			if k, ok := entry[name]; ok && k != nil {
+				key = k
+				break
+			}
+		}
+		var val any
+		for _, name := range []string{"value", "v", "Value", "V"} {
+			if v, ok := entry[name]; ok {
+				val = v
+				break
+			}
+		}
+		switch k := key.(type) {
+		case string:
+			out[k] = val
+		case float64, bool:
+			out[fmt.Sprint(k)] = val
+		default:
+			return nil, fmt.Errorf("cannot use %s as an object key", typeName(key))
+		}
+	}
+	return out, nil
+}
+
+func toJSON(in any) (any, error) {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// keyed pairs an array element with the outputs of a key filter for it.
+type keyed struct {
+	key, value any
+}
+
+func keyBy(in any, f filter) ([]keyed, error) {
+	arr, err := array(in, "sorted")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]keyed, len(arr))
+	for i, e := range arr {
+		ks, err := f(e)
+		if err != nil {
+			return nil, err
+		}
+		if ks == nil {
+			ks = []any{}
+		}
+		out[i] = keyed{key: ks, value: e}
+	}
+	return out, nil
+}
+
+func sortBy(in any, f filter) (any, error) {
+	pairs, err := keyBy(in, f)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(pairs, func(a, b keyed) int { return compare(a.key, b.key) })
+	out := make([]any, len(pairs))
+	for i, p := range pairs {
+		out[i] = p.value
+	}
+	return out, nil
+}
+
+// groupBy sorts in by f and folds each run of equal keys with fold.
+func groupBy(in any, f filter, fold func([]any) any) (any, error) {
+	pairs, err := keyBy(in, f)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(pairs, func(a, b keyed) int { return compare(a.key, b.key) })
+	out := []any{}
+	for i := 0; i < len(pairs); {
+		j := i
+		var group []any
+		for ; j < len(pairs) && compare(pairs[i].key, pairs[j].key) == 0; j++ {
+			group = append(group, pairs[j].value)
+		}
+		out = append(out, fold(group))
+		i = j
+	}
+	return out, nil
+}
+
+// extreme returns the element with the smallest (sign -1) or largest (sign 1)
+// key, or null for an empty array.
+func extreme(in any, f filter, sign int) (any, error) {
+	pairs, err := keyBy(in, f)
+	if err != nil {
+		return nil, err
+	}
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	best := pairs[0]
+	for _, p := range pairs[1:] {
+		// ties go to the last element for max and the first for min, as in jq
+		if c := compare(p.key, best.key) * sign; c > 0 || (c == 0 && sign > 0) {
+			best = p
+		}
+	}
+	return best.value, nil
+}
+
+func flatten(in any, depth int) (any, error) {
+	arr, err := array(in, "flattened")
+	if err != nil {
+		return nil, err
+	}
+	out := []any{}
+	for _, e := range arr {
+		if inner, ok := e.([]any); ok && depth > 0 {
+			flat, _ := flatten(inner, depth-1)
+			out = append(out, flat.([]any)...)
+			continue
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// contains reports whether b is within a: substrings for strings, and
+// recursively contained elements and fields for arrays and objects.
+func contains(a, b any) bool {
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		return ok && strings.Contains(x, y)
+	case []any:
+		y, ok := b.([]any)
+		if !ok {
+			return false
+		}
+		for _, want := range y {
+			if !slices.ContainsFunc(x, func(have any) bool { return contains(have, want) }) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, want := range y {
+			have, found := x[k]
+			if !found || !contains(have, want) {
+				return false
+			}
+		}
+		return true
+	}
+	return compare(a, b) == 0
+}
+
+func rangeFilter(from, upto filter) filter {
+	return func(in any) ([]any, error) {
+		froms, err := from(in)
+		if err != nil {
+			return nil, err
+		}
+		uptos, err := upto(in)
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, f := range froms {
+			for _, u := range uptos {
+				start, sok := f.(float64)
+				end, eok := u.(float64)
+				if !sok || !eok {
+					return nil, fmt.Errorf("range bounds must be numbers")
+				}
+				for i := start; i < end; i++ {
+					out = append(out, i)
+				}
+			}
+		}
+		return out, nil
+	}
+}
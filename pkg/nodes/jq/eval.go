@@ -0,0 +1,524 @@
+package jq
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+func identity(in any) ([]any, error) {
+	return []any{in}, nil
+}
+
+func literal(v any) filter {
+	return func(any) ([]any, error) {
+		return []any{v}, nil
+	}
+}
+
+// pipe feeds every output of left into right.
+func pipe(left, right filter) filter {
+	return func(in any) ([]any, error) {
+		mid, err := left(in)
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, v := range mid {
+			r, err := right(v)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r...)
+		}
+		return out, nil
+	}
+}
+
+// comma outputs everything from left, then everything from right.
+func comma(left, right filter) filter {
+	return func(in any) ([]any, error) {
+		l, err := left(in)
+		if err != nil {
+			return nil, err
+		}
+		r, err := right(in)
+		if err != nil {
+			return nil, err
+		}
+		return append(l, r...), nil
+	}
+}
+
+// alternative outputs the truthy outputs of left, or those of right when there
+// are none. Errors from left count as no output.
+func alternative(left, right filter) filter {
+	return func(in any) ([]any, error) {
+		l, _ := left(in)
+		var out []any
+		for _, v := range l {
+			if truthy(v) {
+				out = append(out, v)
+			}
+		}
+		if len(out) > 0 {
+			return out, nil
+		}
+		return right(in)
+	}
+}
+
+// logical implements and (or when or is set), only evaluating right when
+// left does not already decide the result.
+func logical(left, right filter, or bool) filter {
+	return func(in any) ([]any, error) {
+		l, err := left(in)
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, lv := range l {
+			if truthy(lv) == or {
+				out = append(out, or)
+				continue
+			}
+			r, err := right(in)
+			if err != nil {
+				return nil, err
+			}
+			for _, rv := range r {
+				out = append(out, truthy(rv))
+			}
+		}
+		return out, nil
+	}
+}
+
+// binary applies op to every combination of the outputs of left and right,
+// iterating right in the outer loop as jq does.
+func binary(left, right filter, op func(a, b any) (any, error)) filter {
+	return func(in any) ([]any, error) {
+		r, err := right(in)
+		if err != nil {
+			return nil, err
+		}
+		l, err := left(in)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]any, 0, len(l)*len(r))
+		for _, rv := range r {
+			for _, lv := range l {
+				v, err := op(lv, rv)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, v)
+			}
+		}
+		return out, nil
+	}
+}
+
+func compareOp(op string) func(a, b any) (any, error) {
+	return func(a, b any) (any, error) {
+		c := compare(a, b)
+		switch op {
+		case "==":
+			return c == 0, nil
+		case "!=":
+			return c != 0, nil
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}
+}
+
+func add(a, b any) (any, error) {
+	if a == nil {
+		return b, nil
+	}
+	if b == nil {
+		return a, nil
+	}
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			return x + y, nil
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return x + y, nil
+		}
+	case []any:
+		if y, ok := b.([]any); ok {
+			return append(append(make([]any, 0, len(x)+len(y)), x...), y...), nil
+		}
+	case map[string]any:
+		if y, ok := b.(map[string]any); ok {
+			out := make(map[string]any, len(x)+len(y))
+			for k, v := range x {
+				out[k] = v
+			}
+			for k, v := range y {
+				out[k] = v
+			}
+			return out, nil
+		}
+	}
+	return nil, operandError("added", a, b)
+}
+
+func subtract(a, b any) (any, error) {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			return x - y, nil
+		}
+	case []any:
+		if y, ok := b.([]any); ok {
+			out := make([]any, 0, len(x))
+			for _, v := range x {
+				if !containsValue(y, v) {
+					out = append(out, v)
+				}
+			}
+			return out, nil
+		}
+	}
+	return nil, operandError("subtracted", a, b)
+}
+
+func multiply(a, b any) (any, error) {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			return x * y, nil
+		}
+	case map[string]any:
+		if y, ok := b.(map[string]any); ok {
+			return deepMerge(x, y), nil
+		}
+	}
+	return nil, operandError("multiplied", a, b)
+}
+
+// deepMerge merges b into a copy of a, merging nested objects rather than
+// replacing them.
+func deepMerge(a, b map[string]any) map[string]any {
+	out := make(map[string]any, len(a)+len(b))
+	for k, v := range a {
+		out[k] = v
+	}
+	for k, v := range b {
+		am, aok := out[k].(map[string]any)
+		bm, bok := v.(map[string]any)
+		if aok && bok {
+			out[k] = deepMerge(am, bm)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func divide(a, b any) (any, error) {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			if y == 0 {
+				return nil, fmt.Errorf("%s and %s cannot be divided because the divisor is zero", typeName(a), typeName(b))
+			}
+			return x / y, nil
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return splitString(x, y), nil
+		}
+	}
+	return nil, operandError("divided", a, b)
+}
+
+func modulo(a, b any) (any, error) {
+	x, xok := a.(float64)
+	y, yok := b.(float64)
+	if !xok || !yok {
+		return nil, operandError("divided", a, b)
+	}
+	if int64(y) == 0 {
+		return nil, fmt.Errorf("%s and %s cannot be divided because the divisor is zero", typeName(a), typeName(b))
+	}
+	return float64(int64(x) % int64(math.Abs(y))), nil
+}
+
+func operandError(verb string, a, b any) error {
+	return fmt.Errorf("%s and %s cannot be %s", typeName(a), typeName(b), verb)
+}
+
+func splitString(s, sep string) []any {
+	if s == "" {
+		return []any{}
+	}
+	parts := strings.Split(s, sep)
+	out := make([]any, len(parts))
+	for i, p := range parts {
+		out[i] = p
+	}
+	return out
+}
+
+// field is .name.
+func field(name string) filter {
+	return func(in any) ([]any, error) {
+		switch v := in.(type) {
+		case nil:
+			return []any{nil}, nil
+		case map[string]any:
+			return []any{v[name]}, nil
+		}
+		return nil, fmt.Errorf("cannot index %s with %q", typeName(in), name)
+	}
+}
+
+// index is .[key] for every output of key.
+func index(key filter) filter {
+	return func(in any) ([]any, error) {
+		keys, err := key(in)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]any, 0, len(keys))
+		for _, k := range keys {
+			v, err := indexValue(in, k)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	}
+}
+
+func indexValue(in, key any) (any, error) {
+	switch v := in.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		if k, ok := key.(string); ok {
+			return v[k], nil
+		}
+	case []any:
+		if k, ok := key.(float64); ok {
+			i := int(math.Floor(k))
+			if i < 0 {
+				i += len(v)
+			}
+			if i < 0 || i >= len(v) {
+				return nil, nil
+			}
+			return v[i], nil
+		}
+	}
+	return nil, fmt.Errorf("cannot index %s with %s", typeName(in), typeName(key))
+}
+
+// slice is .[from:to] where either bound may be missing.
+func slice(from, to filter) filter {
+	bound := func(f filter, in any) ([]any, error) {
+		if f == nil {
+			return []any{nil}, nil
+		}
+		return f(in)
+	}
+	return func(in any) ([]any, error) {
+		froms, err := bound(from, in)
+		if err != nil {
+			return nil, err
+		}
+		tos, err := bound(to, in)
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, t := range tos {
+			for _, f := range froms {
+				v, err := sliceValue(in, f, t)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, v)
+			}
+		}
+		return out, nil
+	}
+}
+
+func sliceValue(in, from, to any) (any, error) {
+	var size int
+	switch v := in.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		size = len(v)
+	case string:
+		size = len([]rune(v))
+	default:
+		return nil, fmt.Errorf("cannot slice %s", typeName(in))
+	}
+	clamp := func(b any, def int) (int, error) {
+		if b == nil {
+			return def, nil
+		}
+		f, ok := b.(float64)
+		if !ok {
+			return 0, fmt.Errorf("slice bounds must be numbers, got %s", typeName(b))
+		}
+		i := int(math.Floor(f))
+		if i < 0 {
+			i += size
+		}
+		return min(max(i, 0), size), nil
+	}
+	start, err := clamp(from, 0)
+	if err != nil {
+		return nil, err
+	}
+	end, err := clamp(to, size)
+	if err != nil {
+		return nil, err
+	}
+	end = max(end, start)
+	if s, ok := in.(string); ok {
+		return string([]rune(s)[start:end]), nil
+	}
+	return append([]any{}, in.([]any)[start:end]...), nil
+}
+
+// iterate is .[], the values of an array or object.
+func iterate(in any) ([]any, error) {
+	switch v := in.(type) {
+	case []any:
+		return append([]any{}, v...), nil
+	case map[string]any:
+		out := make([]any, 0, len(v))
+		for _, k := range sortedKeys(v) {
+			out = append(out, v[k])
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("cannot iterate over %s", typeName(in))
+}
+
+// optional is f?, which drops the error of a failing f.
+func optional(f filter) filter {
+	return func(in any) ([]any, error) {
+		out, err := f(in)
+		if err != nil {
+			return nil, nil
+		}
+		return out, nil
+	}
+}
+
+// recurse is .., the input and every value within it, in pre-order.
+func recurse(in any) ([]any, error) {
+	out := []any{in}
+	switch in.(type) {
+	case []any, map[string]any:
+		children, _ := iterate(in)
+		for _, c := range children {
+			r, _ := recurse(c)
+			out = append(out, r...)
+		}
+	}
+	return out, nil
+}
+
+// collect is [f], an array of every output of f.
+func collect(f filter) filter {
+	return func(in any) ([]any, error) {
+		if f == nil {
+			return []any{[]any{}}, nil
+		}
+		out, err := f(in)
+		if err != nil {
+			return nil, err
+		}
+		if out == nil {
+			out = []any{}
+		}
+		return []any{out}, nil
+	}
+}
+
+type objectEntry struct {
+	key, value filter
+}
+
+// object builds one object for every combination of key and value outputs.
+func object(entries []objectEntry) filter {
+	return func(in any) ([]any, error) {
+		objs := []map[string]any{{}}
+		for _, e := range entries {
+			keys, err := e.key(in)
+			if err != nil {
+				return nil, err
+			}
+			values, err := e.value(in)
+			if err != nil {
+				return nil, err
+			}
+			next := make([]map[string]any, 0, len(objs)*len(keys)*len(values))
+			for _, obj := range objs {
+				for _, k := range keys {
+					ks, ok := k.(string)
+					if !ok {
+						return nil, fmt.Errorf("object keys must be strings, got %s", typeName(k))
+					}
+					for _, v := range values {
+						o := make(map[string]any, len(obj)+1)
+						for ok, ov := range obj {
+							o[ok] = ov
+						}
+						o[ks] = v
+						next = append(next, o)
+					}
+				}
+			}
+			objs = next
+		}
+		out := make([]any, len(objs))
+		for i, o := range objs {
+			out[i] = o
+		}
+		return out, nil
+	}
+}
+
+func ifThenElse(cond, then, otherwise filter) filter {
+	return func(in any) ([]any, error) {
+		conds, err := cond(in)
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, c := range conds {
+			branch := otherwise
+			if truthy(c) {
+				branch = then
+			}
+			r, err := branch(in)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r...)
+		}
+		return out, nil
+	}
+}
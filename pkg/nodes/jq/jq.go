@@ -0,0 +1,161 @@
+// Package jq evaluates a subset of the jq filter language over tree data.
+//
+// Supported are the identity (.), field and index access (.foo, ."foo",
+// .[0], .["foo"]), slices (.[1:3]), iteration (.[]), recursion (..), the
+// optional suffix (?), pipes (|), commas (,), alternatives (//), literals,
+// array and object construction ({a, b: .c, (.k): .v}), arithmetic
+// (+ - * / %), comparisons (== != < <= > >=), and, or, if/then/elif/else/end
+// and the builtins listed in Builtins. Variables, reduce, path assignment and
+// string interpolation are not supported.
+//
+// Values are plain Go data as produced by encoding/json: nil, bool, float64,
+// string, []any and map[string]any.
+package jq
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/crosleyzack/wndr/pkg/nodes"
+)
+
+// filter produces the outputs of a jq expression for one input.
+type filter func(in any) ([]any, error)
+
+// Filter is a compiled jq program.
+type Filter struct {
+	src string
+	run filter
+}
+
+// syntaxError reports a problem at offset in the program src.
+func syntaxError(src string, offset int, msg string) error {
+	return &nodes.SyntaxError{Kind: "filter", Text: src, Offset: offset, Msg: msg}
+}
+
+// Compile parses a jq program.
+func Compile(src string) (*Filter, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: src, tokens: tokens}
+	run, err := p.program()
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{src: src, run: run}, nil
+}
+
+// String returns the program the filter was compiled from.
+func (f *Filter) String() string {
+	return f.src
+}
+
+// Run evaluates the filter with input and returns every output in order.
+// Numbers of any Go type in input are treated as float64, and values jq has no
+// type for, such as TOML dates, as their string form.
+func (f *Filter) Run(input any) ([]any, error) {
+	return f.run(normalize(input))
+}
+
+// RunNode evaluates the filter with the data under n as input. Tree leaves only
+// hold text, so leaves reading as numbers or booleans are given those types.
+func (f *Filter) RunNode(n *nodes.Node) ([]any, error) {
+	return f.run(typedLeaves(nodes.ToValue(n)))
+}
+
+// normalize converts v into the value types jq works with.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case nil, bool, float64, string:
+		return v
+	case int:
+		return float64(v)
+	case int8:
+		return float64(v)
+	case int16:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case uint8:
+		return float64(v)
+	case uint16:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = normalize(e)
+		}
+		return out
+	case []map[string]any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = normalize(e)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = normalize(e)
+		}
+		return out
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[fmt.Sprint(k)] = normalize(e)
+		}
+		return out
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// typedLeaves recovers numbers and booleans from the text of tree leaves.
+func typedLeaves(v any) any {
+	switch v := v.(type) {
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+		if v == "true" || v == "false" {
+			return v == "true"
+		}
+		return v
+	case []any:
+		for i, e := range v {
+			v[i] = typedLeaves(e)
+		}
+		return v
+	case map[string]any:
+		for k, e := range v {
+			v[k] = typedLeaves(e)
+		}
+		return v
+	}
+	return v
+}
+
+// sortedKeys returns the keys of m in jq's order.
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
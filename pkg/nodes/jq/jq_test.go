@@ -0,0 +1,221 @@
+package jq
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testInput = `{
+  "name": "cluster",
+  "pods": [
+    {"name": "api", "status": "Running", "restarts": 3, "labels": {"app": "api", "tier": "web"}},
+    {"name": "db", "status": "Pending", "restarts": 0, "labels": {"app": "db"}},
+    {"name": "worker", "status": "Running", "restarts": 12, "labels": {"app": "worker"}}
+  ],
+  "empty": null
+}`
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{filter: ".", want: []string{`{"empty":null,"name":"cluster","pods":[{"labels":{"app":"api","tier":"web"},"name":"api","restarts":3,"status":"Running"},{"labels":{"app":"db"},"name":"db","restarts":0,"status":"Pending"},{"labels":{"app":"worker"},"name":"worker","restarts":12,"status":"Running"}]}`}},
+		{filter: "", want: []string{`{"empty":null,"name":"cluster","pods":[{"labels":{"app":"api","tier":"web"},"name":"api","restarts":3,"status":"Running"},{"labels":{"app":"db"},"name":"db","restarts":0,"status":"Pending"},{"labels":{"app":"worker"},"name":"worker","restarts":12,"status":"Running"}]}`}},
+		{filter: ".name", want: []string{`"cluster"`}},
+		{filter: `."name"`, want: []string{`"cluster"`}},
+		{filter: `.["name"]`, want: []string{`"cluster"`}},
+		{filter: ".missing", want: []string{`null`}},
+		{filter: ".empty.deeper", want: []string{`null`}},
+		{filter: ".pods[0].name", want: []string{`"api"`}},
+		{filter: ".pods.[0].name", want: []string{`"api"`}},
+		{filter: ".pods[-1].name", want: []string{`"worker"`}},
+		{filter: ".pods[5]", want: []string{`null`}},
+		{filter: ".pods[1:][].name", want: []string{`"db"`, `"worker"`}},
+		{filter: ".pods[:1] | length", want: []string{`1`}},
+		{filter: `.name[1:3]`, want: []string{`"lu"`}},
+		{filter: ".pods[].name", want: []string{`"api"`, `"db"`, `"worker"`}},
+		{filter: ".pods[] | .name", want: []string{`"api"`, `"db"`, `"worker"`}},
+		{filter: ".name, .empty", want: []string{`"cluster"`, `null`}},
+		{filter: `.pods[] | select(.status == "Running") | .name`, want: []string{`"api"`, `"worker"`}},
+		{filter: ".pods[] | select(.restarts > 2 and .labels.tier) | .name", want: []string{`"api"`}},
+		{filter: ".pods[] | select(.restarts >= 12 or .restarts == 0) | .name", want: []string{`"db"`, `"worker"`}},
+		{filter: ".pods | map(.restarts)", want: []string{`[3,0,12]`}},
+		{filter: ".pods | map(.restarts) | add", want: []string{`15`}},
+		{filter: ".pods | map(.name) | join(\",\")", want: []string{`"api,db,worker"`}},
+		{filter: ".pods | map(select(.status != \"Running\"))[].name", want: []string{`"db"`}},
+		{filter: ". | keys", want: []string{`["empty","name","pods"]`}},
+		{filter: ".pods | keys", want: []string{`[0,1,2]`}},
+		{filter: ".pods | length", want: []string{`3`}},
+		{filter: ".name | length", want: []string{`7`}},
+		{filter: ".pods[0].labels | has(\"tier\"), has(\"nope\")", want: []string{`true`, `false`}},
+		{filter: "{name, count: (.pods | length)}", want: []string{`{"count":3,"name":"cluster"}`}},
+		{filter: `{"quoted": 1, (.name): 2}`, want: []string{`{"cluster":2,"quoted":1}`}},
+		{filter: "{a: (1, 2)}", want: []string{`{"a":1}`, `{"a":2}`}},
+		{filter: "[.pods[] | .labels.app]", want: []string{`["api","db","worker"]`}},
+		{filter: "[]", want: []string{`[]`}},
+		{filter: "[.pods[] | select(.restarts > 100)]", want: []string{`[]`}},
+		{filter: "1 + 2 * 3 - 4 / 2", want: []string{`5`}},
+		{filter: "10 % 3, -(1)", want: []string{`1`, `-1`}},
+		{filter: `"a" + "b", [1] + [2], {"a": 1} + {"b": 2}, null + 1`, want: []string{`"ab"`, `[1,2]`, `{"a":1,"b":2}`, `1`}},
+		{filter: "[1, 2, 3, 2] - [2]", want: []string{`[1,3]`}},
+		{filter: `{"a": {"b": 1}} * {"a": {"c": 2}}`, want: []string{`{"a":{"b":1,"c":2}}`}},
+		{filter: `"a,b" / ","`, want: []string{`["a","b"]`}},
+		{filter: "(1, 2) + (10, 20)", want: []string{`11`, `12`, `21`, `22`}},
+		{filter: ".empty // \"default\"", want: []string{`"default"`}},
+		{filter: ".name // \"default\"", want: []string{`"cluster"`}},
+		{filter: "(.pods[].name | select(. == \"x\")) // \"none\"", want: []string{`"none"`}},
+		{filter: "if .empty then 1 elif .name == \"cluster\" then 2 else 3 end", want: []string{`2`}},
+		{filter: "if false then 1 end", want: []string{testInputCompact()}},
+		{filter: "null < false, false < true, true < 0, 0 < \"\", \"\" < [], [] < {}", want: []string{`true`, `true`, `true`, `true`, `true`, `true`}},
+		{filter: "[1, 2] == [1, 2], {\"a\": 1} != {\"a\": 1}", want: []string{`true`, `false`}},
+		{filter: ".pods[0].name.first?", want: nil},
+		{filter: ".pods[0].name[]?", want: nil},
+		{filter: "[..] | length", want: []string{`23`}},
+		{filter: ".pods | sort_by(.restarts) | map(.name)", want: []string{`["db","api","worker"]`}},
+		{filter: ".pods | group_by(.status) | map(length)", want: []string{`[1,2]`}},
+		{filter: ".pods | unique_by(.status) | map(.name)", want: []string{`["db","api"]`}},
+		{filter: ".pods | max_by(.restarts).name, min_by(.restarts).name", want: []string{`"worker"`, `"db"`}},
+		{filter: "[3, 1, 2, 1] | sort, unique, reverse, min, max, first, last", want: []string{`[1,1,2,3]`, `[1,2,3]`, `[1,2,1,3]`, `1`, `3`, `3`, `1`}},
+		{filter: "[true, false] | any, all", want: []string{`true`, `false`}},
+		{filter: ".pods[0].labels | to_entries", want: []string{`[{"key":"app","value":"api"},{"key":"tier","value":"web"}]`}},
+		{filter: ".pods[0].labels | with_entries(.value |= 1)?", want: nil},
+		{filter: ".pods[0].labels | with_entries(select(.key == \"app\"))", want: []string{`{"app":"api"}`}},
+		{filter: `[{"name": "a", "v": 1}] | from_entries`, want: []string{`{"a":1}`}},
+		{filter: ".pods[0].labels | map_values(ascii_upcase)", want: []string{`{"app":"API","tier":"WEB"}`}},
+		{filter: ".pods[0] | type, (.restarts | type), (.labels | type)", want: []string{`"object"`, `"number"`, `"object"`}},
+		{filter: ".pods[0].restarts | tostring, tojson", want: []string{`"3"`, `"3"`}},
+		{filter: `"12" | tonumber`, want: []string{`12`}},
+		{filter: `"{\"a\":[1]}" | fromjson`, want: []string{`{"a":[1]}`}},
+		{filter: `.name | startswith("clu"), endswith("x"), ltrimstr("clu"), test("^c.*r$"), contains("ust")`, want: []string{`true`, `false`, `"ster"`, `true`, `true`}},
+		{filter: `.name | split("u")`, want: []string{`["cl","ster"]`}},
+		{filter: `{"a": [1, {"b": 2}]} | contains({"a": [{"b": 2}]})`, want: []string{`true`}},
+		{filter: "[range(3)], [range(1; 3)]", want: []string{`[0,1,2]`, `[1,2]`}},
+		{filter: "[limit(2; .pods[].name)], first(.pods[].name), last(.pods[].name)", want: []string{`["api","db"]`, `"api"`, `"worker"`}},
+		{filter: "[[1, [2]], 3] | flatten, flatten(1)", want: []string{`[1,2,3]`, `[1,[2],3]`}},
+		{filter: "[.. | numbers]", want: []string{`[3,0,12]`}},
+		{filter: "[.[] | strings], [.[] | nulls]", want: []string{`["cluster"]`, `[null]`}},
+		{filter: "3.7 | floor", want: []string{`3`}},
+		{filter: "empty", want: nil},
+		{filter: "not", want: []string{`false`}},
+		{filter: "[.pods[] | .name] # trailing comment", want: []string{`["api","db","worker"]`}},
+		{filter: "[recurse(.children[]?; true)] | length", want: nil},
+	}
+	var input any
+	require.NoError(t, json.Unmarshal([]byte(testInput), &input))
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := Compile(tt.filter)
+			if tt.want == nil && err != nil {
+				// a few cases check that unsupported syntax fails cleanly
+				var serr *nodes.SyntaxError
+				require.True(t, errors.As(err, &serr), err)
+				return
+			}
+			require.NoError(t, err)
+			got, err := f.Run(input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, encode(t, got))
+		})
+	}
+}
+
+func testInputCompact() string {
+	var v any
+	if err := json.Unmarshal([]byte(testInput), &v); err != nil {
+		panic(err)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func encode(t *testing.T, vs []any) []string {
+	t.Helper()
+	if vs == nil {
+		return nil
+	}
+	out := make([]string, len(vs))
+	for i, v := range vs {
+		b, err := json.Marshal(v)
+		require.NoError(t, err)
+		out[i] = string(b)
+	}
+	return out
+}
+
+func TestRunErrors(t *testing.T) {
+	for _, filter := range []string{
+		".name.first",
+		".name[]",
+		`.pods + "x"`,
+		"1 / 0",
+		"true | length",
+		".pods[1:].name",
+		`"x" | test("(")`,
+	} {
+		t.Run(filter, func(t *testing.T) {
+			f, err := Compile(filter)
+			require.NoError(t, err)
+			_, err = f.Run(map[string]any{"name": "a", "pods": []any{}})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, filter := range []string{
+		".[",
+		"(.a",
+		"{a:}",
+		`"open`,
+		"nosuchfunc",
+		"select(.a; .b)",
+		"if . then 1",
+		". as $x | $x",
+		`"\(.a)"`,
+		". )",
+		"@",
+	} {
+		t.Run(filter, func(t *testing.T) {
+			_, err := Compile(filter)
+			var serr *nodes.SyntaxError
+			require.True(t, errors.As(err, &serr), "got %v", err)
+			assert.Equal(t, filter, serr.Text)
+		})
+	}
+}
+
+func TestRunNormalizesNumbers(t *testing.T) {
+	f, err := Compile(".a + .b + .c")
+	require.NoError(t, err)
+	got, err := f.Run(map[string]any{"a": int64(1), "b": uint64(2), "c": 3})
+	require.NoError(t, err)
+	assert.Equal(t, []any{6.0}, got)
+}
+
+func TestRunNode(t *testing.T) {
+	root := nodes.New(map[string]any{
+		"items": []any{
+			map[string]any{"n": 2.0, "ok": true},
+			map[string]any{"n": 10.0, "ok": false},
+		},
+	}, 0, nodes.EmptyRepr)
+	f, err := Compile(".items | map(select(.n > 5 or .ok)) | length")
+	require.NoError(t, err)
+	// leaf text is read back as numbers and booleans, so 10 > 5 numerically
+	got, err := f.RunNode(root)
+	require.NoError(t, err)
+	assert.Equal(t, []any{2.0}, got)
+}
+
+func TestBuiltins(t *testing.T) {
+	names := Builtins()
+	assert.Contains(t, names, "select/1")
+	assert.Contains(t, names, "map/1")
+	assert.IsIncreasing(t, names)
+}
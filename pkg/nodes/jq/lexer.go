@@ -0,0 +1,181 @@
+package jq
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	// tokenIdent is a name such as a builtin or keyword
+	tokenIdent
+	// tokenField is .name; text holds the name
+	tokenField
+	tokenString
+	tokenNumber
+	// tokenPunct is an operator or bracket; text holds it
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+// punctuation is every operator, longest first so that // is not read as /.
+var punctuation = []string{
+	"..", "//", "==", "!=", "<=", ">=",
+	".", "|", ",", "(", ")", "[", "]", "{", "}", ":", ";", "?",
+	"<", ">", "+", "-", "*", "/", "%",
+}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	errorf := func(pos int, msg string) error {
+		return syntaxError(src, pos, msg)
+	}
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#':
+			// comment to the end of the line
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '.' && i+1 < len(src) && isIdentStart(src[i+1]):
+			start := i
+			i++
+			for i < len(src) && isIdentPart(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenField, text: src[start+1 : i], pos: start})
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && isIdentPart(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[start:i], pos: start})
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(src) && strings.IndexByte("0123456789.eE", src[i]) >= 0 {
+				// allow a sign only directly after the exponent marker
+				if (src[i] == 'e' || src[i] == 'E') && i+1 < len(src) && (src[i+1] == '+' || src[i+1] == '-') {
+					i++
+				}
+				i++
+			}
+			f, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, errorf(start, "invalid number "+src[start:i])
+			}
+			tokens = append(tokens, token{kind: tokenNumber, num: f, text: src[start:i], pos: start})
+		case c == '$':
+			return nil, errorf(i, "variables are not supported")
+		case c == '"':
+			s, end, err := lexString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: s, pos: i})
+			i = end
+		default:
+			found := false
+			for _, p := range punctuation {
+				if strings.HasPrefix(src[i:], p) {
+					tokens = append(tokens, token{kind: tokenPunct, text: p, pos: i})
+					i += len(p)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, errorf(i, "unexpected character "+strconv.QuoteRune(rune(c)))
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+// lexString reads the double quoted string starting at src[start], returning
+// its value and the offset just past the closing quote.
+func lexString(src string, start int) (string, int, error) {
+	errorf := func(pos int, msg string) error {
+		return syntaxError(src, pos, msg)
+	}
+	var b strings.Builder
+	i := start + 1
+	for {
+		if i >= len(src) {
+			return "", 0, errorf(start, "unterminated string")
+		}
+		c := src[i]
+		switch c {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 >= len(src) {
+				return "", 0, errorf(i, "unterminated string")
+			}
+			i++
+			switch e := src[i]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case '"', '\\', '/':
+				b.WriteByte(e)
+			case 'u':
+				r, ok := hex4(src, i+1)
+				if !ok {
+					return "", 0, errorf(i, "invalid \\u escape")
+				}
+				i += 4
+				if utf16.IsSurrogate(r) && strings.HasPrefix(src[i+1:], `\u`) {
+					if low, ok := hex4(src, i+3); ok {
+						r = utf16.DecodeRune(r, low)
+						i += 6
+					}
+				}
+				b.WriteRune(r)
+			case '(':
+				return "", 0, errorf(i, "string interpolation is not supported")
+			default:
+				return "", 0, errorf(i, "invalid escape \\"+string(e))
+			}
+			i++
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+}
+
+func hex4(src string, at int) (rune, bool) {
+	if at+4 > len(src) {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(src[at:at+4], 16, 32)
+	return rune(v), err == nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
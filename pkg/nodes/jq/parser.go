@@ -0,0 +1,460 @@
+package jq
+
+import (
+	"fmt"
+)
+
+// parser is a recursive descent parser building the filter closures directly.
+// Precedence, lowest first: | , // or and comparisons + - * / % postfix.
+type parser struct {
+	src    string
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// is reports whether the next token is the punctuation or keyword text.
+func (p *parser) is(text string) bool {
+	t := p.peek()
+	return (t.kind == tokenPunct || t.kind == tokenIdent) && t.text == text
+}
+
+func (p *parser) accept(text string) bool {
+	if p.is(text) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.errorf("expected %s", text)
+	}
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return syntaxError(p.src, p.peek().pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) program() (filter, error) {
+	if p.peek().kind == tokenEOF {
+		// an empty program is the identity, as in jq
+		return identity, nil
+	}
+	f, err := p.pipe()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf("unexpected %s", describe(t))
+	}
+	return f, nil
+}
+
+func describe(t token) string {
+	switch t.kind {
+	case tokenField:
+		return "." + t.text
+	case tokenString:
+		return fmt.Sprintf("%q", t.text)
+	default:
+		return t.text
+	}
+}
+
+func (p *parser) pipe() (filter, error) {
+	left, err := p.comma()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("|") {
+		return left, nil
+	}
+	right, err := p.pipe()
+	if err != nil {
+		return nil, err
+	}
+	return pipe(left, right), nil
+}
+
+func (p *parser) comma() (filter, error) {
+	left, err := p.alternative()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(",") {
+		right, err := p.alternative()
+		if err != nil {
+			return nil, err
+		}
+		left = comma(left, right)
+	}
+	return left, nil
+}
+
+func (p *parser) alternative() (filter, error) {
+	left, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("//") {
+		return left, nil
+	}
+	right, err := p.alternative()
+	if err != nil {
+		return nil, err
+	}
+	return alternative(left, right), nil
+}
+
+func (p *parser) or() (filter, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = logical(left, right, true)
+	}
+	return left, nil
+}
+
+func (p *parser) and() (filter, error) {
+	left, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and") {
+		right, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		left = logical(left, right, false)
+	}
+	return left, nil
+}
+
+func (p *parser) comparison() (filter, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			right, err := p.additive()
+			if err != nil {
+				return nil, err
+			}
+			return binary(left, right, compareOp(op)), nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) additive() (filter, error) {
+	left, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op func(a, b any) (any, error)
+		switch {
+		case p.accept("+"):
+			op = add
+		case p.accept("-"):
+			op = subtract
+		default:
+			return left, nil
+		}
+		right, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = binary(left, right, op)
+	}
+}
+
+func (p *parser) multiplicative() (filter, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op func(a, b any) (any, error)
+		switch {
+		case p.accept("*"):
+			op = multiply
+		case p.accept("/"):
+			op = divide
+		case p.accept("%"):
+			op = modulo
+		default:
+			return left, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = binary(left, right, op)
+	}
+}
+
+func (p *parser) unary() (filter, error) {
+	if p.accept("-") {
+		f, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		return binary(literal(0.0), f, subtract), nil
+	}
+	return p.postfix()
+}
+
+func (p *parser) postfix() (filter, error) {
+	f, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokenField:
+			p.next()
+			f = pipe(f, field(t.text))
+		case p.is(".") && p.tokens[p.pos+1].kind == tokenString:
+			p.next()
+			f = pipe(f, field(p.next().text))
+		case p.is(".") && p.tokens[p.pos+1].kind == tokenPunct && p.tokens[p.pos+1].text == "[":
+			// .a.[0] is the same as .a[0]
+			p.next()
+		case p.is("["):
+			suffix, err := p.bracket()
+			if err != nil {
+				return nil, err
+			}
+			f = pipe(f, suffix)
+		case p.is("?"):
+			p.next()
+			f = optional(f)
+		default:
+			return f, nil
+		}
+	}
+}
+
+// bracket parses [], [e], [e:e] and their open ended slices.
+func (p *parser) bracket() (filter, error) {
+	p.next() // [
+	if p.accept("]") {
+		return iterate, nil
+	}
+	var from, to filter
+	var err error
+	if !p.is(":") {
+		if from, err = p.pipe(); err != nil {
+			return nil, err
+		}
+	}
+	if !p.accept(":") {
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return index(from), nil
+	}
+	if !p.is("]") {
+		if to, err = p.pipe(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return slice(from, to), nil
+}
+
+func (p *parser) primary() (filter, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenField:
+		p.next()
+		return field(t.text), nil
+	case tokenNumber:
+		p.next()
+		return literal(t.num), nil
+	case tokenString:
+		p.next()
+		return literal(t.text), nil
+	case tokenIdent:
+		return p.ident()
+	case tokenEOF:
+		return nil, p.errorf("unexpected end of filter")
+	}
+	switch t.text {
+	case ".":
+		p.next()
+		if s := p.peek(); s.kind == tokenString {
+			p.next()
+			return field(s.text), nil
+		}
+		return identity, nil
+	case "..":
+		p.next()
+		return recurse, nil
+	case "(":
+		p.next()
+		f, err := p.pipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return f, nil
+	case "[":
+		p.next()
+		if p.accept("]") {
+			return collect(nil), nil
+		}
+		f, err := p.pipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return collect(f), nil
+	case "{":
+		return p.object()
+	}
+	return nil, p.errorf("unexpected %s", describe(t))
+}
+
+func (p *parser) ident() (filter, error) {
+	t := p.next()
+	switch t.text {
+	case "true":
+		return literal(true), nil
+	case "false":
+		return literal(false), nil
+	case "null":
+		return literal(nil), nil
+	case "if":
+		return p.conditional()
+	case "then", "elif", "else", "end", "and", "or":
+		p.pos--
+		return nil, p.errorf("unexpected %s", t.text)
+	}
+	var args []filter
+	if p.accept("(") {
+		for {
+			arg, err := p.pipe()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.accept(")") {
+				break
+			}
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	f, err := builtin(t.text, args)
+	if err != nil {
+		return nil, syntaxError(p.src, t.pos, err.Error())
+	}
+	return f, nil
+}
+
+// conditional parses the rest of if c then a (elif c then a)* (else b)? end.
+func (p *parser) conditional() (filter, error) {
+	cond, err := p.pipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("then"); err != nil {
+		return nil, err
+	}
+	then, err := p.pipe()
+	if err != nil {
+		return nil, err
+	}
+	otherwise := identity
+	switch {
+	case p.accept("elif"):
+		if otherwise, err = p.conditional(); err != nil {
+			return nil, err
+		}
+		return ifThenElse(cond, then, otherwise), nil
+	case p.accept("else"):
+		if otherwise, err = p.pipe(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("end"); err != nil {
+		return nil, err
+	}
+	return ifThenElse(cond, then, otherwise), nil
+}
+
+// object parses {k: v, ...}. A key may be a name, a string or a
+// parenthesised filter; a bare name or string k is short for k: .k.
+func (p *parser) object() (filter, error) {
+	p.next() // {
+	var entries []objectEntry
+	for !p.accept("}") {
+		if len(entries) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		var e objectEntry
+		t := p.next()
+		switch {
+		case t.kind == tokenIdent || t.kind == tokenString:
+			e.key = literal(t.text)
+			e.value = field(t.text)
+		case t.kind == tokenPunct && t.text == "(":
+			key, err := p.pipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			e.key = key
+		default:
+			p.pos--
+			return nil, p.errorf("expected an object key")
+		}
+		if p.accept(":") {
+			// values stop at a comma, which separates entries
+			value, err := p.alternative()
+			if err != nil {
+				return nil, err
+			}
+			e.value = value
+		} else if e.value == nil {
+			return nil, p.errorf("expected :")
+		}
+		entries = append(entries, e)
+	}
+	return object(entries), nil
+}
@@ -0,0 +1,97 @@
+package jq
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// typeName is the jq name of the type of v.
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
+
+// truthy reports whether v counts as true: anything but null and false.
+func truthy(v any) bool {
+	b, ok := v.(bool)
+	return v != nil && (!ok || b)
+}
+
+// typeRank orders values of different types:
+// null < false < true < numbers < strings < arrays < objects.
+func typeRank(v any) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 2
+		}
+		return 1
+	case float64:
+		return 3
+	case string:
+		return 4
+	case []any:
+		return 5
+	default:
+		return 6
+	}
+}
+
+// compare orders two values the way jq sorts them. Arrays compare element by
+// element; objects compare their sorted keys first and then their values.
+func compare(a, b any) int {
+	if c := cmp.Compare(typeRank(a), typeRank(b)); c != 0 {
+		return c
+	}
+	switch x := a.(type) {
+	case float64:
+		return cmp.Compare(x, b.(float64))
+	case string:
+		return strings.Compare(x, b.(string))
+	case []any:
+		y := b.([]any)
+		for i := range min(len(x), len(y)) {
+			if c := compare(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(x), len(y))
+	case map[string]any:
+		y := b.(map[string]any)
+		xk, yk := sortedKeys(x), sortedKeys(y)
+		if c := slices.Compare(xk, yk); c != 0 {
+			return c
+		}
+		for _, k := range xk {
+			if c := compare(x[k], y[k]); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+func containsValue(arr []any, v any) bool {
+	for _, e := range arr {
+		if compare(e, v) == 0 {
+			return true
+		}
+	}
+	return false
+}
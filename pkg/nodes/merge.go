@@ -0,0 +1,318 @@
+package nodes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/crosleyzack/wndr/pkg/omap"
+	"github.com/google/uuid"
+)
+
+// MergeStrategy is how Merge combines two values found at the same path.
+type MergeStrategy int
+
+const (
+	// MergeDeep merges objects key by key, merging the values of keys found
+	// in both. Arrays are replaced. It is the default for every path.
+	MergeDeep MergeStrategy = iota
+	// MergeShallow merges objects key by key, but the later value of a key
+	// found in both replaces the earlier one whole.
+	MergeShallow
+	// MergeReplace keeps the later value in place of the earlier one.
+	MergeReplace
+	// MergeAppend adds the elements of the later array after those of the
+	// earlier one.
+	MergeAppend
+	// MergeByKey merges the elements of two arrays of objects that have the
+	// same value for the rule's Key, and appends the rest.
+	MergeByKey
+)
+
+var mergeStrategyNames = map[MergeStrategy]string{
+	MergeDeep:    "deep",
+	MergeShallow: "shallow",
+	MergeReplace: "replace",
+	MergeAppend:  "append",
+	MergeByKey:   "merge-by-key",
+}
+
+func (s MergeStrategy) String() string {
+	if name, ok := mergeStrategyNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("MergeStrategy(%d)", int(s))
+}
+
+// GetMergeStrategies returns the strategy names accepted by ParseMergeRule.
+func GetMergeStrategies() []string {
+	return []string{"deep", "shallow", "replace", "append", "merge-by-key:<key>"}
+}
+
+// MergeRule sets the strategy Merge uses for the values at Path. An array
+// index in Path matches every element, so containers[0] covers each element
+// of containers. A strategy that does not apply to the values found, such as
+// MergeAppend for two objects, is ignored.
+type MergeRule struct {
+	Path     Path
+	Strategy MergeStrategy
+	// Key is the field identifying array elements for MergeByKey
+	Key string
+}
+
+// ParseMergeRule reads a rule written as <path>=<strategy>, with the path in
+// syntax s and the strategy one of GetMergeStrategies, such as
+// spec.containers=merge-by-key:name.
+func ParseMergeRule(text string, s PathSyntax) (MergeRule, error) {
+	i := strings.LastIndex(text, "=")
+	if i < 0 {
+		return MergeRule{}, fmt.Errorf("merge rule %q: expected <path>=<strategy>", text)
+	}
+	path, err := ParsePath(text[:i], s)
+	if err != nil {
+		return MergeRule{}, fmt.Errorf("merge rule %q: %w", text, err)
+	}
+	rule := MergeRule{Path: path}
+	name, key, hasKey := strings.Cut(text[i+1:], ":")
+	for st, n := range mergeStrategyNames {
+		if strings.EqualFold(n, name) {
+			rule.Strategy = st
+			if (st == MergeByKey) != hasKey || (hasKey && key == "") {
+				return MergeRule{}, fmt.Errorf("merge rule %q: only merge-by-key takes a key, and it needs one", text)
+			}
+			rule.Key = key
+			return rule, nil
+		}
+	}
+	return MergeRule{}, fmt.Errorf("merge rule %q: unknown strategy %q: expected one of %s", text, name, strings.Join(GetMergeStrategies(), ", "))
+}
+
+// Merge layers trees in order, each later tree taking precedence over the
+// ones before it, and returns the result as a new tree. The inputs are left
+// unchanged. Values of different kinds, and scalars, are replaced by the later
+// value; objects and arrays are combined with the strategy of the last rule
+// whose path matches, or MergeDeep. Nodes in the result keep the IDs of the
+// nodes they were copied from, so each value can be traced to the tree it came
+// from. Summaries are rebuilt with repr, or LeafValuesOnly when repr is nil.
+func Merge(trees []*Node, rules []MergeRule, repr ReprNode) *Node {
+	out, _ := MergeWithProvenance(trees, rules, repr)
+	return out
+}
+
+// Provenance records where the values of a merged tree came from.
+type Provenance struct {
+	// Sources holds the index of the input tree of every node, by ID
+	Sources map[uuid.UUID]int
+	// Shadowed holds the earlier values a node of the merged tree replaced,
+	// by its ID, the most recent first
+	Shadowed map[uuid.UUID][]*Node
+}
+
+// Source returns the index of the input tree n came from.
+func (p *Provenance) Source(n *Node) (int, bool) {
+	i, ok := p.Sources[n.ID]
+	return i, ok
+}
+
+// MergeWithProvenance merges trees like Merge, and also reports which tree
+// each node came from and which values it replaced. The replaced values are
+// copies summarized with repr.
+func MergeWithProvenance(trees []*Node, rules []MergeRule, repr ReprNode) (*Node, *Provenance) {
+	if repr == nil {
+		repr = LeafValuesOnly
+	}
+	m := &merger{rules: rules, prov: &Provenance{Sources: map[uuid.UUID]int{}, Shadowed: map[uuid.UUID][]*Node{}}}
+	var out *Node
+	for i, t := range trees {
+		DFS(t, func(n *Node, _ int) error {
+			m.prov.Sources[n.ID] = i
+			return nil
+		}, WithNextNodes(AllChildren))
+		if out == nil {
+			out = copyKeepingIDs(t)
+			continue
+		}
+		out = m.merge(out, copyKeepingIDs(t), Path{})
+	}
+	if out == nil {
+		out = &Node{Children: omap.New[string, *Node](), Kind: Object}
+	}
+	out.Parent = nil
+	out.Expand = true
+	summarize(out, repr)
+	for _, shadowed := range m.prov.Shadowed {
+		for _, n := range shadowed {
+			summarize(n, repr)
+		}
+	}
+	return out, m.prov
+}
+
+type merger struct {
+	rules []MergeRule
+	prov  *Provenance
+}
+
+// mergeKind is what sort of value a node holds, as far as merging goes
+type mergeKind int
+
+const (
+	scalarKind mergeKind = iota
+	objectKind
+	arrayKind
+)
+
+// kindOf classifies n by its Kind, so an object keyed 0, 1, ... is merged as
+// an object. A root has no Kind of its own and is an array when ArrayLen
+// finds it keyed by index.
+func kindOf(n *Node) mergeKind {
+	switch {
+	case n.Kind == Array:
+		return arrayKind
+	case n.Kind == Object:
+		return objectKind
+	case IsRoot(n):
+		if _, ok := ArrayLen(n); ok {
+			return arrayKind
+		}
+		return objectKind
+	}
+	return scalarKind
+}
+
+// rule returns the rule for the values at path.
+func (m *merger) rule(path Path) MergeRule {
+	rule := MergeRule{Strategy: MergeDeep}
+	for _, r := range m.rules {
+		if matchesRule(r.Path, path) {
+			rule = r
+		}
+	}
+	return rule
+}
+
+// merge combines a with the later value b, both of which it may change, and
+// returns the result, which takes the key of a.
+func (m *merger) merge(a, b *Node, path Path) *Node {
+	b.Key = a.Key
+	kind := kindOf(a)
+	if kind != kindOf(b) || kind == scalarKind {
+		return m.replace(a, b)
+	}
+	rule := m.rule(path)
+	switch kind {
+	case objectKind:
+		if rule.Strategy == MergeReplace {
+			return m.replace(a, b)
+		}
+		for key, bc := range b.Children.Iter() {
+			ac := Child(a, key)
+			switch {
+			case ac == nil:
+			case rule.Strategy == MergeShallow:
+				bc = m.replace(ac, bc)
+			default:
+				bc = m.merge(ac, bc, append(path, PathElem{Key: key}))
+			}
+			attach(a, key, bc)
+		}
+		return a
+	default:
+		switch rule.Strategy {
+		case MergeAppend:
+			size, _ := ArrayLen(a)
+			for i, bc := range elements(b) {
+				attach(a, strconv.Itoa(size+i), bc)
+			}
+			return a
+		case MergeByKey:
+			for _, bc := range elements(b) {
+				size, _ := ArrayLen(a)
+				i := matchByKey(a, bc, rule.Key)
+				if i < 0 {
+					attach(a, strconv.Itoa(size), bc)
+					continue
+				}
+				key := strconv.Itoa(i)
+				attach(a, key, m.merge(Child(a, key), bc, append(path, PathElem{Key: key, IsIndex: true})))
+			}
+			return a
+		}
+		return m.replace(a, b)
+	}
+}
+
+// replace records that b takes the place of a, and the values a shadowed, and
+// returns b.
+func (m *merger) replace(a, b *Node) *Node {
+	m.prov.Shadowed[b.ID] = append([]*Node{a}, m.prov.Shadowed[a.ID]...)
+	delete(m.prov.Shadowed, a.ID)
+	return b
+}
+
+// elements returns the elements of the array n in order.
+func elements(n *Node) []*Node {
+	size, _ := ArrayLen(n)
+	out := make([]*Node, size)
+	for i := range out {
+		out[i] = Child(n, strconv.Itoa(i))
+	}
+	return out
+}
+
+// matchByKey returns the index of the element of the array a whose field key
+// has the same value as that of n, or -1 when there is none.
+func matchByKey(a, n *Node, key string) int {
+	field := Child(n, key)
+	if field == nil || !IsLeaf(field) {
+		return -1
+	}
+	for i, e := range elements(a) {
+		if f := Child(e, key); f != nil && IsLeaf(f) && f.Value == field.Value {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchesRule reports whether path is covered by the rule path p, in which
+// an index matches any index.
+func matchesRule(p, path Path) bool {
+	if len(p) != len(path) {
+		return false
+	}
+	for i, e := range p {
+		if e.IsIndex && path[i].IsIndex {
+			continue
+		}
+		if e.Key != path[i].Key {
+			return false
+		}
+	}
+	return true
+}
+
+// copyKeepingIDs returns a deep copy of n that keeps the IDs of the nodes it
+// copies. The copy has no parent.
+func copyKeepingIDs(n *Node) *Node {
+	c := Clone(n)
+	var keep func(c, n *Node)
+	keep = func(c, n *Node) {
+		c.ID = n.ID
+		for key, child := range n.Children.Iter() {
+			keep(Child(c, key), child)
+		}
+	}
+	keep(c, n)
+	return c
+}
+
+// summarize rebuilds the summaries of n and every container below it.
+func summarize(n *Node, repr ReprNode) {
+	for _, child := range n.Children.Iter() {
+		summarize(child, repr)
+	}
+	if !IsLeaf(n) && !IsRoot(n) {
+		n.Value = repr(n)
+	}
+}
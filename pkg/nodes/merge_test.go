@@ -0,0 +1,172 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	base := map[string]any{
+		"image": map[string]any{"repo": "app", "tag": "1.0"},
+		"env":   map[string]any{"A": "1", "B": "2"},
+		"args":  []any{"--a"},
+		"containers": []any{
+			map[string]any{"name": "app", "cpu": "1"},
+			map[string]any{"name": "sidecar", "cpu": "1"},
+		},
+		"labels": map[string]any{},
+	}
+	prod := map[string]any{
+		"image": map[string]any{"tag": "2.0"},
+		"env":   map[string]any{"B": "3"},
+		"args":  []any{"--b"},
+		"containers": []any{
+			map[string]any{"name": "sidecar", "cpu": "2"},
+			map[string]any{"name": "proxy"},
+		},
+		"labels": map[string]any{"team": "x"},
+	}
+	tests := []struct {
+		name  string
+		rules []string
+		want  map[string]any
+	}{
+		{
+			name: "deep objects, replaced arrays",
+			want: map[string]any{
+				"image":      map[string]any{"repo": "app", "tag": "2.0"},
+				"env":        map[string]any{"A": "1", "B": "3"},
+				"args":       []any{"--b"},
+				"containers": prod["containers"],
+				"labels":     map[string]any{"team": "x"},
+			},
+		},
+		{
+			name:  "shallow",
+			rules: []string{"=shallow"},
+			want: map[string]any{
+				"image":      map[string]any{"tag": "2.0"},
+				"env":        map[string]any{"B": "3"},
+				"args":       []any{"--b"},
+				"containers": prod["containers"],
+				"labels":     map[string]any{"team": "x"},
+			},
+		},
+		{
+			name:  "append and merge by key",
+			rules: []string{"args=append", "containers=merge-by-key:name"},
+			want: map[string]any{
+				"image": map[string]any{"repo": "app", "tag": "2.0"},
+				"env":   map[string]any{"A": "1", "B": "3"},
+				"args":  []any{"--a", "--b"},
+				"containers": []any{
+					map[string]any{"name": "app", "cpu": "1"},
+					map[string]any{"name": "sidecar", "cpu": "2"},
+					map[string]any{"name": "proxy"},
+				},
+				"labels": map[string]any{"team": "x"},
+			},
+		},
+		{
+			name:  "replace an object and matched elements",
+			rules: []string{"env=replace", "containers=merge-by-key:name", "containers[0]=replace"},
+			want: map[string]any{
+				"image": map[string]any{"repo": "app", "tag": "2.0"},
+				"env":   map[string]any{"B": "3"},
+				"args":  []any{"--b"},
+				"containers": []any{
+					map[string]any{"name": "app", "cpu": "1"},
+					map[string]any{"name": "sidecar", "cpu": "2"},
+					map[string]any{"name": "proxy"},
+				},
+				"labels": map[string]any{"team": "x"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules []MergeRule
+			for _, text := range tt.rules {
+				r, err := ParseMergeRule(text, DotSyntax)
+				require.NoError(t, err)
+				rules = append(rules, r)
+			}
+			a, b := New(base, 0, LeafValuesOnly), New(prod, 0, LeafValuesOnly)
+			before := []any{ToValue(a), ToValue(b)}
+			got := Merge([]*Node{a, b}, rules, LeafValuesOnly)
+			checkTree(t, got)
+			assert.Equal(t, tt.want, ToValue(got))
+			assert.Equal(t, before, []any{ToValue(a), ToValue(b)}, "the inputs are untouched")
+		})
+	}
+}
+
+func TestMergeKeepsIDs(t *testing.T) {
+	a := New(map[string]any{"x": "1", "y": "1"}, 0, LeafValuesOnly)
+	b := New(map[string]any{"y": "2"}, 0, LeafValuesOnly)
+	c := New(map[string]any{"z": []any{"3"}}, 0, LeafValuesOnly)
+	got := Merge([]*Node{a, b, c}, nil, nil)
+	assert.Equal(t, Child(a, "x").ID, Child(got, "x").ID)
+	assert.Equal(t, Child(b, "y").ID, Child(got, "y").ID)
+	assert.Equal(t, Child(Child(c, "z"), "0").ID, Child(Child(got, "z"), "0").ID)
+	assert.Empty(t, ToValue(Merge(nil, nil, nil)))
+}
+
+func TestMergeKinds(t *testing.T) {
+	a := New(map[string]any{"v": map[string]any{"k": "1"}, "w": "s"}, 0, LeafValuesOnly)
+	b := New(map[string]any{"v": []any{"1"}, "w": map[string]any{"k": "2"}}, 0, LeafValuesOnly)
+	got := Merge([]*Node{a, b}, []MergeRule{{Path: Path{{Key: "v"}}, Strategy: MergeAppend}}, nil)
+	assert.Equal(t, map[string]any{"v": []any{"1"}, "w": map[string]any{"k": "2"}}, ToValue(got))
+}
+
+func TestMergeNumericKeys(t *testing.T) {
+	base := New(map[string]any{"ports": map[string]any{"0": "http", "1": "https"}}, 0, LeafValuesOnly)
+	prod := New(map[string]any{"ports": map[string]any{"0": "grpc"}}, 0, LeafValuesOnly)
+	named := New(map[string]any{"ports": map[string]any{"0": "grpc", "name": "z"}}, 0, LeafValuesOnly)
+	assert.Equal(t, map[string]any{"ports": map[string]any{"0": "grpc", "1": "https"}}, ToValue(Merge([]*Node{base, prod}, nil, nil)))
+	assert.Equal(t, map[string]any{"ports": map[string]any{"0": "grpc", "1": "https", "name": "z"}}, ToValue(Merge([]*Node{base, named}, nil, nil)))
+}
+
+func TestParseMergeRule(t *testing.T) {
+	r, err := ParseMergeRule(".spec.containers=merge-by-key:name", JqSyntax)
+	require.NoError(t, err)
+	assert.Equal(t, MergeRule{Path: Path{{Key: "spec"}, {Key: "containers"}}, Strategy: MergeByKey, Key: "name"}, r)
+	r, err = ParseMergeRule("a\\=b=Append", DotSyntax)
+	require.NoError(t, err)
+	assert.Equal(t, MergeRule{Path: Path{{Key: "a=b"}}, Strategy: MergeAppend}, r)
+
+	for _, text := range []string{"spec", "a=merge", "a=merge-by-key", "a=merge-by-key:", "a=append:name", "a[=deep"} {
+		_, err := ParseMergeRule(text, DotSyntax)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestMergeWithProvenance(t *testing.T) {
+	a := New(map[string]any{"x": "1", "y": "1", "env": map[string]any{"A": "1"}}, 0, LeafValuesOnly)
+	b := New(map[string]any{"y": "2", "env": map[string]any{"B": "2"}}, 0, LeafValuesOnly)
+	c := New(map[string]any{"y": "3", "env": "none"}, 0, LeafValuesOnly)
+	got, prov := MergeWithProvenance([]*Node{a, b, c}, nil, LeafValuesOnly)
+	assert.Equal(t, map[string]any{"x": "1", "y": "3", "env": "none"}, ToValue(got))
+
+	for key, want := range map[string]int{"x": 0, "y": 2, "env": 2} {
+		i, ok := prov.Source(Child(got, key))
+		assert.True(t, ok, key)
+		assert.Equal(t, want, i, key)
+	}
+
+	var values []string
+	for _, n := range prov.Shadowed[Child(got, "y").ID] {
+		i, _ := prov.Source(n)
+		values = append(values, n.Value)
+		assert.Equal(t, 2-len(values), i)
+	}
+	assert.Equal(t, []string{"2", "1"}, values, "latest first")
+
+	env := prov.Shadowed[Child(got, "env").ID]
+	require.Len(t, env, 1)
+	assert.Equal(t, map[string]any{"A": "1", "B": "2"}, ToValue(env[0]), "the merged value is shadowed")
+	assert.Equal(t, "1 2", env[0].Value, "and summarized")
+	assert.Empty(t, prov.Shadowed[Child(got, "x").ID])
+}
@@ -3,7 +3,9 @@
 package nodes
 
 import (
+	"crypto/rand"
 	"strconv"
+	"time"
 
 	"github.com/crosleyzack/wndr/pkg/omap"
 	"github.com/google/uuid"
@@ -23,8 +25,25 @@ type Node struct {
 	Parent *Node
 	// Expand indicates if the node is expanded
 	Expand bool
+	// Kind is the type of value the node holds, which tells an empty object
+	// or array from a string that reads {} or []
+	Kind Kind
+	// hash caches Hash; nil until it is first computed and after a change
+	hash *Hash
 }
 
+// Kind is the type of value a node holds.
+type Kind uint8
+
+const (
+	// Scalar is a string, number, boolean or null
+	Scalar Kind = iota
+	// Object is an object, whose children are keyed by name
+	Object
+	// Array is an array, whose children are keyed by index
+	Array
+)
+
 // Equal returns true if the two nodes are equal
 func (n *Node) Equal(other *Node) bool {
 	return n.ID == other.ID
@@ -83,7 +102,63 @@ func ToMap(nodes ...*Node) map[string]any {
 	return m
 }
 
+// ToValue converts a node back to plain data: a scalar becomes its string
+// value, an array (see ArrayLen) a []any in index order, and an object a
+// map[string]any, however empty.
+func ToValue(n *Node) any {
+	return toValue(n, func(n *Node) any { return n.Value })
+}
+
+// TypedValue is ToValue with scalars typed as they read: null as nil, true and
+// false as bools, and numbers as int64 or float64.
+func TypedValue(n *Node) any {
+	return toValue(n, typedScalar)
+}
+
+// toValue converts n to plain data with its scalars converted by scalar.
+func toValue(n *Node, scalar func(*Node) any) any {
+	if IsLeaf(n) {
+		switch n.Kind {
+		case Object:
+			return map[string]any{}
+		case Array:
+			return []any{}
+		}
+		return scalar(n)
+	}
+	if size, ok := ArrayLen(n); ok {
+		arr := make([]any, size)
+		for i := range arr {
+			arr[i] = toValue(Child(n, strconv.Itoa(i)), scalar)
+		}
+		return arr
+	}
+	m := make(map[string]any, n.Children.Len())
+	for key, child := range n.Children.Iter() {
+		m[key] = toValue(child, scalar)
+	}
+	return m
+}
+
+// typedScalar returns the value of the scalar n typed as getJSONType reads it.
+func typedScalar(n *Node) any {
+	switch getJSONType(n) {
+	case "null":
+		return nil
+	case "boolean":
+		return n.Value == "true"
+	case "integer":
+		i, _ := strconv.ParseInt(n.Value, 10, 64)
+		return i
+	case "number":
+		f, _ := strconv.ParseFloat(n.Value, 64)
+		return f
+	}
+	return n.Value
+}
+
 func addChild(node, child *Node) {
+	Invalidate(node)
 	child.Parent = node
 	node.Children.Put(child.Key, child)
 }
@@ -92,32 +167,16 @@ func addChild(node, child *Node) {
 // root: it is not rendered, so the JSON's top-level entries are its children at
 // display layer 0.
 func New(json map[string]any, displayLayers uint, repr ReprNode) *Node {
-	root := &Node{
-		ID:       uuid.New(),
-		Children: omap.New[string, *Node](),
-		Expand:   true,
-	}
+	b := newBuilder(displayLayers, repr)
+	root := b.node()
+	root.Children = omap.New[string, *Node]()
+	root.Expand = true
 	for k, v := range json {
-		addChild(root, NewNode(k, v, 0, displayLayers, repr))
+		addChild(root, b.build(root, b.key(k), v, 0))
 	}
 	return root
 }
 
-// makeTree creates a node for a JSON object at the given display layer. Its
-// children are one layer deeper.
-func makeTree(json map[string]any, layer uint, displayLayers uint, repr ReprNode) *Node {
-	tree := &Node{
-		ID:       uuid.New(),
-		Children: omap.New[string, *Node](),
-		Expand:   layer < displayLayers,
-	}
-	for k, v := range json {
-		node := NewNode(k, v, layer+1, displayLayers, repr)
-		addChild(tree, node)
-	}
-	return tree
-}
-
 // IsArray checks if a node represents an array (all children have numeric keys)
 func IsArray(n *Node) bool {
 	if IsLeaf(n) {
@@ -131,6 +190,32 @@ func IsArray(n *Node) bool {
 	return true
 }
 
+// ArrayLen returns the length of n when it is an array: when its Kind is
+// Array, or, for a root, which has no Kind of its own, when its children are
+// keyed exactly 0 through n-1, as a document whose top-level value is an array
+// is. An empty array, which is a leaf, has length 0.
+func ArrayLen(n *Node) (int, bool) {
+	if IsLeaf(n) {
+		return 0, n.Kind == Array
+	}
+	if !IsRoot(n) || n.Kind != Scalar {
+		if n.Kind != Array {
+			return 0, false
+		}
+		return n.Children.Len(), true
+	}
+	if !IsArray(n) {
+		return 0, false
+	}
+	size := n.Children.Len()
+	for i := range size {
+		if Child(n, strconv.Itoa(i)) == nil {
+			return 0, false
+		}
+	}
+	return size, true
+}
+
 // IsLeafArray checks if a node represents an array (all children have numeric keys)
 // and all children are leaf nodes
 func IsLeafArray(n *Node) bool {
@@ -150,38 +235,113 @@ func IsLeafArray(n *Node) bool {
 
 // NewNode creates a new node from a key and value
 func NewNode(key string, value any, layer uint, displayLayers uint, repr ReprNode) *Node {
-	node := &Node{
-		ID:     uuid.New(),
-		Key:    key,
-		Expand: layer < displayLayers,
+	return newBuilder(displayLayers, repr).build(nil, key, value, layer)
+}
+
+// blockSize is the number of nodes a builder allocates at once
+const blockSize = 256
+
+// builder creates the nodes of a tree. It allocates them, and their IDs, in
+// blocks rather than one at a time, and interns keys so the many objects of an
+// array that share keys share their strings too.
+type builder struct {
+	displayLayers uint
+	repr          ReprNode
+	block         []Node
+	// random holds the random bytes for the IDs of the nodes in block
+	random  []byte
+	keys    map[string]string
+	indices []string
+}
+
+func newBuilder(displayLayers uint, repr ReprNode) *builder {
+	return &builder{
+		displayLayers: displayLayers,
+		repr:          repr,
+		keys:          map[string]string{},
 	}
+}
+
+// node returns a new node with a random ID, a version 4 UUID as made by
+// uuid.New.
+func (b *builder) node() *Node {
+	if len(b.block) == 0 {
+		b.block = make([]Node, blockSize)
+		b.random = make([]byte, blockSize*len(uuid.UUID{}))
+		if _, err := rand.Read(b.random); err != nil {
+			// as uuid.New, which panics when the random source fails
+			panic(err)
+		}
+	}
+	n := &b.block[0]
+	b.block = b.block[1:]
+	b.random = b.random[copy(n.ID[:], b.random):]
+	n.ID[6] = n.ID[6]&0x0f | 0x40 // version 4
+	n.ID[8] = n.ID[8]&0x3f | 0x80 // RFC 4122 variant
+	return n
+}
+
+// key returns the interned copy of k.
+func (b *builder) key(k string) string {
+	if interned, ok := b.keys[k]; ok {
+		return interned
+	}
+	b.keys[k] = k
+	return k
+}
+
+// index returns the key of the array element at i.
+func (b *builder) index(i int) string {
+	for len(b.indices) <= i {
+		b.indices = append(b.indices, strconv.Itoa(len(b.indices)))
+	}
+	return b.indices[i]
+}
+
+// build creates the node for key and value, to be added to parent, at the
+// given display layer. Its children are one layer deeper. The node's parent is
+// set from the start so repr sees the path to it.
+func (b *builder) build(parent *Node, key string, value any, layer uint) *Node {
+	node := b.node()
+	node.Key = key
+	node.Parent = parent
+	node.Expand = layer < b.displayLayers
 	switch v := value.(type) {
 	case string:
 		node.Value = v
 	case int:
 		node.Value = strconv.FormatInt(int64(v), 10)
+	case int64:
+		// YAML and TOML decode integers as int64 or uint64
+		node.Value = strconv.FormatInt(v, 10)
+	case uint64:
+		node.Value = strconv.FormatUint(v, 10)
 	case float64:
 		node.Value = strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		// TOML datetimes
+		node.Value = v.Format(time.RFC3339Nano)
 	case bool:
 		node.Value = strconv.FormatBool(v)
 	case []any:
-		node.Children = omap.New[string, *Node]()
+		node.Kind = Array
+		node.Children = omap.New[string, *Node](omap.WithCapacity(len(v)))
 		for i, child := range v {
-			n := NewNode(strconv.FormatUint(uint64(i), 10), child, layer+1, displayLayers, repr)
-			addChild(node, n)
+			addChild(node, b.build(node, b.index(i), child, layer+1))
 		}
 		node.Value = "[]"
 		if node.Children.Len() > 0 {
-			node.Value = repr(node)
+			node.Value = b.repr(node)
 		}
 	case map[string]any:
-		// the map node itself sits at `layer`; makeTree places its children one
-		// layer deeper.
-		node = makeTree(v, layer, displayLayers, repr)
-		node.Key = key
+		node.Kind = Object
+		node.Children = omap.New[string, *Node](omap.WithCapacity(len(v)))
+		for k, child := range v {
+			addChild(node, b.build(node, b.key(k), child, layer+1))
+		}
 		node.Value = "{}"
 		if node.Children.Len() > 0 {
-			node.Value = repr(node)
+			node.Value = b.repr(node)
 		}
 	}
 	return node
@@ -2,6 +2,7 @@ package nodes
 
 import (
 	"testing"
+	"time"
 
 	"github.com/crosleyzack/wndr/pkg/omap"
 	"github.com/stretchr/testify/assert"
@@ -26,6 +27,24 @@ func TestMakeNode(t *testing.T) {
 			value:    42,
 			expected: Node{Key: "number", Value: "42", Expand: true},
 		},
+		{
+			name:     "int64 value",
+			key:      "number",
+			value:    int64(-7),
+			expected: Node{Key: "number", Value: "-7", Expand: true},
+		},
+		{
+			name:     "uint64 value",
+			key:      "number",
+			value:    uint64(18446744073709551615),
+			expected: Node{Key: "number", Value: "18446744073709551615", Expand: true},
+		},
+		{
+			name:     "time value",
+			key:      "at",
+			value:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			expected: Node{Key: "at", Value: "2024-05-01T12:00:00Z", Expand: true},
+		},
 		{
 			name:     "float value",
 			key:      "float",
@@ -199,6 +218,41 @@ func TestToMap(t *testing.T) {
 	}
 }
 
+func TestToValue(t *testing.T) {
+	data := map[string]any{
+		"name": "alice",
+		"tags": []any{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"},
+		"pets": []any{map[string]any{"kind": "cat"}},
+	}
+	root := New(data, 0, EmptyRepr)
+	assert.Equal(t, "alice", ToValue(Child(root, "name")))
+	// arrays come back in index order, not the lexical order of their keys
+	assert.Equal(t, data["tags"], ToValue(Child(root, "tags")))
+	assert.Equal(t, data, ToValue(root))
+
+	// objects with numeric keys that are not 0 through n-1 stay objects
+	ports := map[string]any{"80": "http", "443": "https"}
+	assert.Equal(t, ports, ToValue(New(ports, 0, EmptyRepr)))
+	sparse := map[string]any{"0": "a", "2": "c"}
+	assert.Equal(t, sparse, ToValue(New(sparse, 0, EmptyRepr)))
+
+	// empty arrays and objects stay arrays and objects
+	empty := map[string]any{"list": []any{}, "map": map[string]any{}}
+	assert.Equal(t, empty, ToValue(New(empty, 0, EmptyRepr)))
+}
+
+func TestTypedValue(t *testing.T) {
+	data := map[string]any{
+		"count": 3, "ratio": 0.5, "ok": true, "none": nil, "name": "x",
+		"list": []any{1, "a"}, "empty": []any{},
+	}
+	want := map[string]any{
+		"count": int64(3), "ratio": 0.5, "ok": true, "none": nil, "name": "x",
+		"list": []any{int64(1), "a"}, "empty": []any{},
+	}
+	assert.Equal(t, want, TypedValue(New(data, 0, EmptyRepr)))
+}
+
 // childMap builds a Children map keyed by each node's Key, matching how the
 // package stores children internally.
 func childMap(children ...*Node) omap.OMap[string, *Node] {
@@ -0,0 +1,545 @@
+package nodes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PathSyntax is a notation for writing a Path as text.
+type PathSyntax int
+
+const (
+	// DotSyntax joins keys with dots and writes array indices in brackets:
+	// spec.containers[0].image. Dots, brackets and backslashes within keys are
+	// escaped with a backslash.
+	DotSyntax PathSyntax = iota
+	// JSONPathSyntax is an RFC 9535 JSONPath selecting exactly one node:
+	// $.spec.containers[0]['image-name'].
+	JSONPathSyntax
+	// PointerSyntax is an RFC 6901 JSON Pointer: /spec/containers/0/image.
+	PointerSyntax
+	// JqSyntax is a jq path expression: .spec.containers[0]["image-name"].
+	JqSyntax
+	// YqSyntax is a yq path expression: .spec.containers[0]."image-name".
+	YqSyntax
+)
+
+var pathSyntaxNames = map[PathSyntax]string{
+	DotSyntax:      "dot",
+	JSONPathSyntax: "jsonpath",
+	PointerSyntax:  "pointer",
+	JqSyntax:       "jq",
+	YqSyntax:       "yq",
+}
+
+func (s PathSyntax) String() string {
+	if name, ok := pathSyntaxNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("PathSyntax(%d)", int(s))
+}
+
+// GetPathSyntaxes returns the names accepted by ParsePathSyntax.
+func GetPathSyntaxes() []string {
+	return []string{"dot", "jsonpath", "pointer", "jq", "yq"}
+}
+
+// ParsePathSyntax returns the syntax with the given name, as listed by
+// GetPathSyntaxes.
+func ParsePathSyntax(name string) (PathSyntax, error) {
+	for s, n := range pathSyntaxNames {
+		if strings.EqualFold(n, name) {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown path syntax %q: expected one of %s", name, strings.Join(GetPathSyntaxes(), ", "))
+}
+
+// PathElem is one step of a Path: an object key, or an array index when
+// IsIndex is set.
+type PathElem struct {
+	Key     string
+	IsIndex bool
+}
+
+// Path is the location of a node as the steps from the root to it. Unlike the
+// []string from GetPathToNode, it records which steps are array indices so
+// they can be written as such.
+type Path []PathElem
+
+// SyntaxError reports text that could not be parsed as a path, or as a query
+// or filter built on paths, and where in the text the problem is.
+type SyntaxError struct {
+	// Kind is what the text was parsed as, such as "dot path" or "filter"
+	Kind string
+	// Text is the text as given
+	Text string
+	// Offset is the byte offset in Text of the problem
+	Offset int
+	// Msg describes the problem
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid %s %q at offset %d: %s", e.Kind, e.Text, e.Offset, e.Msg)
+}
+
+// PathTo returns the path from the root of n's tree to n. Children of a node
+// parsed from an array, as reported by ArrayLen, are index steps.
+func PathTo(n *Node) Path {
+	var p Path
+	for cur := n; cur != nil && cur.Parent != nil; cur = cur.Parent {
+		_, isIndex := ArrayLen(cur.Parent)
+		p = append(p, PathElem{Key: cur.Key, IsIndex: isIndex})
+	}
+	for i, j := 0, len(p)-1; i < j; i, j = i+1, j-1 {
+		p[i], p[j] = p[j], p[i]
+	}
+	return p
+}
+
+// Keys returns the key of each step, as used by GetNodeFromPath.
+func (p Path) Keys() []string {
+	keys := make([]string, len(p))
+	for i, e := range p {
+		keys[i] = e.Key
+	}
+	return keys
+}
+
+// Resolve returns the node at p under root, or nil when there is none.
+func (p Path) Resolve(root *Node) *Node {
+	n, rem := GetNodeFromPath(root, p.Keys())
+	if len(rem) > 0 {
+		return nil
+	}
+	return n
+}
+
+// String formats p in DotSyntax.
+func (p Path) String() string {
+	return p.Format(DotSyntax)
+}
+
+// plainKey matches keys that may be written after a dot without quoting in
+// JSONPath, jq and yq.
+var plainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Format writes p in the given syntax. The root is written as the empty string
+// in DotSyntax and PointerSyntax, $ in JSONPathSyntax and . in JqSyntax and
+// YqSyntax. DotSyntax has no way to quote a key, so a path whose first key is
+// empty is written the same as the root.
+func (p Path) Format(s PathSyntax) string {
+	var b strings.Builder
+	switch s {
+	case JSONPathSyntax:
+		b.WriteString("$")
+	case JqSyntax, YqSyntax:
+		if len(p) == 0 {
+			return "."
+		}
+	}
+	for i, e := range p {
+		switch s {
+		case DotSyntax:
+			switch {
+			case e.IsIndex:
+				b.WriteString("[" + e.Key + "]")
+			default:
+				if i > 0 {
+					b.WriteByte('.')
+				}
+				b.WriteString(escapeDotKey(e.Key))
+			}
+		case JSONPathSyntax:
+			switch {
+			case e.IsIndex:
+				b.WriteString("[" + e.Key + "]")
+			case plainKey.MatchString(e.Key):
+				b.WriteString("." + e.Key)
+			default:
+				b.WriteString("[" + singleQuote(e.Key) + "]")
+			}
+		case PointerSyntax:
+			b.WriteByte('/')
+			b.WriteString(strings.ReplaceAll(strings.ReplaceAll(e.Key, "~", "~0"), "/", "~1"))
+		case JqSyntax:
+			if i == 0 && (e.IsIndex || !plainKey.MatchString(e.Key)) {
+				// a leading [ would be an array literal rather than a path
+				b.WriteByte('.')
+			}
+			switch {
+			case e.IsIndex:
+				b.WriteString("[" + e.Key + "]")
+			case plainKey.MatchString(e.Key):
+				b.WriteString("." + e.Key)
+			default:
+				b.WriteString("[" + doubleQuote(e.Key) + "]")
+			}
+		case YqSyntax:
+			if i == 0 && e.IsIndex {
+				b.WriteByte('.')
+			}
+			switch {
+			case e.IsIndex:
+				b.WriteString("[" + e.Key + "]")
+			case plainKey.MatchString(e.Key):
+				b.WriteString("." + e.Key)
+			default:
+				b.WriteString("." + doubleQuote(e.Key))
+			}
+		}
+	}
+	return b.String()
+}
+
+func escapeDotKey(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		if strings.ContainsRune(`.[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// singleQuote writes key as a JSONPath single quoted string.
+func singleQuote(key string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range key {
+		switch {
+		case r == '\'' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20:
+			fmt.Fprintf(&b, "\\u%04x", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// doubleQuote writes key as a JSON string, as read by jq and yq.
+func doubleQuote(key string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	// encoding a string cannot fail
+	_ = enc.Encode(key)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// ParsePath reads text written in the given syntax. Steps written as array
+// indices, such as [0], are index steps; JSON Pointer cannot tell indices from
+// keys, so its steps are all keys. Either way the path resolves the same.
+func ParsePath(text string, s PathSyntax) (Path, error) {
+	p := &pathParser{src: text, syntax: s}
+	switch s {
+	case DotSyntax:
+		return p.dot()
+	case JSONPathSyntax:
+		return p.jsonPath()
+	case PointerSyntax:
+		return p.pointer()
+	case JqSyntax, YqSyntax:
+		return p.jq()
+	}
+	return nil, fmt.Errorf("unknown path syntax %v", s)
+}
+
+type pathParser struct {
+	src    string
+	syntax PathSyntax
+	pos    int
+}
+
+func (p *pathParser) errorf(format string, args ...any) error {
+	return &SyntaxError{Kind: p.syntax.String() + " path", Text: p.src, Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *pathParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *pathParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *pathParser) dot() (Path, error) {
+	path := Path{}
+	if p.src == "" {
+		return path, nil
+	}
+	var key strings.Builder
+	// pending is set while a key is being read, so an empty key between two
+	// dots is still a step
+	pending := true
+	flush := func() {
+		if pending {
+			path = append(path, PathElem{Key: key.String()})
+		}
+		key.Reset()
+		pending = false
+	}
+	for !p.eof() {
+		c := p.src[p.pos]
+		switch c {
+		case '\\':
+			p.pos++
+			if p.eof() {
+				return nil, p.errorf("trailing backslash")
+			}
+			key.WriteByte(p.src[p.pos])
+			p.pos++
+		case '.':
+			flush()
+			pending = true
+			p.pos++
+		case '[':
+			if p.pos == 0 {
+				// a path starting with an index has no key before it
+				pending = false
+			}
+			flush()
+			i, err := p.index()
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, PathElem{Key: i, IsIndex: true})
+		case ']':
+			return nil, p.errorf("unexpected ]")
+		default:
+			key.WriteByte(c)
+			pending = true
+			p.pos++
+		}
+	}
+	flush()
+	return path, nil
+}
+
+// index reads [n], returning n.
+func (p *pathParser) index() (string, error) {
+	p.pos++ // [
+	start := p.pos
+	for !p.eof() && p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+	if p.pos == start || p.peek() != ']' {
+		return "", p.errorf("expected an array index")
+	}
+	digits := p.src[start:p.pos]
+	p.pos++ // ]
+	// normalize leading zeros so [01] finds the element keyed "1"
+	i, err := strconv.Atoi(digits)
+	if err != nil {
+		return "", p.errorf("invalid array index %s", digits)
+	}
+	return strconv.Itoa(i), nil
+}
+
+func (p *pathParser) jsonPath() (Path, error) {
+	if p.peek() != '$' {
+		return nil, p.errorf("path must start with $")
+	}
+	p.pos++
+	path := Path{}
+	for !p.eof() {
+		switch p.peek() {
+		case '.':
+			p.pos++
+			start := p.pos
+			for !p.eof() && p.peek() != '.' && p.peek() != '[' {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, p.errorf("expected a name")
+			}
+			path = append(path, PathElem{Key: p.src[start:p.pos]})
+		case '[':
+			if q := p.src[min(p.pos+1, len(p.src)-1)]; q == '\'' || q == '"' {
+				p.pos++
+				key, err := p.quoted()
+				if err != nil {
+					return nil, err
+				}
+				if p.peek() != ']' {
+					return nil, p.errorf("expected ]")
+				}
+				p.pos++
+				path = append(path, PathElem{Key: key})
+				continue
+			}
+			i, err := p.index()
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, PathElem{Key: i, IsIndex: true})
+		default:
+			return nil, p.errorf("unexpected %q", p.peek())
+		}
+	}
+	return path, nil
+}
+
+// quoted reads a single or double quoted string with JSON style escapes.
+func (p *pathParser) quoted() (string, error) {
+	quote := p.peek()
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.eof() {
+			p.pos = start
+			return "", p.errorf("unterminated string")
+		}
+		c := p.src[p.pos]
+		p.pos++
+		switch c {
+		case quote:
+			return b.String(), nil
+		case '\\':
+			if p.eof() {
+				return "", p.errorf("unterminated string")
+			}
+			e := p.src[p.pos]
+			p.pos++
+			switch e {
+			case '\'', '"', '\\', '/':
+				b.WriteByte(e)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				// let the JSON decoder handle \u escapes and surrogate pairs
+				end := p.pos + 4
+				if end+6 <= len(p.src) && p.src[end:end+2] == `\u` {
+					end += 6
+				}
+				if end > len(p.src) {
+					return "", p.errorf("invalid \\u escape")
+				}
+				var s string
+				if err := json.Unmarshal([]byte(`"\u`+p.src[p.pos:end]+`"`), &s); err != nil {
+					return "", p.errorf("invalid \\u escape")
+				}
+				b.WriteString(s)
+				p.pos = end
+			default:
+				return "", p.errorf("invalid escape \\%c", e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func (p *pathParser) pointer() (Path, error) {
+	text := p.src
+	if rest, ok := strings.CutPrefix(text, "#"); ok {
+		// URI fragment form, percent-encoded
+		decoded, err := url.PathUnescape(rest)
+		if err != nil {
+			p.pos = 1
+			return nil, p.errorf("invalid percent-encoding")
+		}
+		text = decoded
+	}
+	path := Path{}
+	if text == "" {
+		return path, nil
+	}
+	if text[0] != '/' {
+		return nil, p.errorf("pointer must start with /")
+	}
+	for _, tok := range strings.Split(text[1:], "/") {
+		// ~ may only be followed by 0 or 1
+		for j := 0; j < len(tok); j++ {
+			if tok[j] == '~' && (j+1 == len(tok) || (tok[j+1] != '0' && tok[j+1] != '1')) {
+				return nil, p.errorf("~ must be followed by 0 or 1")
+			}
+		}
+		// ~1 is unescaped first so "~01" becomes "~1" rather than "/"
+		path = append(path, PathElem{Key: strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")})
+	}
+	return path, nil
+}
+
+// jq reads jq and yq paths, which accept the same forms: .name, ."name",
+// ["name"], .["name"] and [0].
+func (p *pathParser) jq() (Path, error) {
+	path := Path{}
+	if p.peek() != '.' {
+		return nil, p.errorf("path must start with .")
+	}
+	if p.src == "." {
+		return path, nil
+	}
+	for !p.eof() {
+		if p.peek() == '.' {
+			p.pos++
+			switch p.peek() {
+			case '[':
+				// .[...] is the same as [...]
+				continue
+			case '"':
+				key, err := p.quoted()
+				if err != nil {
+					return nil, err
+				}
+				path = append(path, PathElem{Key: key})
+				continue
+			}
+			start := p.pos
+			for !p.eof() && p.peek() != '.' && p.peek() != '[' {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, p.errorf("expected a name")
+			}
+			path = append(path, PathElem{Key: p.src[start:p.pos]})
+			continue
+		}
+		if p.peek() != '[' {
+			return nil, p.errorf("unexpected %q", p.peek())
+		}
+		if p.src[min(p.pos+1, len(p.src)-1)] == '"' {
+			p.pos++
+			key, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			if p.peek() != ']' {
+				return nil, p.errorf("expected ]")
+			}
+			p.pos++
+			path = append(path, PathElem{Key: key})
+			continue
+		}
+		i, err := p.index()
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, PathElem{Key: i, IsIndex: true})
+	}
+	return path, nil
+}
@@ -0,0 +1,168 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pathSyntaxTree() *Node {
+	return New(map[string]any{
+		"spec": map[string]any{
+			"containers": []any{
+				map[string]any{"image-name": "app", "a.b": "dotted"},
+			},
+		},
+		"a/b":      map[string]any{"m~n": "pointer"},
+		`q"s'\`:    "quotes",
+		"":         "empty",
+		"with [x]": "brackets",
+	}, 0, EmptyRepr)
+}
+
+func TestPathFormat(t *testing.T) {
+	root := pathSyntaxTree()
+	container := Child(Child(Child(root, "spec"), "containers"), "0")
+	tests := []struct {
+		name string
+		node *Node
+		want map[PathSyntax]string
+	}{
+		{
+			name: "root",
+			node: root,
+			want: map[PathSyntax]string{DotSyntax: "", JSONPathSyntax: "$", PointerSyntax: "", JqSyntax: ".", YqSyntax: "."},
+		},
+		{
+			name: "array index",
+			node: Child(container, "image-name"),
+			want: map[PathSyntax]string{
+				DotSyntax:      "spec.containers[0].image-name",
+				JSONPathSyntax: "$.spec.containers[0]['image-name']",
+				PointerSyntax:  "/spec/containers/0/image-name",
+				JqSyntax:       `.spec.containers[0]["image-name"]`,
+				YqSyntax:       `.spec.containers[0]."image-name"`,
+			},
+		},
+		{
+			name: "dotted key",
+			node: Child(container, "a.b"),
+			want: map[PathSyntax]string{
+				DotSyntax:      `spec.containers[0].a\.b`,
+				JSONPathSyntax: "$.spec.containers[0]['a.b']",
+				PointerSyntax:  "/spec/containers/0/a.b",
+				JqSyntax:       `.spec.containers[0]["a.b"]`,
+				YqSyntax:       `.spec.containers[0]."a.b"`,
+			},
+		},
+		{
+			name: "pointer escapes",
+			node: Child(Child(root, "a/b"), "m~n"),
+			want: map[PathSyntax]string{
+				DotSyntax:      "a/b.m~n",
+				JSONPathSyntax: "$['a/b']['m~n']",
+				PointerSyntax:  "/a~1b/m~0n",
+				JqSyntax:       `.["a/b"]["m~n"]`,
+				YqSyntax:       `."a/b"."m~n"`,
+			},
+		},
+		{
+			name: "quotes",
+			node: Child(root, `q"s'\`),
+			want: map[PathSyntax]string{
+				DotSyntax:      `q"s'\\`,
+				JSONPathSyntax: `$['q"s\'\\']`,
+				PointerSyntax:  `/q"s'\`,
+				JqSyntax:       `.["q\"s'\\"]`,
+				YqSyntax:       `."q\"s'\\"`,
+			},
+		},
+		{
+			name: "brackets",
+			node: Child(root, "with [x]"),
+			want: map[PathSyntax]string{
+				DotSyntax:      `with \[x\]`,
+				JSONPathSyntax: "$['with [x]']",
+				PointerSyntax:  "/with [x]",
+				JqSyntax:       `.["with [x]"]`,
+				YqSyntax:       `."with [x]"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PathTo(tt.node)
+			for syntax, want := range tt.want {
+				got := p.Format(syntax)
+				assert.Equal(t, want, got, syntax)
+
+				// every formatted path parses back to a path to the same node
+				parsed, err := ParsePath(got, syntax)
+				require.NoError(t, err, "%v %s", syntax, got)
+				assert.Same(t, tt.node, parsed.Resolve(root), "%v %s", syntax, got)
+			}
+		})
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		text   string
+		syntax PathSyntax
+		want   Path
+	}{
+		{text: "a..b", syntax: DotSyntax, want: Path{{Key: "a"}, {Key: ""}, {Key: "b"}}},
+		{text: "[01]", syntax: DotSyntax, want: Path{{Key: "1", IsIndex: true}}},
+		{text: `$["double"]`, syntax: JSONPathSyntax, want: Path{{Key: "double"}}},
+		{text: `$['é']`, syntax: JSONPathSyntax, want: Path{{Key: "é"}}},
+		{text: "/", syntax: PointerSyntax, want: Path{{Key: ""}}},
+		// ~01 is ~ followed by 1, not /
+		{text: "/~01", syntax: PointerSyntax, want: Path{{Key: "~1"}}},
+		{text: "#/c%25d", syntax: PointerSyntax, want: Path{{Key: "c%d"}}},
+		{text: `.a.["b"].[2]`, syntax: JqSyntax, want: Path{{Key: "a"}, {Key: "b"}, {Key: "2", IsIndex: true}}},
+		{text: `."x y"[0]`, syntax: YqSyntax, want: Path{{Key: "x y"}, {Key: "0", IsIndex: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.syntax.String()+" "+tt.text, func(t *testing.T) {
+			got, err := ParsePath(tt.text, tt.syntax)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	tests := []struct {
+		text   string
+		syntax PathSyntax
+	}{
+		{text: `a\`, syntax: DotSyntax},
+		{text: "a]", syntax: DotSyntax},
+		{text: "a[x]", syntax: DotSyntax},
+		{text: "a", syntax: JSONPathSyntax},
+		{text: "$['a", syntax: JSONPathSyntax},
+		{text: "$.", syntax: JSONPathSyntax},
+		{text: "foo", syntax: PointerSyntax},
+		{text: "/a~", syntax: PointerSyntax},
+		{text: "/a~x", syntax: PointerSyntax},
+		{text: "a", syntax: JqSyntax},
+		{text: `.["a"`, syntax: JqSyntax},
+		{text: `."\q"`, syntax: YqSyntax},
+	}
+	for _, tt := range tests {
+		_, err := ParsePath(tt.text, tt.syntax)
+		var perr *SyntaxError
+		assert.ErrorAs(t, err, &perr, "%v %s", tt.syntax, tt.text)
+	}
+}
+
+func TestParsePathSyntax(t *testing.T) {
+	for _, name := range GetPathSyntaxes() {
+		s, err := ParsePathSyntax(name)
+		require.NoError(t, err)
+		assert.Equal(t, name, s.String())
+	}
+	_, err := ParsePathSyntax("xpath")
+	assert.Error(t, err)
+}
@@ -0,0 +1,726 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/crosleyzack/wndr/pkg/nodes"
+)
+
+// path is a parsed JSONPath: a start node followed by segments, each applied
+// to every node matched so far.
+type path struct {
+	// relative paths start at the current filter node (@) rather than the
+	// root ($)
+	relative bool
+	segments []segment
+}
+
+// segment applies its selectors to a node's children, or with descendant set
+// to the children of the node and of every node below it.
+type segment struct {
+	descendant bool
+	selectors  []selector
+}
+
+type selector interface {
+	// selectFrom appends the children of n it selects to out
+	selectFrom(n, root *nodes.Node, out []*nodes.Node) []*nodes.Node
+}
+
+func (p path) eval(cur, root *nodes.Node) []*nodes.Node {
+	matched := []*nodes.Node{root}
+	if p.relative {
+		matched = []*nodes.Node{cur}
+	}
+	for _, seg := range p.segments {
+		var next []*nodes.Node
+		for _, n := range matched {
+			targets := []*nodes.Node{n}
+			if seg.descendant {
+				targets = descendants(n, nil)
+			}
+			for _, t := range targets {
+				for _, sel := range seg.selectors {
+					next = sel.selectFrom(t, root, next)
+				}
+			}
+		}
+		matched = next
+	}
+	return matched
+}
+
+// children returns the children of n in document order: index order for
+// arrays, key order otherwise.
+func children(n *nodes.Node) []*nodes.Node {
+	if size, ok := nodes.ArrayLen(n); ok {
+		out := make([]*nodes.Node, size)
+		for i := range size {
+			out[i] = nodes.Child(n, strconv.Itoa(i))
+		}
+		return out
+	}
+	return n.Children.Arr()
+}
+
+// descendants appends n and every node below it to out, in pre-order.
+func descendants(n *nodes.Node, out []*nodes.Node) []*nodes.Node {
+	out = append(out, n)
+	for _, c := range children(n) {
+		out = descendants(c, out)
+	}
+	return out
+}
+
+type nameSelector string
+
+func (s nameSelector) selectFrom(n, _ *nodes.Node, out []*nodes.Node) []*nodes.Node {
+	if c := nodes.Child(n, string(s)); c != nil {
+		out = append(out, c)
+	}
+	return out
+}
+
+type wildcardSelector struct{}
+
+func (wildcardSelector) selectFrom(n, _ *nodes.Node, out []*nodes.Node) []*nodes.Node {
+	return append(out, children(n)...)
+}
+
+type indexSelector int
+
+func (s indexSelector) selectFrom(n, _ *nodes.Node, out []*nodes.Node) []*nodes.Node {
+	size, ok := nodes.ArrayLen(n)
+	if !ok {
+		return out
+	}
+	i := int(s)
+	if i < 0 {
+		i += size
+	}
+	if i < 0 || i >= size {
+		return out
+	}
+	return append(out, nodes.Child(n, strconv.Itoa(i)))
+}
+
+type sliceSelector struct {
+	start, end *int
+	step       int
+}
+
+func (s sliceSelector) selectFrom(n, _ *nodes.Node, out []*nodes.Node) []*nodes.Node {
+	size, ok := nodes.ArrayLen(n)
+	if !ok || s.step == 0 {
+		return out
+	}
+	// bounds follow RFC 9535 section 2.3.4.2.2
+	normalize := func(i int) int {
+		if i < 0 {
+			return i + size
+		}
+		return i
+	}
+	if s.step > 0 {
+		lower, upper := 0, size
+		if s.start != nil {
+			lower = min(max(normalize(*s.start), 0), size)
+		}
+		if s.end != nil {
+			upper = min(max(normalize(*s.end), 0), size)
+		}
+		for i := lower; i < upper; i += s.step {
+			out = append(out, nodes.Child(n, strconv.Itoa(i)))
+		}
+		return out
+	}
+	upper, lower := size-1, -1
+	if s.start != nil {
+		upper = min(max(normalize(*s.start), -1), size-1)
+	}
+	if s.end != nil {
+		lower = min(max(normalize(*s.end), -1), size-1)
+	}
+	for i := upper; i > lower; i += s.step {
+		out = append(out, nodes.Child(n, strconv.Itoa(i)))
+	}
+	return out
+}
+
+type filterSelector struct {
+	expr filterExpr
+}
+
+func (s filterSelector) selectFrom(n, root *nodes.Node, out []*nodes.Node) []*nodes.Node {
+	for _, c := range children(n) {
+		if s.expr.test(c, root) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// filterExpr is a filter predicate tested against each candidate node.
+type filterExpr interface {
+	test(cur, root *nodes.Node) bool
+}
+
+type orExpr struct{ left, right filterExpr }
+
+func (e orExpr) test(cur, root *nodes.Node) bool {
+	return e.left.test(cur, root) || e.right.test(cur, root)
+}
+
+type andExpr struct{ left, right filterExpr }
+
+func (e andExpr) test(cur, root *nodes.Node) bool {
+	return e.left.test(cur, root) && e.right.test(cur, root)
+}
+
+type notExpr struct{ expr filterExpr }
+
+func (e notExpr) test(cur, root *nodes.Node) bool {
+	return !e.expr.test(cur, root)
+}
+
+// existsExpr is true when its path matches at least one node.
+type existsExpr struct{ path path }
+
+func (e existsExpr) test(cur, root *nodes.Node) bool {
+	return len(e.path.eval(cur, root)) > 0
+}
+
+type compareExpr struct {
+	op          string
+	left, right operand
+}
+
+func (e compareExpr) test(cur, root *nodes.Node) bool {
+	a, b := e.left.resolve(cur, root), e.right.resolve(cur, root)
+	switch e.op {
+	case "==":
+		return equal(a, b)
+	case "!=":
+		return !equal(a, b)
+	case "<":
+		return less(a, b)
+	case "<=":
+		return less(a, b) || equal(a, b)
+	case ">":
+		return less(b, a)
+	case ">=":
+		return less(b, a) || equal(a, b)
+	}
+	return false
+}
+
+type valueKind int
+
+const (
+	// kindNothing is the result of a path matching no node, or several
+	kindNothing valueKind = iota
+	kindString
+	kindNumber
+	kindBool
+	kindNull
+	kindNode
+)
+
+// value is a comparison operand once resolved against the current node.
+type value struct {
+	kind valueKind
+	// s holds string and boolean literals
+	s    string
+	f    float64
+	node *nodes.Node
+}
+
+// operand is either a literal or a path that must match a single node.
+type operand interface {
+	resolve(cur, root *nodes.Node) value
+}
+
+func (v value) resolve(_, _ *nodes.Node) value {
+	return v
+}
+
+func (p path) resolve(cur, root *nodes.Node) value {
+	matched := p.eval(cur, root)
+	if len(matched) != 1 {
+		return value{kind: kindNothing}
+	}
+	return value{kind: kindNode, node: matched[0]}
+}
+
+// equal compares two operands. A leaf node is compared by its text against
+// string and boolean literals and as a number against number literals.
+func equal(a, b value) bool {
+	if a.kind == kindNothing || b.kind == kindNothing {
+		return a.kind == b.kind
+	}
+	if a.kind != kindNode && b.kind != kindNode {
+		return a == b
+	}
+	if a.kind != kindNode {
+		a, b = b, a
+	}
+	n := a.node
+	if !nodes.IsLeaf(n) {
+		return b.kind == kindNode && b.node == n
+	}
+	switch b.kind {
+	case kindNumber:
+		f, ok := number(n.Value)
+		return ok && f == b.f
+	case kindNull:
+		return n.Value == ""
+	case kindNode:
+		if !nodes.IsLeaf(b.node) {
+			return false
+		}
+		fa, okA := number(n.Value)
+		fb, okB := number(b.node.Value)
+		if okA && okB {
+			return fa == fb
+		}
+		return n.Value == b.node.Value
+	default:
+		return n.Value == b.s
+	}
+}
+
+// less orders two operands when both are numbers or both are text. Leaves
+// count as both when their value parses as a number.
+func less(a, b value) bool {
+	x, y := scalarOf(a), scalarOf(b)
+	switch {
+	case x.isNum && y.isNum:
+		return x.f < y.f
+	case x.isText && y.isText:
+		return x.s < y.s
+	}
+	return false
+}
+
+type scalar struct {
+	s      string
+	f      float64
+	isNum  bool
+	isText bool
+}
+
+func scalarOf(v value) scalar {
+	switch v.kind {
+	case kindNumber:
+		return scalar{f: v.f, isNum: true}
+	case kindString:
+		return scalar{s: v.s, isText: true}
+	case kindNode:
+		if !nodes.IsLeaf(v.node) {
+			return scalar{}
+		}
+		f, ok := number(v.node.Value)
+		return scalar{s: v.node.Value, f: f, isNum: ok, isText: true}
+	}
+	return scalar{}
+}
+
+func number(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+// parser is a recursive descent parser over a JSONPath expression.
+type parser struct {
+	src string
+	pos int
+}
+
+func parseJSONPath(src string) (path, error) {
+	p := &parser{src: src}
+	if !p.consume("$") {
+		return path{}, p.errorf("query must start with $")
+	}
+	segs, err := p.segments()
+	if err != nil {
+		return path{}, err
+	}
+	if !p.eof() {
+		return path{}, p.errorf("unexpected %q", p.peek())
+	}
+	return path{segments: segs}, nil
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &nodes.SyntaxError{Kind: "query", Text: p.src, Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) consume(s string) bool {
+	if strings.HasPrefix(p.src[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() && strings.IndexByte(" \t\n\r", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// segments parses segments until the next character cannot continue the path.
+func (p *parser) segments() ([]segment, error) {
+	var segs []segment
+	for {
+		var seg segment
+		var err error
+		switch {
+		case p.consume(".."):
+			if p.peek() == '[' {
+				seg, err = p.bracketSegment()
+			} else {
+				seg, err = p.dotSegment()
+			}
+			seg.descendant = true
+		case p.consume("."):
+			seg, err = p.dotSegment()
+		case p.peek() == '[':
+			seg, err = p.bracketSegment()
+		default:
+			return segs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		segs = append(segs, seg)
+	}
+}
+
+// dotSegment parses the name or wildcard following . or ..
+func (p *parser) dotSegment() (segment, error) {
+	if p.consume("*") {
+		return segment{selectors: []selector{wildcardSelector{}}}, nil
+	}
+	start := p.pos
+	for !p.eof() {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if !isNameRune(r) {
+			break
+		}
+		p.pos += size
+	}
+	if p.pos == start {
+		return segment{}, p.errorf("expected a name")
+	}
+	return segment{selectors: []selector{nameSelector(p.src[start:p.pos])}}, nil
+}
+
+// isNameRune reports whether r may appear in a dotted name. Hyphens are
+// allowed, unlike in RFC 9535, as they are common in configuration keys.
+func isNameRune(r rune) bool {
+	return r == '_' || r == '-' || r >= 0x80 ||
+		(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+func (p *parser) bracketSegment() (segment, error) {
+	p.pos++ // [
+	var seg segment
+	for {
+		p.skipSpace()
+		sel, err := p.selector()
+		if err != nil {
+			return segment{}, err
+		}
+		seg.selectors = append(seg.selectors, sel)
+		p.skipSpace()
+		if p.consume(",") {
+			continue
+		}
+		if p.consume("]") {
+			return seg, nil
+		}
+		return segment{}, p.errorf("expected , or ]")
+	}
+}
+
+func (p *parser) selector() (selector, error) {
+	switch c := p.peek(); {
+	case c == '*':
+		p.pos++
+		return wildcardSelector{}, nil
+	case c == '\'' || c == '"':
+		s, err := p.stringLiteral()
+		if err != nil {
+			return nil, err
+		}
+		return nameSelector(s), nil
+	case c == '?':
+		p.pos++
+		expr, err := p.orExpr()
+		if err != nil {
+			return nil, err
+		}
+		return filterSelector{expr: expr}, nil
+	case c == '-' || c == ':' || (c >= '0' && c <= '9'):
+		return p.indexOrSlice()
+	}
+	return nil, p.errorf("expected a selector")
+}
+
+func (p *parser) indexOrSlice() (selector, error) {
+	start, err := p.optionalInt()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.consume(":") {
+		if start == nil {
+			return nil, p.errorf("expected an index")
+		}
+		return indexSelector(*start), nil
+	}
+	sel := sliceSelector{start: start, step: 1}
+	p.skipSpace()
+	if sel.end, err = p.optionalInt(); err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.consume(":") {
+		p.skipSpace()
+		step, err := p.optionalInt()
+		if err != nil {
+			return nil, err
+		}
+		if step != nil {
+			sel.step = *step
+		}
+	}
+	return sel, nil
+}
+
+var intPattern = regexp.MustCompile(`^-?[0-9]+`)
+
+func (p *parser) optionalInt() (*int, error) {
+	m := intPattern.FindString(p.src[p.pos:])
+	if m == "" {
+		return nil, nil
+	}
+	i, err := strconv.Atoi(m)
+	if err != nil {
+		return nil, p.errorf("invalid integer %s", m)
+	}
+	p.pos += len(m)
+	return &i, nil
+}
+
+var numberPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?`)
+
+// stringLiteral parses a single or double quoted string with JSON escapes.
+func (p *parser) stringLiteral() (string, error) {
+	quote := p.peek()
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.src[p.pos]
+		p.pos++
+		switch c {
+		case quote:
+			return b.String(), nil
+		case '\\':
+			r, err := p.escape()
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func (p *parser) escape() (rune, error) {
+	if p.eof() {
+		return 0, p.errorf("unterminated string")
+	}
+	c := p.src[p.pos]
+	p.pos++
+	switch c {
+	case 'b':
+		return '\b', nil
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case '/', '\\', '\'', '"':
+		return rune(c), nil
+	case 'u':
+		r, err := p.hex4()
+		if err != nil {
+			return 0, err
+		}
+		if utf16.IsSurrogate(r) && p.consume(`\u`) {
+			low, err := p.hex4()
+			if err != nil {
+				return 0, err
+			}
+			r = utf16.DecodeRune(r, low)
+		}
+		return r, nil
+	}
+	return 0, p.errorf("invalid escape \\%c", c)
+}
+
+func (p *parser) hex4() (rune, error) {
+	if p.pos+4 > len(p.src) {
+		return 0, p.errorf("invalid \\u escape")
+	}
+	v, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid \\u escape")
+	}
+	p.pos += 4
+	return rune(v), nil
+}
+
+func (p *parser) orExpr() (filterExpr, error) {
+	left, err := p.andExpr()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !p.consume("||") {
+			return left, nil
+		}
+		right, err := p.andExpr()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left: left, right: right}
+	}
+}
+
+func (p *parser) andExpr() (filterExpr, error) {
+	left, err := p.unaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !p.consume("&&") {
+			return left, nil
+		}
+		right, err := p.unaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left: left, right: right}
+	}
+}
+
+func (p *parser) unaryExpr() (filterExpr, error) {
+	p.skipSpace()
+	switch {
+	case p.consume("!"):
+		expr, err := p.unaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr: expr}, nil
+	case p.consume("("):
+		expr, err := p.orExpr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !p.consume(")") {
+			return nil, p.errorf("expected )")
+		}
+		return expr, nil
+	}
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	op := p.comparisonOp()
+	if op == "" {
+		if pa, ok := left.(path); ok {
+			return existsExpr{path: pa}, nil
+		}
+		return nil, p.errorf("expected a comparison")
+	}
+	p.skipSpace()
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return compareExpr{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) comparisonOp() string {
+	// two character operators first so <= is not read as <
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func (p *parser) operand() (operand, error) {
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.pos++
+		segs, err := p.segments()
+		if err != nil {
+			return nil, err
+		}
+		return path{relative: c == '@', segments: segs}, nil
+	case c == '\'' || c == '"':
+		s, err := p.stringLiteral()
+		if err != nil {
+			return nil, err
+		}
+		return value{kind: kindString, s: s}, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		m := numberPattern.FindString(p.src[p.pos:])
+		f, err := strconv.ParseFloat(m, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number")
+		}
+		p.pos += len(m)
+		return value{kind: kindNumber, f: f}, nil
+	case p.consume("true"):
+		return value{kind: kindBool, s: "true"}, nil
+	case p.consume("false"):
+		return value{kind: kindBool, s: "false"}, nil
+	case p.consume("null"):
+		return value{kind: kindNull}, nil
+	}
+	return nil, p.errorf("expected a path or literal")
+}
@@ -0,0 +1,79 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPath(t *testing.T) {
+	root := testStore(t)
+	tests := []struct {
+		name string
+		expr string
+		want []string
+	}{
+		{name: "root", expr: "$", want: []string{"$"}},
+		{name: "bracket names", expr: `$["store"]['bicycle']`, want: []string{"$.store.bicycle"}},
+		{name: "wildcard", expr: "$.store.*", want: []string{"$.store.bicycle", "$.store.book"}},
+		{name: "index", expr: "$.store.book[2].title", want: []string{"$.store.book[2].title"}},
+		{name: "negative index", expr: "$.store.book[-1].title", want: []string{"$.store.book[3].title"}},
+		{name: "index out of range", expr: "$.store.book[4]", want: []string{}},
+		{name: "index on object", expr: "$.store[0]", want: []string{}},
+		{name: "union", expr: "$.store.book[0,3].price", want: []string{"$.store.book[0].price", "$.store.book[3].price"}},
+		{name: "slice", expr: "$.store.book[1:3].price", want: []string{"$.store.book[1].price", "$.store.book[2].price"}},
+		{name: "open slice", expr: "$.store.book[:2].price", want: []string{"$.store.book[0].price", "$.store.book[1].price"}},
+		{name: "negative slice", expr: "$.store.book[-2:].price", want: []string{"$.store.book[2].price", "$.store.book[3].price"}},
+		{name: "slice step", expr: "$.store.book[::2].price", want: []string{"$.store.book[0].price", "$.store.book[2].price"}},
+		{name: "reverse slice", expr: "$.store.book[::-1].price", want: []string{
+			"$.store.book[3].price", "$.store.book[2].price", "$.store.book[1].price", "$.store.book[0].price",
+		}},
+		{name: "zero step", expr: "$.store.book[::0]", want: []string{}},
+		{name: "descendant names", expr: "$..author", want: []string{
+			"$.store.book[0].author", "$.store.book[1].author", "$.store.book[2].author", "$.store.book[3].author",
+		}},
+		{name: "descendant bracket", expr: "$..book[0].title", want: []string{"$.store.book[0].title"}},
+		{name: "descendant wildcard", expr: "$.store.bicycle..*", want: []string{"$.store.bicycle.color", "$.store.bicycle.price"}},
+		{name: "number filter", expr: "$.store.book[?@.price < 10].title", want: []string{"$.store.book[0].title", "$.store.book[2].title"}},
+		{name: "parenthesised filter", expr: "$.store.book[?(@.price >= 22.99)].title", want: []string{"$.store.book[3].title"}},
+		{name: "string filter", expr: `$.store.book[?@.category == "reference"].title`, want: []string{"$.store.book[0].title"}},
+		{name: "existence filter", expr: "$.store.book[?@.isbn].title", want: []string{"$.store.book[2].title", "$.store.book[3].title"}},
+		{name: "negated filter", expr: "$.store.book[?!@.isbn].title", want: []string{"$.store.book[0].title", "$.store.book[1].title"}},
+		{name: "and or", expr: "$.store.book[?@.isbn && @.price < 10 || @.category == 'reference'].title", want: []string{
+			"$.store.book[0].title", "$.store.book[2].title",
+		}},
+		{name: "grouping", expr: "$.store.book[?@.isbn && (@.price < 10 || @.category == 'reference')].title", want: []string{
+			"$.store.book[2].title",
+		}},
+		{name: "absolute filter operand", expr: "$.store.book[?@.price < $.store.bicycle.price && @.price > 20].title", want: []string{
+			"$.store.book[3].title",
+		}},
+		{name: "not equal", expr: "$.store.book[?@.category != 'fiction'].title", want: []string{"$.store.book[0].title"}},
+		{name: "boolean literal", expr: "$[?@ == true]", want: []string{"$['with space']"}},
+		{name: "missing operand", expr: "$.store.book[?@.missing == null]", want: []string{}},
+		{name: "hyphenated name", expr: "$.store.book[?@.isbn == '0-553-21311-3'].title", want: []string{"$.store.book[2].title"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Find(root, tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, paths(got))
+		})
+	}
+}
+
+func TestJSONPathRoundTrip(t *testing.T) {
+	root := testStore(t)
+	// every normalized path selects exactly the node it was made from
+	for _, n := range descendants(root, nil) {
+		path := nodes.PathTo(n).Format(nodes.JSONPathSyntax)
+		got, err := Find(root, path)
+		require.NoError(t, err)
+		require.Len(t, got, 1, path)
+		assert.Same(t, n, got[0])
+	}
+	quoted := nodes.New(map[string]any{"it's": "x"}, 0, nodes.EmptyRepr)
+	assert.Equal(t, `$['it\'s']`, nodes.PathTo(nodes.Child(quoted, "it's")).Format(nodes.JSONPathSyntax))
+}
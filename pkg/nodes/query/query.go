@@ -0,0 +1,89 @@
+// Package query finds nodes in a tree with JSONPath (RFC 9535) expressions and
+// JSON Pointers (RFC 6901).
+//
+// The supported JSONPath subset covers the root ($), child names (.name and
+// ['name']), wildcards (* and [*]), recursive descent (..), array indexes
+// (including negative ones), slices ([start:end:step]), unions ([a,b]) and
+// filter predicates such as [?@.price < 10 && @.tags]. Filter predicates
+// compare with ==, !=, <, <=, > and >=, combine with &&, || and !, and test
+// for existence with a bare path. Function extensions are not supported.
+//
+// Tree values are stored as text, so comparisons are made on that text: a leaf
+// equals a number literal when its value parses to the same number, and null
+// matches a leaf with an empty value.
+package query
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/crosleyzack/wndr/pkg/nodes"
+)
+
+// Query is a compiled JSONPath expression or JSON Pointer.
+type Query struct {
+	src  string
+	eval func(root *nodes.Node) []*nodes.Node
+}
+
+// Compile parses src. Expressions starting with $ are JSONPath, and those that
+// are empty or start with / or # are JSON Pointers. Anything else is read as
+// JSONPath relative to the root, so "spec.containers[0]" is short for
+// "$.spec.containers[0]".
+func Compile(src string) (*Query, error) {
+	src = strings.TrimSpace(src)
+	switch {
+	case src == "" || strings.HasPrefix(src, "/") || strings.HasPrefix(src, "#"):
+		path, err := nodes.ParsePath(src, nodes.PointerSyntax)
+		if err != nil {
+			var perr *nodes.SyntaxError
+			if errors.As(err, &perr) {
+				return nil, &nodes.SyntaxError{Kind: "query", Text: src, Offset: perr.Offset, Msg: perr.Msg}
+			}
+			return nil, err
+		}
+		return &Query{src: src, eval: func(root *nodes.Node) []*nodes.Node {
+			if n := path.Resolve(root); n != nil {
+				return []*nodes.Node{n}
+			}
+			return nil
+		}}, nil
+	case !strings.HasPrefix(src, "$") && strings.HasPrefix(src, "["):
+		src = "$" + src
+	case !strings.HasPrefix(src, "$"):
+		src = "$." + src
+	}
+	p, err := parseJSONPath(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Query{src: src, eval: func(root *nodes.Node) []*nodes.Node {
+		return p.eval(root, root)
+	}}, nil
+}
+
+// Eval returns the nodes under root matched by the query, in document order.
+// root is the document itself, normally the sentinel root returned by
+// nodes.New. A node may be returned more than once when the expression
+// selects it through several branches.
+func (q *Query) Eval(root *nodes.Node) []*nodes.Node {
+	if root == nil {
+		return nil
+	}
+	return q.eval(root)
+}
+
+// String returns the expression the query was compiled from, with any
+// shorthand expanded.
+func (q *Query) String() string {
+	return q.src
+}
+
+// Find compiles src and evaluates it against root.
+func Find(root *nodes.Node, src string) ([]*nodes.Node, error) {
+	q, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return q.Eval(root), nil
+}
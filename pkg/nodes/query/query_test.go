@@ -0,0 +1,93 @@
+package query
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/crosleyzack/wndr/pkg/format"
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStore is the bookstore document from the JSONPath specifications.
+func testStore(t *testing.T) *nodes.Node {
+	t.Helper()
+	m, err := format.ParseJson([]byte(`{
+  "store": {
+    "book": [
+      {"category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95},
+      {"category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99},
+      {"category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99},
+      {"category": "fiction", "author": "J. R. R. Tolkien", "title": "The Lord of the Rings", "isbn": "0-395-19395-8", "price": 22.99}
+    ],
+    "bicycle": {"color": "red", "price": 399}
+  },
+  "a/b": {"m~n": "escaped"},
+  "with space": true
+}`))
+	require.NoError(t, err)
+	return nodes.New(m, 0, nodes.EmptyRepr)
+}
+
+// paths returns the normalized path of each node, so results read clearly.
+func paths(ns []*nodes.Node) []string {
+	out := make([]string, len(ns))
+	for i, n := range ns {
+		out[i] = nodes.PathTo(n).Format(nodes.JSONPathSyntax)
+	}
+	return out
+}
+
+func TestCompile(t *testing.T) {
+	root := testStore(t)
+	tests := []struct {
+		name string
+		expr string
+		want []string
+	}{
+		{name: "jsonpath", expr: "$.store.bicycle.color", want: []string{"$.store.bicycle.color"}},
+		{name: "shorthand", expr: "store.bicycle.color", want: []string{"$.store.bicycle.color"}},
+		{name: "shorthand bracket", expr: "['with space']", want: []string{"$['with space']"}},
+		{name: "pointer", expr: "/store/book/1/title", want: []string{"$.store.book[1].title"}},
+		{name: "escaped pointer", expr: "/a~1b/m~0n", want: []string{"$['a/b']['m~n']"}},
+		{name: "fragment pointer", expr: "#/with%20space", want: []string{"$['with space']"}},
+		{name: "empty pointer", expr: "", want: []string{"$"}},
+		{name: "missing pointer", expr: "/store/nope", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Find(root, tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, paths(got))
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, expr := range []string{
+		"$.",
+		"$[",
+		"$['open",
+		"$[?@.a ==]",
+		"$[?(@.a]",
+		"$[?'lit']",
+		"$.a b",
+		"/a~2",
+		"#%zz",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := Compile(expr)
+			var serr *nodes.SyntaxError
+			require.True(t, errors.As(err, &serr), "got %v", err)
+			assert.Equal(t, expr, serr.Text)
+		})
+	}
+}
+
+func TestEvalNilRoot(t *testing.T) {
+	q, err := Compile("$..*")
+	require.NoError(t, err)
+	assert.Nil(t, q.Eval(nil))
+	assert.Equal(t, "$..*", q.String())
+}
@@ -84,6 +84,9 @@ func leafValuesWithBracketsHelper(n *Node, includeKey bool) string {
 			b.WriteString(spacerToken(first))
 			b.WriteString(leafValuesWithBracketsHelper(child, true))
 			first = false
+			if summaryFull(&b) {
+				break
+			}
 		}
 		b.WriteString("}")
 	} else if n.Value != "" {
@@ -105,6 +108,9 @@ func LeafValuesOnly(n *Node) string {
 			b.WriteString(spacerToken(first))
 			b.WriteString(LeafValuesOnly(child))
 			first = false
+			if summaryFull(&b) {
+				break
+			}
 		}
 	} else {
 		b.WriteString(n.Value)
@@ -121,6 +127,9 @@ func LeafKeyAndValues(n *Node) string {
 			b.WriteString(spacerToken(first))
 			b.WriteString(LeafKeyAndValues(child))
 			first = false
+			if summaryFull(&b) {
+				break
+			}
 		}
 	} else {
 		b.WriteString(n.Key)
@@ -273,6 +282,12 @@ func truncateIfNeeded(s string) string {
 	return s
 }
 
+// summaryFull reports whether b already holds more than truncateIfNeeded keeps,
+// so the rest of a large subtree need not be summarized.
+func summaryFull(b *strings.Builder) bool {
+	return len(strings.TrimSpace(b.String())) > MaxStringLength
+}
+
 // spacerToken returns a space if not the first element
 func spacerToken(first bool) string {
 	if first {
@@ -1,14 +1,20 @@
 package nodes
 
 import (
-	"bytes"
-	"crypto/sha256"
+	"context"
+	"errors"
 	"fmt"
+	"slices"
 )
 
 // SearchConfig configuration for performing a search on the tree
 type searchConfig struct {
 	NextNodes NextNodes
+	// prune reports whether to leave out the nodes below a node; nil prunes
+	// nothing
+	prune func(*Node, int) bool
+	// ctx stops the search once it is done; nil never stops it
+	ctx context.Context
 }
 
 func defaultSearchConfig() *searchConfig {
@@ -17,6 +23,14 @@ func defaultSearchConfig() *searchConfig {
 	}
 }
 
+func newSearchConfig(opts []DFSOption) *searchConfig {
+	conf := defaultSearchConfig()
+	for _, opt := range opts {
+		opt(conf)
+	}
+	return conf
+}
+
 type DFSOption func(*searchConfig)
 type NextNodes func(n *Node) []*Node
 
@@ -26,29 +40,73 @@ func WithNextNodes(f NextNodes) DFSOption {
 	}
 }
 
-// DFS perform depth first search on tree and run f on nodes
-func DFS(node *Node, f func(*Node, int) error, opts ...DFSOption) error {
-	conf := defaultSearchConfig()
-	for _, opt := range opts {
-		opt(conf)
+// WithPrune leaves out the nodes below each node for which f, given the node
+// and its depth, returns true. The node itself is still visited.
+func WithPrune(f func(n *Node, depth int) bool) DFSOption {
+	return func(c *searchConfig) {
+		c.prune = f
 	}
-	if node == nil {
-		return fmt.Errorf("received nil node")
+}
+
+// WithContext stops the search before the next node once ctx is done. DFS then
+// returns ctx's error; the iterators stop yielding, so callers check ctx.Err
+// to tell a cancelled walk from a finished one.
+func WithContext(ctx context.Context) DFSOption {
+	return func(c *searchConfig) {
+		c.ctx = ctx
+	}
+}
+
+// next returns the nodes below n at depth to visit.
+func (c *searchConfig) next(n *Node, depth int) []*Node {
+	if c.prune != nil && c.prune(n, depth) {
+		return nil
+	}
+	return c.NextNodes(n)
+}
+
+// err returns the error of the search's context once it is done.
+func (c *searchConfig) err() error {
+	if c.ctx == nil {
+		return nil
 	}
-	start := []*Node{node}
+	return c.ctx.Err()
+}
+
+// start returns the nodes a search from node begins with: its children when it
+// is the root, which is never visited, and otherwise node itself.
+func start(node *Node) []*Node {
 	if IsRoot(node) {
-		start = node.Children.Arr()
+		return node.Children.Arr()
 	}
-	return dfs(start, f, conf, 0)
+	return []*Node{node}
+}
+
+// DFS perform depth first search on tree and run f on nodes. f may return
+// SkipChildren to leave out the nodes below the current one; any other error
+// stops the search and is returned.
+func DFS(node *Node, f func(*Node, int) error, opts ...DFSOption) error {
+	conf := newSearchConfig(opts)
+	if node == nil {
+		return fmt.Errorf("received nil node")
+	}
+	return dfs(start(node), f, conf, 0)
 }
 
 // dfs implementation of dfs
 func dfs(nodes []*Node, f func(*Node, int) error, conf *searchConfig, layer int) error {
 	for _, node := range nodes {
-		if err := f(node, layer); err != nil {
+		if err := conf.err(); err != nil {
 			return err
 		}
-		next := conf.NextNodes(node)
+		err := f(node, layer)
+		if errors.Is(err, SkipChildren) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		next := conf.next(node, layer)
 		if len(next) > 0 {
 			if err := dfs(next, f, conf, layer+1); err != nil {
 				return err
@@ -58,13 +116,14 @@ func dfs(nodes []*Node, f func(*Node, int) error, conf *searchConfig, layer int)
 	return nil
 }
 
-// DFSIter a DFS implementation as an iterator for efficient searches
+// DFSIter a DFS implementation as an iterator for efficient searches. It visits
+// siblings in reverse document order.
+//
+// Deprecated: use PreOrder, which keeps document order, and filter the nodes
+// it yields.
 func DFSIter(node *Node, f func(*Node) bool, opts ...DFSOption) func(func(*Node) bool) {
 	// get config
-	conf := defaultSearchConfig()
-	for _, opt := range opts {
-		opt(conf)
-	}
+	conf := newSearchConfig(opts)
 	stack := []*Node{node}
 	if IsRoot(node) {
 		stack = node.Children.Arr()
@@ -107,29 +166,39 @@ type ChildNode struct {
 	Rem  []string
 }
 
+// SkipChildren is returned by the function passed to DFS or DFSMulti to leave
+// out the nodes or paths below the current one. It is not returned by either.
+var SkipChildren = errors.New("skip children")
+
+// pendingVisit is a path DFSMulti has yet to visit and the node at it in each tree
+type pendingVisit struct {
+	path  []string
+	nodes []ChildNode
+}
+
 // DFSMulti a DFS implementation that operates across multiple trees simultaneously
 func DFSMulti(f func([]string, []ChildNode) error, trees ...*Node) error {
-	stack := [][]string{{}}
-	var path []string
-	nodes := make([]ChildNode, len(trees))
-	for {
-		if len(stack) == 0 {
-			return nil
-		}
-		path, stack = pop(stack)
-		// get children at this path
-		for i, tree := range trees {
-			n, remainder := GetNodeFromPath(tree, path)
-			nodes[i] = ChildNode{Node: n, Rem: remainder}
-		}
+	root := pendingVisit{path: []string{}, nodes: make([]ChildNode, len(trees))}
+	for i, tree := range trees {
+		n, remainder := GetNodeFromPath(tree, root.path)
+		root.nodes[i] = ChildNode{Node: n, Rem: remainder}
+	}
+	stack := []pendingVisit{root}
+	var v pendingVisit
+	for len(stack) > 0 {
+		v, stack = pop(stack)
 		// process on nodes
-		if err := f(path, nodes); err != nil {
+		err := f(v.path, v.nodes)
+		if errors.Is(err, SkipChildren) {
+			continue
+		}
+		if err != nil {
 			return err
 		}
-		// get the paths to children for each tree and add union to stack
-		stack = append(stack, getNewPaths(nodes)...)
-
+		// add the union of the children in each tree to the stack
+		stack = append(stack, childVisits(v)...)
 	}
+	return nil
 }
 
 func pop[T any](arr []T) (T, []T) {
@@ -137,33 +206,40 @@ func pop[T any](arr []T) (T, []T) {
 	return arr[last], arr[:last]
 }
 
-func getNewPaths(nodes []ChildNode) [][]string {
-	newPaths := make(map[[32]byte][]string)
-	for _, node := range nodes {
+// childVisits returns a visit for each key under v's path in any tree. Each
+// node is found from the node at v, so no tree is walked from its root again.
+func childVisits(v pendingVisit) []pendingVisit {
+	var keys []string
+	seen := map[string]bool{}
+	for _, cn := range v.nodes {
 		// we only care about nodes that exist at this path already. A nil
-		// node (a nil tree, or a path GetNodeFromPath could not resolve)
+		// node (a nil tree, or a path that could not be resolved)
 		// contributes no children.
-		if node.Node == nil || len(node.Rem) > 0 {
+		if cn.Node == nil || len(cn.Rem) > 0 {
 			continue
 		}
-		for _, child := range node.Node.Children.Iter() {
-			path := GetPathToNode(child)
-			newPaths[sha256.Sum256(pathToBytes(path))] = path
+		for key := range cn.Node.Children.Keys() {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
 		}
 	}
-	ret := make([][]string, 0, len(newPaths))
-	for _, path := range newPaths {
-		ret = append(ret, path)
-	}
-	return ret
-}
-
-func pathToBytes(path []string) []byte {
-	b := bytes.Buffer{}
-	for _, loc := range path {
-		b.Write([]byte(loc))
-		// separator so ["a","b"] and ["ab"] do not hash to the same key
-		b.WriteByte(0)
+	out := make([]pendingVisit, len(keys))
+	for i, key := range keys {
+		next := pendingVisit{path: append(slices.Clone(v.path), key), nodes: make([]ChildNode, len(v.nodes))}
+		for j, cn := range v.nodes {
+			switch {
+			case cn.Node == nil || len(cn.Rem) > 0:
+				next.nodes[j] = ChildNode{Node: cn.Node, Rem: append(slices.Clone(cn.Rem), key)}
+			case Child(cn.Node, key) != nil:
+				next.nodes[j] = ChildNode{Node: Child(cn.Node, key)}
+			default:
+				// no such child; keep the furthest node along the path
+				next.nodes[j] = ChildNode{Node: cn.Node, Rem: []string{key}}
+			}
+		}
+		out[i] = next
 	}
-	return b.Bytes()
+	return out
 }
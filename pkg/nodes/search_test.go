@@ -197,8 +197,8 @@ type multiVisit struct {
 }
 
 // collectMulti runs DFSMulti over trees and returns one multiVisit per callback
-// invocation. Visit order is not asserted because getNewPaths derives the next
-// paths from a map, so callers compare with assert.ElementsMatch.
+// invocation. Visit order is not asserted, so callers compare with
+// assert.ElementsMatch.
 func collectMulti(t *testing.T, trees ...*Node) []multiVisit {
 	t.Helper()
 	var got []multiVisit
@@ -318,6 +318,19 @@ func TestDFSMultiError(t *testing.T) {
 	assert.Equal(t, 2, visited)
 }
 
+func TestDFSMultiSkipChildren(t *testing.T) {
+	var visited [][]string
+	err := DFSMulti(func(path []string, _ []ChildNode) error {
+		visited = append(visited, path)
+		if len(path) == 1 && path[0] == "a" {
+			return SkipChildren
+		}
+		return nil
+	}, New(map[string]any{"a": map[string]any{"deep": "1"}, "b": map[string]any{"c": "2"}}, 5, EmptyRepr))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, [][]string{{}, {"a"}, {"b"}, {"b", "c"}}, visited)
+}
+
 func TestAllChildren(t *testing.T) {
 	child := &Node{Key: "child"}
 	tests := []struct {
@@ -0,0 +1,282 @@
+package nodes
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// SummaryRule summarizes the objects and arrays at the paths matching Path
+// with Template.
+type SummaryRule struct {
+	Path     Glob
+	Template *template.Template
+}
+
+// ParseSummaryRule parses a rule summarizing the objects and arrays at the
+// paths matching glob with text, a text/template template. The template is
+// executed on the data of the object or array, so {{.name}} is the value of its
+// name key. Besides the built-in functions it can call:
+//
+//	count  the number of entries of an object or array
+//	join   the values of an array or object joined by a separator: {{join ", " .args}}
+//	first  the first value of an array or object
+//	type   the JSON type of a value: object, array, string, number, boolean or null
+func ParseSummaryRule(glob, text string) (SummaryRule, error) {
+	g, err := ParseGlob(glob)
+	if err != nil {
+		return SummaryRule{}, fmt.Errorf("summary rule: %w", err)
+	}
+	t, err := template.New(glob).Funcs(summaryFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return SummaryRule{}, fmt.Errorf("summary rule for %s: %w", glob, err)
+	}
+	return SummaryRule{Path: g, Template: t}, nil
+}
+
+var summaryFuncs = template.FuncMap{
+	"count": func(v any) int {
+		switch t := v.(type) {
+		case map[string]any:
+			return len(t)
+		case []any:
+			return len(t)
+		case string:
+			return len(t)
+		}
+		return 0
+	},
+	"join": func(sep string, v any) string {
+		values := summaryValues(v)
+		parts := make([]string, len(values))
+		for i, value := range values {
+			parts[i] = fmt.Sprint(value)
+		}
+		return strings.Join(parts, sep)
+	},
+	"first": func(v any) any {
+		if values := summaryValues(v); len(values) > 0 {
+			return values[0]
+		}
+		return nil
+	},
+	"type": func(v any) string {
+		switch v.(type) {
+		case map[string]any:
+			return "object"
+		case []any:
+			return "array"
+		case int64, float64:
+			return "number"
+		case bool:
+			return "boolean"
+		case nil:
+			return "null"
+		}
+		return "string"
+	},
+}
+
+// summaryValues returns the values of an array in order, of an object in key
+// order, or v itself.
+func summaryValues(v any) []any {
+	switch t := v.(type) {
+	case []any:
+		return t
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for key := range t {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		values := make([]any, len(keys))
+		for i, key := range keys {
+			values[i] = t[key]
+		}
+		return values
+	case nil:
+		return nil
+	}
+	return []any{v}
+}
+
+// TemplateRepr returns a ReprNode summarizing each object and array with the
+// template of the first rule whose path matches it. The rest, and those whose
+// template fails, are summarized with fallback, or LeafValuesOnly when nil.
+func TemplateRepr(rules []SummaryRule, fallback ReprNode) ReprNode {
+	if fallback == nil {
+		fallback = LeafValuesOnly
+	}
+	fields := make([]*summaryFields, len(rules))
+	for i, r := range rules {
+		fields[i] = templateFields(r.Template)
+	}
+	return func(n *Node) string {
+		if len(rules) == 0 || IsLeaf(n) {
+			return fallback(n)
+		}
+		path := GetPathToNode(n)
+		for i, r := range rules {
+			if !r.Path.Match(path) {
+				continue
+			}
+			var b strings.Builder
+			if err := r.Template.Execute(&b, summaryData(n, fields[i])); err != nil {
+				return fallback(n)
+			}
+			return truncateIfNeeded(b.String())
+		}
+		return fallback(n)
+	}
+}
+
+// summaryFields are the keys of the data a summary template refers to, each
+// with the keys below it the template refers to. The whole value of a key is
+// used when all is set, as when the template refers to it rather than to keys
+// within it. ranged is set when the template ranges over it.
+type summaryFields struct {
+	all    bool
+	ranged bool
+	keys   map[string]*summaryFields
+}
+
+// add adds the value at the keys in idents to f and returns its fields.
+func (f *summaryFields) add(idents []string) *summaryFields {
+	for _, ident := range idents {
+		if f.all {
+			return f
+		}
+		if f.keys == nil {
+			f.keys = map[string]*summaryFields{}
+		}
+		next, ok := f.keys[ident]
+		if !ok {
+			next = &summaryFields{}
+			f.keys[ident] = next
+		}
+		f = next
+	}
+	f.all = true
+	f.keys = nil
+	return f
+}
+
+// templateFields returns the fields of its data t refers to, so only they are
+// read from the tree.
+func templateFields(t *template.Template) *summaryFields {
+	f := &summaryFields{}
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil {
+			continue
+		}
+		// the dot of a defined template is not the data, but whatever it is
+		// was passed from the data where the template was called
+		f.walk(tmpl.Root, tmpl.Name() != t.Name())
+	}
+	return f
+}
+
+// walk adds the fields node refers to to f. Within range and with, and
+// templates other than the one executed, dot is not the data, so only the
+// fields reached from $ are added.
+func (f *summaryFields) walk(node parse.Node, inner bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			f.walk(child, inner)
+		}
+	case *parse.ActionNode:
+		f.walk(n.Pipe, inner)
+	case *parse.IfNode:
+		f.walkBranch(&n.BranchNode, inner, inner)
+	case *parse.RangeNode:
+		f.walkBranch(&n.BranchNode, inner, true)
+		// ranging over an empty string fails, so a missing key must be nil
+		if last := n.Pipe.Cmds[len(n.Pipe.Cmds)-1]; !inner && len(last.Args) == 1 {
+			if field, ok := last.Args[0].(*parse.FieldNode); ok {
+				f.add(field.Ident).ranged = true
+			}
+		}
+	case *parse.WithNode:
+		f.walkBranch(&n.BranchNode, inner, true)
+	case *parse.TemplateNode:
+		f.walk(n.Pipe, inner)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			f.walk(cmd, inner)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			f.walk(arg, inner)
+		}
+	case *parse.ChainNode:
+		f.walk(n.Node, inner)
+	case *parse.FieldNode:
+		if !inner {
+			f.add(n.Ident)
+		}
+	case *parse.DotNode:
+		if !inner {
+			f.add(nil)
+		}
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" {
+			f.add(n.Ident[1:])
+		}
+	}
+}
+
+// walkBranch adds the fields of the pipeline of b, and of its bodies, where
+// dot is not the data when body is set.
+func (f *summaryFields) walkBranch(b *parse.BranchNode, inner, body bool) {
+	f.walk(b.Pipe, inner)
+	f.walk(b.List, body)
+	f.walk(b.ElseList, inner)
+}
+
+// summaryData converts n to the data its summary template is executed on,
+// reading only the keys in fields. It is TypedValue, with the keys of fields
+// missing from the tree empty so the template prints nothing for them.
+func summaryData(n *Node, fields *summaryFields) any {
+	if IsLeaf(n) || fields.all {
+		return TypedValue(n)
+	}
+	if _, ok := ArrayLen(n); ok {
+		// an array has no fields for the template to read
+		return []any{}
+	}
+	m := make(map[string]any, len(fields.keys))
+	for key, keyFields := range fields.keys {
+		if child := Child(n, key); child != nil {
+			m[key] = summaryData(child, keyFields)
+		} else {
+			m[key] = missingData(keyFields)
+		}
+	}
+	return m
+}
+
+// missingData is the data for a key missing from the tree: empty, nil when it
+// is ranged over, or an object of the keys within it in fields, so {{.a.b}} is
+// empty too.
+func missingData(fields *summaryFields) any {
+	if fields.ranged {
+		return nil
+	}
+	if fields.all {
+		return ""
+	}
+	m := make(map[string]any, len(fields.keys))
+	for key, keyFields := range fields.keys {
+		m[key] = missingData(keyFields)
+	}
+	return m
+}
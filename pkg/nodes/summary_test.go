@@ -0,0 +1,94 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateRepr(t *testing.T) {
+	rule := func(glob, text string) SummaryRule {
+		r, err := ParseSummaryRule(glob, text)
+		require.NoError(t, err)
+		return r
+	}
+	repr := TemplateRepr([]SummaryRule{
+		rule("pods[*]", "{{.name}} ({{.status}})"),
+		rule("pods[*].ports", "{{count .}} ports: {{join \", \" .}}"),
+		rule("**.labels", "{{first .}} is {{type (first .)}}"),
+		rule("broken", "{{.a.b.c}}"),
+	}, KeyCountOnly)
+	root := New(map[string]any{
+		"pods": []any{
+			map[string]any{"name": "web", "status": "Running", "ports": []any{80, 443}, "labels": map[string]any{"b": "x", "a": true}},
+			map[string]any{"name": "db"},
+		},
+		"broken": map[string]any{"a": "1"},
+		"other":  map[string]any{"a": "1"},
+	}, 0, repr)
+	pods := Child(root, "pods")
+	web := Child(pods, "0")
+
+	assert.Equal(t, "web (Running)", web.Value)
+	assert.Equal(t, "db ()", Child(pods, "1").Value, "a missing key is empty")
+	assert.Equal(t, "2 ports: 80, 443", Child(web, "ports").Value)
+	assert.Equal(t, "true is boolean", Child(web, "labels").Value)
+	assert.Equal(t, "ⓘ (2 items)", pods.Value, "no rule matches")
+	assert.Equal(t, "ⓘ (1 keys)", Child(root, "broken").Value, "the template failed")
+	assert.Equal(t, "ⓘ (1 keys)", Child(root, "other").Value)
+
+	// summaries rebuilt after an edit use the rules too
+	_, err := SetOp{Path: Path{{Key: "pods"}, {Key: "1", IsIndex: true}, {Key: "name"}}, Value: "cache"}.Apply(root, repr)
+	require.NoError(t, err)
+	assert.Equal(t, "cache ()", Child(pods, "1").Value)
+}
+
+func TestTemplateReprMissingKeys(t *testing.T) {
+	rule, err := ParseSummaryRule("*", "{{.name}}|{{.meta.owner}}|{{range .tags}}{{.}};{{end}}")
+	require.NoError(t, err)
+	repr := TemplateRepr([]SummaryRule{rule}, KeyCountOnly)
+	root := New(map[string]any{
+		"a": map[string]any{"name": "<no value>", "meta": map[string]any{"owner": "ops"}, "tags": []any{"x", "y"}},
+		"b": map[string]any{"other": 1},
+	}, 0, repr)
+
+	assert.Equal(t, "<no value>|ops|x;y;", Child(root, "a").Value, "data is kept as is")
+	assert.Equal(t, "||", Child(root, "b").Value, "missing keys are empty")
+}
+
+func TestTemplateFields(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want any
+	}{
+		{text: "{{.name}} ({{.status.phase}})", want: map[string]any{"name": "web", "status": map[string]any{"phase": "Running"}}},
+		{text: "{{count .ports}}", want: map[string]any{"ports": []any{int64(80), int64(443)}}},
+		{text: "{{range .ports}}{{.}}{{$.name}}{{end}}", want: map[string]any{"name": "web", "ports": []any{int64(80), int64(443)}}},
+		{text: "{{with .status}}{{.phase}}{{end}}", want: map[string]any{"status": map[string]any{"phase": "Running", "ready": true}}},
+		{text: "{{if .name}}{{.name}}{{end}}", want: map[string]any{"name": "web"}},
+		{text: "static", want: map[string]any{}},
+	} {
+		t.Run(tc.text, func(t *testing.T) {
+			rule, err := ParseSummaryRule("*", tc.text)
+			require.NoError(t, err)
+			root := New(map[string]any{
+				"name":   "web",
+				"ports":  []any{80, 443},
+				"status": map[string]any{"phase": "Running", "ready": true},
+				"spec":   map[string]any{"image": "nginx"},
+			}, 0, EmptyRepr)
+			assert.Equal(t, tc.want, summaryData(root, templateFields(rule.Template)))
+		})
+	}
+	rule, err := ParseSummaryRule("*", "{{count .}}")
+	require.NoError(t, err)
+	assert.True(t, templateFields(rule.Template).all, "dot is the whole data")
+}
+
+func TestParseSummaryRule(t *testing.T) {
+	_, err := ParseSummaryRule("a", "{{.name")
+	assert.Error(t, err)
+	_, err = ParseSummaryRule("a.[b", "x")
+	assert.Error(t, err)
+}
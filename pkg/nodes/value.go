@@ -0,0 +1,303 @@
+package nodes
+
+import (
+	"encoding"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// CycleValue is the value of a node standing in for a value that contains
+// itself, such as a pointer back to a struct it is in.
+const CycleValue = "<cycle>"
+
+// FromValue creates a new tree from any Go value, as New does from decoded
+// JSON, so data can be shown without converting it to a map first. Structs,
+// maps, slices, arrays, pointers and interfaces are walked with reflection:
+//
+//   - struct fields are named, left out and inlined following their json
+//     tags, or yaml tags when they have none, as encoding/json does, and
+//     omitempty leaves out empty values; unexported fields are left out,
+//     though those of an embedded struct are promoted as usual
+//   - values implementing encoding.TextMarshaler, such as time.Time and
+//     net.IP, are leaves holding their text, and []byte is base64 encoded
+//   - structs with no exported fields, channels, functions and complex
+//     numbers are leaves holding their String method's result, or
+//     fmt.Sprint's
+//   - a value found again inside itself is a leaf holding CycleValue
+//
+// An array or slice becomes a tree keyed by index, and any other value that
+// is not an object a tree holding it under the key "value".
+func FromValue(v any, displayLayers uint, repr ReprNode) *Node {
+	data := (&valueWalker{seen: map[visit]bool{}}).walk(reflect.ValueOf(v))
+	switch t := data.(type) {
+	case map[string]any:
+		return New(t, displayLayers, repr)
+	case []any:
+		m := make(map[string]any, len(t))
+		for i, e := range t {
+			m[strconv.Itoa(i)] = e
+		}
+		return New(m, displayLayers, repr)
+	default:
+		return New(map[string]any{"value": data}, displayLayers, repr)
+	}
+}
+
+var (
+	textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+	stringer      = reflect.TypeFor[fmt.Stringer]()
+)
+
+// visit identifies a pointer, map or slice being walked, to find cycles. The
+// type tells a struct from its first field, which share an address.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// valueWalker converts Go values to the plain data New builds trees from.
+type valueWalker struct {
+	// seen holds the pointers, maps and slices on the path to the value
+	// being walked
+	seen map[visit]bool
+}
+
+// walk converts v to nil, a string, bool, int64, uint64 or float64, a []any,
+// or a map[string]any.
+func (w *valueWalker) walk(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if text, ok := marshalText(v); ok {
+		return text
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Interface {
+			return w.walk(v.Elem())
+		}
+		return w.enter(v, 0, func() any { return w.walk(v.Elem()) })
+	case reflect.Struct:
+		return w.walkStruct(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		return w.enter(v, 0, func() any {
+			m := make(map[string]any, v.Len())
+			iter := v.MapRange()
+			for iter.Next() {
+				m[mapKey(iter.Key())] = w.walk(iter.Value())
+			}
+			return m
+		})
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return base64.StdEncoding.EncodeToString(v.Bytes())
+		}
+		return w.enter(v, v.Len(), func() any { return w.walkArray(v) })
+	case reflect.Array:
+		return w.walkArray(v)
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.Float32:
+		// formatted at 32 bits, so 0.1 is not shown as 0.10000000149011612
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32)
+	case reflect.Float64:
+		return v.Float()
+	default:
+		return str(v)
+	}
+}
+
+// enter walks the pointer, map or slice v with walk, unless v is already
+// being walked further up, which makes it a cycle.
+func (w *valueWalker) enter(v reflect.Value, n int, walk func() any) any {
+	key := visit{ptr: v.Pointer(), typ: v.Type(), len: n}
+	if w.seen[key] {
+		return CycleValue
+	}
+	w.seen[key] = true
+	defer delete(w.seen, key)
+	return walk()
+}
+
+func (w *valueWalker) walkArray(v reflect.Value) []any {
+	arr := make([]any, v.Len())
+	for i := range arr {
+		arr[i] = w.walk(v.Index(i))
+	}
+	return arr
+}
+
+// walkStruct converts the exported fields of v to a map, or v to a string when
+// it has none.
+func (w *valueWalker) walkStruct(v reflect.Value) any {
+	m := map[string]any{}
+	exported := w.addFields(m, v)
+	if !exported {
+		return str(v)
+	}
+	return m
+}
+
+// addFields adds the exported fields of the struct v to m, reporting whether
+// it has any. Fields already in m, from an outer struct, are kept.
+func (w *valueWalker) addFields(m map[string]any, v reflect.Value) bool {
+	exported := false
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, omitEmpty, inline, skip := fieldTag(f)
+		// the exported fields of an embedded struct of an unexported type
+		// are still promoted, unless it is embedded by pointer
+		promoted := inline && f.Type.Kind() == reflect.Struct
+		if skip || (!f.IsExported() && !promoted) {
+			continue
+		}
+		fv := v.Field(i)
+		if inline {
+			// the fields of an embedded struct are promoted to v
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			inner := map[string]any{}
+			if w.addFields(inner, fv) {
+				exported = true
+			}
+			for k, iv := range inner {
+				if _, ok := m[k]; !ok {
+					m[k] = iv
+				}
+			}
+			continue
+		}
+		exported = true
+		if omitEmpty && isEmpty(fv) {
+			continue
+		}
+		// an outer field hides a promoted one of the same name
+		m[name] = w.walk(fv)
+	}
+	return exported
+}
+
+// fieldTag reads the json tag of f, or its yaml tag when it has none. It
+// returns the key to use, whether empty values are left out, whether f is an
+// embedded struct whose fields are inlined, and whether f is left out.
+func fieldTag(f reflect.StructField) (name string, omitEmpty, inline, skip bool) {
+	tag, ok := f.Tag.Lookup("json")
+	if !ok {
+		tag = f.Tag.Get("yaml")
+	}
+	if tag == "-" {
+		return "", false, false, true
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	for opt := range strings.SplitSeq(opts, ",") {
+		switch opt {
+		case "omitempty":
+			omitEmpty = true
+		case "inline":
+			inline = true
+		}
+	}
+	if name == "" {
+		name = f.Name
+		// an untagged embedded struct is inlined, as encoding/json does
+		inline = inline || f.Anonymous
+	}
+	return name, omitEmpty, inline && isStruct(f.Type), false
+}
+
+// isStruct reports whether t is a struct, or a pointer to one, that does not
+// marshal itself to text.
+func isStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textMarshaler)
+}
+
+// isEmpty reports whether v is empty as omitempty means it: false, 0, a nil
+// pointer or interface, or an empty array, slice, map or string.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// marshalText returns the text of v when it, or a pointer to it, implements
+// encoding.TextMarshaler.
+func marshalText(v reflect.Value) (string, bool) {
+	// the fields of an unexported embedded struct cannot be used as values
+	if !v.CanInterface() || (v.Kind() == reflect.Pointer && v.IsNil()) {
+		return "", false
+	}
+	if !v.Type().Implements(textMarshaler) {
+		if !v.CanAddr() || !reflect.PointerTo(v.Type()).Implements(textMarshaler) {
+			return "", false
+		}
+		v = v.Addr()
+	}
+	if v.Kind() == reflect.Interface {
+		return "", false
+	}
+	b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// str returns the result of the String method of v, or fmt.Sprint's.
+func str(v reflect.Value) string {
+	if v.CanInterface() {
+		if v.Type().Implements(stringer) {
+			return v.Interface().(fmt.Stringer).String()
+		}
+		if v.CanAddr() && reflect.PointerTo(v.Type()).Implements(stringer) {
+			return v.Addr().Interface().(fmt.Stringer).String()
+		}
+		return fmt.Sprint(v.Interface())
+	}
+	return fmt.Sprint(v)
+}
+
+// mapKey returns the key of a map entry as a string.
+func mapKey(k reflect.Value) string {
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+	if text, ok := marshalText(k); ok {
+		return text
+	}
+	return str(k)
+}
@@ -0,0 +1,88 @@
+package nodes
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type valueBase struct {
+	ID      int    `json:"id"`
+	Comment string `json:"comment,omitempty"`
+}
+
+type valueLevel int
+
+func (l valueLevel) String() string { return [...]string{"low", "high"}[l] }
+
+type valueOpaque struct{ level valueLevel }
+
+func (o valueOpaque) String() string { return "opaque " + o.level.String() }
+
+type valueItem struct {
+	valueBase
+	Name    string            `json:"name"`
+	Tags    []string          `yaml:"tags,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Secret  string            `json:"-"`
+	Created time.Time         `json:"created"`
+	Addr    net.IP            `json:"addr"`
+	Level   valueLevel        `json:"level"`
+	Opaque  valueOpaque       `json:"opaque"`
+	Data    []byte            `json:"data"`
+	Ratio   float32           `json:"ratio"`
+	Next    *valueItem        `json:"next,omitempty"`
+	Any     any               `json:"any"`
+	Plain   int
+	private int
+}
+
+func TestFromValue(t *testing.T) {
+	item := &valueItem{
+		valueBase: valueBase{ID: 7},
+		Name:      "web",
+		Labels:    map[string]string{"app": "web"},
+		Secret:    "hunter2",
+		Created:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Addr:      net.IPv4(10, 0, 0, 1),
+		Level:     1,
+		Data:      []byte("hi"),
+		Ratio:     0.1,
+		Any:       map[netip.Addr]bool{netip.MustParseAddr("10.0.0.2"): true},
+		Plain:     3,
+	}
+	item.Next = item
+	root := FromValue(item, 0, LeafValuesOnly)
+	assert.Equal(t, map[string]any{
+		"id":      "7",
+		"name":    "web",
+		"labels":  map[string]any{"app": "web"},
+		"created": "2024-01-02T03:04:05Z",
+		"addr":    "10.0.0.1",
+		"level":   "1",
+		"opaque":  "opaque low",
+		"data":    "aGk=",
+		"ratio":   "0.1",
+		"next":    CycleValue,
+		"any":     map[string]any{"10.0.0.2": "true"},
+		"Plain":   "3",
+	}, ToValue(root))
+
+	// the same value twice is not a cycle
+	shared := &valueBase{ID: 1}
+	root = FromValue([]any{shared, shared, nil}, 0, LeafValuesOnly)
+	assert.Equal(t, "1", Child(Child(root, "1"), "id").Value)
+	assert.Equal(t, "", Child(root, "2").Value)
+
+	loop := map[string]any{}
+	loop["self"] = loop
+	assert.Equal(t, CycleValue, Child(FromValue(loop, 0, LeafValuesOnly), "self").Value)
+
+	assert.Equal(t, "1", Child(FromValue(valueLevel(1), 0, LeafValuesOnly), "value").Value, "numbers are not Stringers")
+	assert.Equal(t, map[string]any{"80": "http", "443": "https"},
+		ToValue(FromValue(map[int]string{80: "http", 443: "https"}, 0, LeafValuesOnly)))
+	assert.Equal(t, "opaque high", Child(FromValue(valueOpaque{1}, 0, LeafValuesOnly), "value").Value)
+}
@@ -0,0 +1,75 @@
+package nodes
+
+import "iter"
+
+// PreOrder returns an iterator over the nodes under node, each before the
+// nodes below it, in document order, with its depth. The search starts at the
+// children of a root, which is never yielded, at depth 0. Options choose the
+// children to follow, as for DFS, prune subtrees and cancel the walk.
+func PreOrder(node *Node, opts ...DFSOption) iter.Seq2[*Node, int] {
+	conf := newSearchConfig(opts)
+	return func(yield func(*Node, int) bool) {
+		if node == nil {
+			return
+		}
+		var walk func([]*Node, int) bool
+		walk = func(ns []*Node, depth int) bool {
+			for _, n := range ns {
+				if conf.err() != nil || !yield(n, depth) {
+					return false
+				}
+				if !walk(conf.next(n, depth), depth+1) {
+					return false
+				}
+			}
+			return true
+		}
+		walk(start(node), 0)
+	}
+}
+
+// PostOrder returns an iterator like PreOrder, except each node is yielded
+// after the nodes below it.
+func PostOrder(node *Node, opts ...DFSOption) iter.Seq2[*Node, int] {
+	conf := newSearchConfig(opts)
+	return func(yield func(*Node, int) bool) {
+		if node == nil {
+			return
+		}
+		var walk func([]*Node, int) bool
+		walk = func(ns []*Node, depth int) bool {
+			for _, n := range ns {
+				if !walk(conf.next(n, depth), depth+1) {
+					return false
+				}
+				if conf.err() != nil || !yield(n, depth) {
+					return false
+				}
+			}
+			return true
+		}
+		walk(start(node), 0)
+	}
+}
+
+// BFS returns an iterator like PreOrder, except the nodes are yielded a depth
+// at a time, each depth in document order.
+func BFS(node *Node, opts ...DFSOption) iter.Seq2[*Node, int] {
+	conf := newSearchConfig(opts)
+	return func(yield func(*Node, int) bool) {
+		if node == nil {
+			return
+		}
+		level := start(node)
+		for depth := 0; len(level) > 0; depth++ {
+			var below []*Node
+			for _, n := range level {
+				if conf.err() != nil || !yield(n, depth) {
+					return
+				}
+				below = append(below, conf.next(n, depth)...)
+			}
+			level = below
+		}
+	}
+}
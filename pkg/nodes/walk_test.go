@@ -0,0 +1,140 @@
+package nodes
+
+import (
+	"context"
+	"iter"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// visited is a node's key and depth as yielded by a walk.
+type visited struct {
+	Key   string
+	Depth int
+}
+
+func collectWalk(seq iter.Seq2[*Node, int]) []visited {
+	var out []visited
+	for n, depth := range seq {
+		out = append(out, visited{n.Key, depth})
+	}
+	return out
+}
+
+func TestWalk(t *testing.T) {
+	all := WithNextNodes(AllChildren)
+	tests := []struct {
+		name string
+		walk func(*Node, ...DFSOption) iter.Seq2[*Node, int]
+		opts []DFSOption
+		want []visited
+	}{
+		{
+			name: "pre-order obeys expand",
+			walk: PreOrder,
+			want: []visited{{"foo", 0}, {"bad", 1}, {"bar", 1}, {"baz", 2}},
+		},
+		{
+			name: "pre-order",
+			walk: PreOrder,
+			opts: []DFSOption{all},
+			want: []visited{{"foo", 0}, {"bad", 1}, {"unreached", 2}, {"bar", 1}, {"baz", 2}},
+		},
+		{
+			name: "post-order",
+			walk: PostOrder,
+			opts: []DFSOption{all},
+			want: []visited{{"unreached", 2}, {"bad", 1}, {"baz", 2}, {"bar", 1}, {"foo", 0}},
+		},
+		{
+			name: "breadth first",
+			walk: BFS,
+			opts: []DFSOption{all},
+			want: []visited{{"foo", 0}, {"bad", 1}, {"bar", 1}, {"unreached", 2}, {"baz", 2}},
+		},
+		{
+			name: "pruned",
+			walk: PreOrder,
+			opts: []DFSOption{all, WithPrune(func(n *Node, _ int) bool { return n.Key == "bad" })},
+			want: []visited{{"foo", 0}, {"bad", 1}, {"bar", 1}, {"baz", 2}},
+		},
+		{
+			name: "pruned by depth",
+			walk: BFS,
+			opts: []DFSOption{all, WithPrune(func(_ *Node, depth int) bool { return depth >= 1 })},
+			want: []visited{{"foo", 0}, {"bad", 1}, {"bar", 1}},
+		},
+		{
+			name: "post-order pruned",
+			walk: PostOrder,
+			opts: []DFSOption{all, WithPrune(func(n *Node, _ int) bool { return n.Key == "bar" })},
+			want: []visited{{"unreached", 2}, {"bad", 1}, {"bar", 1}, {"foo", 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, collectWalk(tt.walk(rootOf(testSearchTree()), tt.opts...)))
+		})
+	}
+
+	t.Run("starts at a non-root node", func(t *testing.T) {
+		root := New(map[string]any{"bar": map[string]any{"baz": "1"}}, 0, EmptyRepr)
+		assert.Equal(t, []visited{{"bar", 0}, {"baz", 1}}, collectWalk(PreOrder(Child(root, "bar"), WithNextNodes(AllChildren))))
+	})
+
+	t.Run("nil node", func(t *testing.T) {
+		assert.Empty(t, collectWalk(BFS(nil)))
+	})
+}
+
+func TestWalkStops(t *testing.T) {
+	for name, walk := range map[string]func(*Node, ...DFSOption) iter.Seq2[*Node, int]{
+		"pre-order":  PreOrder,
+		"post-order": PostOrder,
+		"bfs":        BFS,
+	} {
+		t.Run(name+" break", func(t *testing.T) {
+			count := 0
+			for range walk(rootOf(testSearchTree()), WithNextNodes(AllChildren)) {
+				count++
+				if count == 2 {
+					break
+				}
+			}
+			assert.Equal(t, 2, count)
+		})
+
+		t.Run(name+" cancelled", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			count := 0
+			for range walk(rootOf(testSearchTree()), WithNextNodes(AllChildren), WithContext(ctx)) {
+				count++
+				if count == 2 {
+					cancel()
+				}
+			}
+			assert.Equal(t, 2, count)
+			assert.Error(t, ctx.Err())
+		})
+	}
+}
+
+func TestDFSSkipChildren(t *testing.T) {
+	var keys []string
+	err := DFS(rootOf(testSearchTree()), func(n *Node, _ int) error {
+		keys = append(keys, n.Key)
+		if n.Key == "bad" {
+			return SkipChildren
+		}
+		return nil
+	}, WithNextNodes(AllChildren))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo", "bad", "bar", "baz"}, keys)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = DFS(rootOf(testSearchTree()), func(*Node, int) error { return nil }, WithContext(ctx))
+	assert.ErrorIs(t, err, context.Canceled)
+}
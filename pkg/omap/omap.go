@@ -4,16 +4,23 @@ package omap
 import (
 	"cmp"
 	"iter"
+	"slices"
 
 	"github.com/tidwall/btree"
 )
 
-// OMap is a map that keeps its keys in sorted order. It pairs a hash map (for
-// constant-time lookup) with a btree.Set over the keys (for ordered iteration),
-// so lookups stay fast without giving up a stable iteration order.
+// smallSize is the most entries an OMap keeps in its slice before moving them
+// to a hash map and btree.
+const smallSize = 16
+
+// OMap is a map that keeps its keys in sorted order. Up to smallSize entries
+// are kept in a slice sorted by key, found by binary search, which takes far
+// less memory than a map. Larger OMaps pair a hash map (for constant-time
+// lookup) with a btree.Set over the keys (for ordered iteration), so lookups
+// stay fast without giving up a stable iteration order.
 //
 // A zero-value OMap is usable but unordered; use New (ordered by default) when
-// you need sorted iteration.
+// you need sorted iteration. An empty OMap allocates nothing.
 //
 // Time complexity (n = number of entries):
 //
@@ -30,16 +37,32 @@ import (
 // Keys order by the built-in "<": numeric for integer keys, lexicographic for
 // strings.
 type OMap[K cmp.Ordered, V any] struct {
-	mp    map[K]V
-	keys  btree.Set[K]
+	// small holds the entries, sorted by key when ordered, until there are
+	// more than smallSize
+	small []entry[K, V]
+	// big holds the entries once there are more than smallSize; nil until
+	// then
+	big   *bigMap[K, V]
 	order bool
 }
 
+type entry[K cmp.Ordered, V any] struct {
+	key K
+	val V
+}
+
+// bigMap holds the entries of a large OMap
+type bigMap[K cmp.Ordered, V any] struct {
+	mp   map[K]V
+	keys btree.Set[K]
+}
+
 // options holds the settings applied by New's Option arguments. It is
 // non-generic so WithOrder needs no type arguments; New's type parameters are
 // supplied explicitly at the call site.
 type options struct {
-	order bool
+	order    bool
+	capacity int
 }
 
 // Option configures an OMap built by New.
@@ -52,6 +75,12 @@ func WithOrder(order bool) Option {
 	return func(o *options) { o.order = order }
 }
 
+// WithCapacity sizes the OMap for n entries, so they are added without it
+// growing.
+func WithCapacity(n int) Option {
+	return func(o *options) { o.capacity = n }
+}
+
 // New returns an empty OMap. It is ordered by default; pass WithOrder(false) to
 // skip the key btree for lower memory use. Seed it from a map with PutAll.
 //
@@ -61,26 +90,55 @@ func New[K cmp.Ordered, V any](opts ...Option) OMap[K, V] {
 	for _, opt := range opts {
 		opt(&cfg)
 	}
-	ret := OMap[K, V]{
-		mp:    make(map[K]V, 0),
-		order: cfg.order,
+	o := OMap[K, V]{order: cfg.order}
+	switch {
+	case cfg.capacity > smallSize:
+		o.big = &bigMap[K, V]{mp: make(map[K]V, cfg.capacity)}
+	case cfg.capacity > 0:
+		o.small = make([]entry[K, V], 0, cfg.capacity)
 	}
-	return ret
+	return o
 }
 
 // Len returns the number of entries.
 //
 // Complexity: O(1).
 func (o *OMap[K, V]) Len() int {
-	return len(o.mp)
+	if o.big != nil {
+		return len(o.big.mp)
+	}
+	return len(o.small)
+}
+
+// find returns the position of key in small, or where it would be inserted,
+// and whether it is there.
+func (o *OMap[K, V]) find(key K) (int, bool) {
+	if o.order {
+		return slices.BinarySearchFunc(o.small, key, func(e entry[K, V], key K) int {
+			return cmp.Compare(e.key, key)
+		})
+	}
+	for i, e := range o.small {
+		if e.key == key {
+			return i, true
+		}
+	}
+	return len(o.small), false
 }
 
 // Get returns the value stored for key and whether it was present.
 //
 // Complexity: O(1).
 func (o *OMap[K, V]) Get(key K) (V, bool) {
-	v, ok := o.mp[key]
-	return v, ok
+	if o.big != nil {
+		v, ok := o.big.mp[key]
+		return v, ok
+	}
+	if i, ok := o.find(key); ok {
+		return o.small[i].val, true
+	}
+	var zero V
+	return zero, false
 }
 
 // Put stores val under key, overwriting any existing value.
@@ -88,13 +146,32 @@ func (o *OMap[K, V]) Get(key K) (V, bool) {
 // Complexity: O(log n) — a constant-time map write plus a logarithmic btree
 // insert.
 func (o *OMap[K, V]) Put(key K, val V) {
-	if o.mp == nil {
-		o.mp = make(map[K]V)
+	if o.big != nil {
+		o.big.mp[key] = val
+		if o.order {
+			o.big.keys.Insert(key)
+		}
+		return
 	}
-	o.mp[key] = val
-	if o.order {
-		o.keys.Insert(key)
+	i, ok := o.find(key)
+	if ok {
+		o.small[i].val = val
+		return
 	}
+	if len(o.small) < smallSize {
+		o.small = slices.Insert(o.small, i, entry[K, V]{key: key, val: val})
+		return
+	}
+	// too large for the slice; move every entry to the map
+	o.big = &bigMap[K, V]{mp: make(map[K]V, len(o.small)+1)}
+	for _, e := range o.small {
+		o.big.mp[e.key] = e.val
+		if o.order {
+			o.big.keys.Insert(e.key)
+		}
+	}
+	o.small = nil
+	o.Put(key, val)
 }
 
 // PutAll stores every entry of m, overwriting any existing keys. A nil map is
@@ -114,14 +191,9 @@ func (o *OMap[K, V]) PutAll(m map[K]V) {
 //
 // Complexity: O(n) time, O(1) space.
 func (o *OMap[K, V]) Keys() iter.Seq[K] {
-	if o.order {
-		return func(yield func(K) bool) {
-			o.keys.Scan(yield) // Scan's callback IS func(K) bool
-		}
-	}
 	return func(yield func(K) bool) {
-		for key := range o.mp {
-			if !yield(key) {
+		for k := range o.Iter() {
+			if !yield(k) {
 				return
 			}
 		}
@@ -131,12 +203,25 @@ func (o *OMap[K, V]) Keys() iter.Seq[K] {
 // Iter returns an iterator over the key/value pairs in ascending key order.
 //
 // Complexity: O(1) to obtain the iterator; consuming it is O(n) time and O(1)
-// space (it streams over the key btree without allocating a snapshot).
+// space (it streams over the keys without allocating a snapshot).
 func (o *OMap[K, V]) Iter() iter.Seq2[K, V] {
 	return func(yield func(K, V) bool) {
-		for key := range o.Keys() {
-			if !yield(key, o.mp[key]) {
-				return
+		switch {
+		case o.big == nil:
+			for _, e := range o.small {
+				if !yield(e.key, e.val) {
+					return
+				}
+			}
+		case o.order:
+			o.big.keys.Scan(func(key K) bool {
+				return yield(key, o.big.mp[key])
+			})
+		default:
+			for key, val := range o.big.mp {
+				if !yield(key, val) {
+					return
+				}
 			}
 		}
 	}
@@ -160,8 +245,14 @@ func (o *OMap[K, V]) Arr() []V {
 //
 // Complexity: O(log n).
 func (o *OMap[K, V]) Delete(key K) {
-	delete(o.mp, key)
-	if o.order {
-		o.keys.Delete(key)
+	if o.big != nil {
+		delete(o.big.mp, key)
+		if o.order {
+			o.big.keys.Delete(key)
+		}
+		return
+	}
+	if i, ok := o.find(key); ok {
+		o.small = slices.Delete(o.small, i, i+1)
 	}
 }
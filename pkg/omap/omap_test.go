@@ -223,3 +223,31 @@ func TestOrderingStringKeysLexicographic(t *testing.T) {
 	o := seed(true, map[string]int{"0": 0, "1": 1, "2": 2, "10": 10})
 	assert.Equal(t, []int{0, 1, 10, 2}, vals(&o))
 }
+
+// TestGrowPastSmall checks that entries keep their order and can still be
+// found and deleted once there are too many for the slice.
+func TestGrowPastSmall(t *testing.T) {
+	for _, capacity := range []int{0, smallSize, 4 * smallSize} {
+		for _, mode := range orderModes {
+			o := New[int, int](WithOrder(mode.order), WithCapacity(capacity))
+			want := make([]int, 0, 3*smallSize)
+			for i := 3*smallSize - 1; i >= 0; i-- {
+				o.Put(i, i*10)
+				want = append([]int{i * 10}, want...)
+			}
+			assert.Equal(t, 3*smallSize, o.Len(), mode.name)
+			if mode.order {
+				assert.Equal(t, want, vals(&o), mode.name)
+			} else {
+				assert.ElementsMatch(t, want, vals(&o), mode.name)
+			}
+			got, ok := o.Get(smallSize + 1)
+			assert.True(t, ok, mode.name)
+			assert.Equal(t, (smallSize+1)*10, got, mode.name)
+			o.Delete(smallSize + 1)
+			_, ok = o.Get(smallSize + 1)
+			assert.False(t, ok, mode.name)
+			assert.Equal(t, 3*smallSize-1, o.Len(), mode.name)
+		}
+	}
+}
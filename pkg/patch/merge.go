@@ -0,0 +1,25 @@
+package patch
+
+// Merge returns doc with the RFC 7386 merge patch applied, leaving doc
+// unchanged. Objects in the patch are merged into doc key by key, a null
+// removes a key, and any other value replaces what was there.
+func Merge(doc, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return clone(patch)
+	}
+	m := map[string]any{}
+	if d, ok := doc.(map[string]any); ok {
+		for k, v := range d {
+			m[k] = clone(v)
+		}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(m, k)
+			continue
+		}
+		m[k] = Merge(m[k], v)
+	}
+	return m
+}
@@ -0,0 +1,377 @@
+// Package patch applies RFC 6902 JSON Patch and RFC 7386 JSON Merge Patch
+// documents to decoded data: the maps, arrays and scalars the format parsers
+// produce.
+//
+// A JSON Patch is read with Parse and applied with Patch.Apply, which either
+// applies every operation or none; a failing operation is reported as an
+// *OpError giving its index in the patch and its path. A merge patch is read
+// with Decode and applied with Merge.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	yaml "github.com/goccy/go-yaml"
+)
+
+var (
+	// ErrInvalid is returned for patches and operations that are malformed,
+	// such as an unknown op or an add without a value
+	ErrInvalid = errors.New("invalid patch")
+	// ErrTestFailed is returned when a test operation finds a different value
+	ErrTestFailed = errors.New("test failed")
+)
+
+// Operation is one step of a JSON Patch. Paths are JSON Pointers.
+type Operation struct {
+	Op    string
+	Path  string
+	From  string
+	Value any
+}
+
+// Patch is a JSON Patch: operations applied in order.
+type Patch []Operation
+
+// OpError is returned when an operation of a patch cannot be read or applied.
+type OpError struct {
+	// Index is the position of the operation in the patch, from 0
+	Index int
+	Op    Operation
+	Err   error
+}
+
+func (e *OpError) Error() string {
+	return fmt.Sprintf("operation %d (%s %s): %v", e.Index, e.Op.Op, e.Op.Path, e.Err)
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// Decode reads a patch document, which may be JSON or YAML. Integers are
+// decoded as int64 rather than float64 so they are written back unchanged.
+func Decode(b []byte) (any, error) {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		if yerr := yaml.Unmarshal(b, &v); yerr != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+	}
+	return numbers(v), nil
+}
+
+// numbers returns v with json.Number and unsigned integers converted to int64
+// or float64.
+func numbers(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = numbers(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = numbers(e)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v)
+		}
+	}
+	return v
+}
+
+// Parse reads a JSON Patch: an array of operations, in JSON or YAML.
+func Parse(b []byte) (Patch, error) {
+	v, err := Decode(b)
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: a JSON Patch is an array of operations", ErrInvalid)
+	}
+	p := make(Patch, len(arr))
+	for i, e := range arr {
+		obj, ok := e.(map[string]any)
+		if !ok {
+			return nil, &OpError{Index: i, Err: fmt.Errorf("%w: an operation is an object", ErrInvalid)}
+		}
+		op, err := operation(obj)
+		if err != nil {
+			return nil, &OpError{Index: i, Op: op, Err: err}
+		}
+		p[i] = op
+	}
+	return p, nil
+}
+
+// operation reads an operation object, checking it has the members its op
+// needs.
+func operation(obj map[string]any) (Operation, error) {
+	var op Operation
+	var ok bool
+	if op.Op, ok = obj["op"].(string); !ok {
+		return op, fmt.Errorf("%w: missing op", ErrInvalid)
+	}
+	if op.Path, ok = obj["path"].(string); !ok {
+		return op, fmt.Errorf("%w: missing path", ErrInvalid)
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value, ok = obj["value"]; !ok {
+			return op, fmt.Errorf("%w: missing value", ErrInvalid)
+		}
+	case "move", "copy":
+		if op.From, ok = obj["from"].(string); !ok {
+			return op, fmt.Errorf("%w: missing from", ErrInvalid)
+		}
+	case "remove":
+	default:
+		return op, fmt.Errorf("%w: unknown op %q", ErrInvalid, op.Op)
+	}
+	return op, nil
+}
+
+// Apply returns doc with every operation applied in order, leaving doc
+// unchanged. When an operation fails none are applied and the error is an
+// *OpError.
+func (p Patch) Apply(doc any) (any, error) {
+	doc = clone(doc)
+	for i, op := range p {
+		var err error
+		if doc, err = op.apply(doc); err != nil {
+			return nil, &OpError{Index: i, Op: op, Err: err}
+		}
+	}
+	return doc, nil
+}
+
+func (o Operation) apply(doc any) (any, error) {
+	path, err := nodes.ParsePath(o.Path, nodes.PointerSyntax)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	switch o.Op {
+	case "add":
+		return add(doc, path, clone(o.Value))
+	case "remove":
+		return remove(doc, path)
+	case "replace":
+		return replace(doc, path, clone(o.Value))
+	case "test":
+		v, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(v, o.Value) {
+			b, _ := json.Marshal(v)
+			return nil, fmt.Errorf("%w: value is %s", ErrTestFailed, b)
+		}
+		return doc, nil
+	}
+	from, err := nodes.ParsePath(o.From, nodes.PointerSyntax)
+	if err != nil {
+		return nil, fmt.Errorf("%w: from: %v", ErrInvalid, err)
+	}
+	v, err := get(doc, from)
+	if err != nil {
+		return nil, fmt.Errorf("from %s: %w", o.From, err)
+	}
+	if o.Op == "copy" {
+		return add(doc, path, clone(v))
+	}
+	if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+		return nil, fmt.Errorf("%w: cannot move a value into itself", ErrInvalid)
+	}
+	if doc, err = remove(doc, from); err != nil {
+		return nil, err
+	}
+	return add(doc, path, v)
+}
+
+// at returns doc with the container holding the last step of path replaced by
+// what fn returns for it.
+func at(doc any, path nodes.Path, fn func(container any, key string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0].Key)
+	}
+	switch c := doc.(type) {
+	case map[string]any:
+		child, ok := c[path[0].Key]
+		if !ok {
+			return nil, nodes.ErrNotFound
+		}
+		v, err := at(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		c[path[0].Key] = v
+		return c, nil
+	case []any:
+		i, err := index(path[0].Key, len(c))
+		if err != nil {
+			return nil, err
+		}
+		v, err := at(c[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		c[i] = v
+		return c, nil
+	}
+	return nil, nodes.ErrNotFound
+}
+
+func get(doc any, path nodes.Path) (any, error) {
+	for _, e := range path {
+		switch c := doc.(type) {
+		case map[string]any:
+			var ok bool
+			if doc, ok = c[e.Key]; !ok {
+				return nil, nodes.ErrNotFound
+			}
+		case []any:
+			i, err := index(e.Key, len(c))
+			if err != nil {
+				return nil, err
+			}
+			doc = c[i]
+		default:
+			return nil, nodes.ErrNotFound
+		}
+	}
+	return doc, nil
+}
+
+func add(doc any, path nodes.Path, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return at(doc, path, func(container any, key string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			c[key] = value
+			return c, nil
+		case []any:
+			if key == "-" {
+				return append(c, value), nil
+			}
+			i, err := index(key, len(c)+1)
+			if err != nil {
+				return nil, err
+			}
+			return slices.Insert(c, i, value), nil
+		}
+		return nil, fmt.Errorf("%w: cannot add to a scalar", ErrInvalid)
+	})
+}
+
+func remove(doc any, path nodes.Path) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalid)
+	}
+	return at(doc, path, func(container any, key string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			if _, ok := c[key]; !ok {
+				return nil, nodes.ErrNotFound
+			}
+			delete(c, key)
+			return c, nil
+		case []any:
+			i, err := index(key, len(c))
+			if err != nil {
+				return nil, err
+			}
+			return slices.Delete(c, i, i+1), nil
+		}
+		return nil, nodes.ErrNotFound
+	})
+}
+
+func replace(doc any, path nodes.Path, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return at(doc, path, func(container any, key string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			if _, ok := c[key]; !ok {
+				return nil, nodes.ErrNotFound
+			}
+			c[key] = value
+			return c, nil
+		case []any:
+			i, err := index(key, len(c))
+			if err != nil {
+				return nil, err
+			}
+			c[i] = value
+			return c, nil
+		}
+		return nil, nodes.ErrNotFound
+	})
+}
+
+// index reads an array index, which must be below n. RFC 6901 allows only
+// digits without leading zeros.
+func index(key string, n int) (int, error) {
+	i, err := strconv.Atoi(key)
+	if err != nil || key[0] < '0' || key[0] > '9' || (key[0] == '0' && len(key) > 1) {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrInvalid, key)
+	}
+	if i >= n {
+		return 0, fmt.Errorf("%w: index %d is past the end of the array", nodes.ErrNotFound, i)
+	}
+	return i, nil
+}
+
+// equal reports whether a and b hold the same data, ignoring differences in
+// the Go types decoders pick for numbers.
+func equal(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	var na, nb any
+	_ = json.Unmarshal(ja, &na)
+	_ = json.Unmarshal(jb, &nb)
+	return reflect.DeepEqual(na, nb)
+}
+
+// clone returns a copy of v that shares no maps or arrays with it.
+func clone(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = clone(e)
+		}
+		return m
+	case []any:
+		arr := make([]any, len(v))
+		for i, e := range v {
+			arr[i] = clone(e)
+		}
+		return arr
+	}
+	return v
+}
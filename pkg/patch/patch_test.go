@@ -0,0 +1,212 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{
+			name:  "add member",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			want:  `{"baz": "qux", "foo": "bar"}`,
+		},
+		{
+			name:  "add array element",
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: `[{"op": "add", "path": "/foo/1", "value": "qux"}, {"op": "add", "path": "/foo/-", "value": 1}]`,
+			want:  `{"foo": ["bar", "qux", "baz", 1]}`,
+		},
+		{
+			name:  "remove",
+			doc:   `{"baz": "qux", "foo": ["a", "b", "c"]}`,
+			patch: `[{"op": "remove", "path": "/baz"}, {"op": "remove", "path": "/foo/1"}]`,
+			want:  `{"foo": ["a", "c"]}`,
+		},
+		{
+			name:  "replace",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "replace", "path": "/baz", "value": null}]`,
+			want:  `{"baz": null, "foo": "bar"}`,
+		},
+		{
+			name:  "move",
+			doc:   `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch: `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			want:  `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{
+			name:  "move array element",
+			doc:   `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch: `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			want:  `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		{
+			name:  "copy and test",
+			doc:   `{"a": {"b": [1, 2]}}`,
+			patch: `[{"op": "copy", "from": "/a", "path": "/c"}, {"op": "test", "path": "/c/b", "value": [1, 2]}, {"op": "add", "path": "/c/b/-", "value": 3}]`,
+			want:  `{"a": {"b": [1, 2]}, "c": {"b": [1, 2, 3]}}`,
+		},
+		{
+			name:  "escaped keys",
+			doc:   `{"a/b": 1, "m~n": 2}`,
+			patch: `[{"op": "test", "path": "/a~1b", "value": 1}, {"op": "remove", "path": "/m~0n"}]`,
+			want:  `{"a/b": 1}`,
+		},
+		{
+			name:  "replace the document",
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "replace", "path": "", "value": [1]}]`,
+			want:  `[1]`,
+		},
+		{
+			name:  "yaml patch",
+			doc:   `{"replicas": 1}`,
+			patch: "- op: replace\n  path: /replicas\n  value: 3\n",
+			want:  `{"replicas": 3}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Decode([]byte(tt.doc))
+			require.NoError(t, err)
+			p, err := Parse([]byte(tt.patch))
+			require.NoError(t, err)
+			got, err := p.Apply(doc)
+			require.NoError(t, err)
+			want, err := Decode([]byte(tt.want))
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestApplyLeavesDocUnchanged(t *testing.T) {
+	doc := map[string]any{"a": []any{int64(1)}}
+	p, err := Parse([]byte(`[{"op": "add", "path": "/a/0", "value": 0}, {"op": "remove", "path": "/b"}]`))
+	require.NoError(t, err)
+	_, err = p.Apply(doc)
+	require.Error(t, err)
+	assert.Equal(t, map[string]any{"a": []any{int64(1)}}, doc)
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		index int
+		want  error
+		msg   string
+	}{
+		{
+			name:  "missing member",
+			patch: `[{"op": "test", "path": "/a", "value": 1}, {"op": "remove", "path": "/x/y"}]`,
+			index: 1,
+			want:  nodes.ErrNotFound,
+			msg:   "operation 1 (remove /x/y): no node at path",
+		},
+		{
+			name:  "failed test",
+			patch: `[{"op": "test", "path": "/a", "value": 2}]`,
+			want:  ErrTestFailed,
+			msg:   "operation 0 (test /a): test failed: value is 1",
+		},
+		{
+			name:  "index past the end",
+			patch: `[{"op": "add", "path": "/list/3", "value": 1}]`,
+			want:  nodes.ErrNotFound,
+		},
+		{
+			name:  "leading zero",
+			patch: `[{"op": "replace", "path": "/list/01", "value": 1}]`,
+			want:  ErrInvalid,
+		},
+		{
+			name:  "move into itself",
+			patch: `[{"op": "move", "from": "/list", "path": "/list/0"}]`,
+			want:  ErrInvalid,
+		},
+		{
+			name:  "missing from",
+			patch: `[{"op": "copy", "from": "/nope", "path": "/b"}]`,
+			want:  nodes.ErrNotFound,
+			msg:   "operation 0 (copy /b): from /nope: no node at path",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse([]byte(tt.patch))
+			require.NoError(t, err)
+			_, err = p.Apply(map[string]any{"a": int64(1), "list": []any{"x", "y"}})
+			var opErr *OpError
+			require.ErrorAs(t, err, &opErr)
+			assert.Equal(t, tt.index, opErr.Index)
+			assert.ErrorIs(t, err, tt.want)
+			if tt.msg != "" {
+				assert.Equal(t, tt.msg, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, patch := range []string{
+		`{"op": "add"}`,
+		`[{"op": "add", "path": "/a"}]`,
+		`[{"op": "move", "path": "/a"}]`,
+		`[{"op": "frobnicate", "path": "/a"}]`,
+		`[{"path": "/a"}]`,
+		`[1]`,
+		`[`,
+	} {
+		_, err := Parse([]byte(patch))
+		assert.ErrorIs(t, err, ErrInvalid, patch)
+	}
+	_, err := Parse([]byte(`[{"op": "remove", "path": "/a"}, {"op": "add", "path": "/b"}]`))
+	var opErr *OpError
+	require.ErrorAs(t, err, &opErr)
+	assert.Equal(t, 1, opErr.Index)
+}
+
+func TestMerge(t *testing.T) {
+	// the examples from RFC 7386 appendix A
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.patch, func(t *testing.T) {
+			doc, err := Decode([]byte(tt.doc))
+			require.NoError(t, err)
+			patch, err := Decode([]byte(tt.patch))
+			require.NoError(t, err)
+			want, err := Decode([]byte(tt.want))
+			require.NoError(t, err)
+			assert.Equal(t, want, Merge(doc, patch))
+		})
+	}
+}
@@ -0,0 +1,312 @@
+// Package redact masks sensitive values, such as passwords and access keys, so
+// they are not shown on screen or printed.
+package redact
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/crosleyzack/wndr/pkg/nodes"
+)
+
+// Mask is shown in place of a redacted value.
+const Mask = "[redacted]"
+
+// RedactConfig holds the redaction rules. A value is redacted when any rule
+// matches it; a rule matching an object or array redacts every value in it.
+type RedactConfig struct {
+	// RedactCommon adds rules for common secrets: keys ending in password,
+	// secret, token, apikey or credentials, and values that are AWS access
+	// key IDs, JWTs, PEM private keys, or GitHub or Slack tokens
+	RedactCommon bool
+	// RedactKeys are regular expressions matched against every key on the
+	// path to a value
+	RedactKeys []string
+	// RedactPaths are globs, as parsed by nodes.ParseGlob, matched against
+	// the path to a value
+	RedactPaths []string
+	// RedactValues are regular expressions matched against values
+	RedactValues []string
+	// RedactHighEntropy redacts values that look randomly generated, as
+	// keys and tokens do
+	RedactHighEntropy bool
+}
+
+// commonKeys and commonValues are the rules added by RedactCommon.
+var (
+	commonKeys   = []string{`(?i)(password|passwd|secret|token|api[_-]?key|private[_-]?key|credentials?)$`}
+	commonValues = []string{
+		// AWS access key IDs
+		`\b(AKIA|ASIA)[0-9A-Z]{16}\b`,
+		// JWTs
+		`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`,
+		`-----BEGIN [A-Z ]*PRIVATE KEY-----`,
+		// GitHub tokens
+		`\bgh[pousr]_[A-Za-z0-9]{36,}\b`,
+		// Slack tokens
+		`\bxox[abposr]-[A-Za-z0-9-]+`,
+	}
+)
+
+// Redactor decides which values to redact. A nil Redactor redacts nothing.
+type Redactor struct {
+	keys    []*regexp.Regexp
+	paths   []nodes.Glob
+	values  []*regexp.Regexp
+	entropy bool
+	// ignored are keys left out of paths before they are matched
+	ignored []string
+}
+
+// New returns a Redactor for the rules in c, or nil when c has none.
+func New(c *RedactConfig) (*Redactor, error) {
+	keys, values := c.RedactKeys, c.RedactValues
+	if c.RedactCommon {
+		keys = append(append([]string{}, commonKeys...), keys...)
+		values = append(append([]string{}, commonValues...), values...)
+	}
+	if len(keys) == 0 && len(values) == 0 && len(c.RedactPaths) == 0 && !c.RedactHighEntropy {
+		return nil, nil
+	}
+	r := &Redactor{entropy: c.RedactHighEntropy}
+	for _, k := range keys {
+		re, err := regexp.Compile(k)
+		if err != nil {
+			return nil, fmt.Errorf("redact key %q: %w", k, err)
+		}
+		r.keys = append(r.keys, re)
+	}
+	for _, v := range values {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, fmt.Errorf("redact value %q: %w", v, err)
+		}
+		r.values = append(r.values, re)
+	}
+	for _, p := range c.RedactPaths {
+		glob, err := nodes.ParseGlob(p)
+		if err != nil {
+			return nil, fmt.Errorf("redact path: %w", err)
+		}
+		r.paths = append(r.paths, glob)
+	}
+	return r, nil
+}
+
+// Ignoring returns a Redactor like r that leaves keys out of paths before
+// matching them, such as the keys labelling each input of a diff tree, so
+// paths in the tree are matched as they were in the inputs.
+func (r *Redactor) Ignoring(keys ...string) *Redactor {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	c.ignored = keys
+	return &c
+}
+
+// HidesPath reports whether everything at p, a path of keys from the root, is
+// redacted, as its key or one above it, or the path itself, matches a rule.
+func (r *Redactor) HidesPath(p []string) bool {
+	if r == nil {
+		return false
+	}
+	if len(r.ignored) > 0 {
+		p = slices.DeleteFunc(slices.Clone(p), func(key string) bool {
+			return slices.Contains(r.ignored, key)
+		})
+	}
+	for _, key := range p {
+		for _, re := range r.keys {
+			if re.MatchString(key) {
+				return true
+			}
+		}
+	}
+	for _, glob := range r.paths {
+		if glob.MatchPrefix(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hides reports whether the value at p is redacted.
+func (r *Redactor) Hides(p []string, value string) bool {
+	if r == nil {
+		return false
+	}
+	if r.HidesPath(p) {
+		return true
+	}
+	if value == "" {
+		return false
+	}
+	for _, re := range r.values {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return r.entropy && random(value)
+}
+
+// minRandomLength and minRandomEntropy are how long a value, and how many bits
+// of entropy per character it has, for it to look randomly generated. Hex
+// strings, such as UUIDs and digests, have at most 4 bits per character and
+// so are not redacted.
+const (
+	minRandomLength  = 20
+	minRandomEntropy = 4.2
+)
+
+// random reports whether s looks randomly generated: long, without spaces,
+// with a high Shannon entropy.
+func random(s string) bool {
+	if len(s) < minRandomLength || strings.ContainsAny(s, " \t\n") || strings.Contains(s, "://") {
+		return false
+	}
+	counts := map[rune]int{}
+	n := 0
+	for _, c := range s {
+		counts[c]++
+		n++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= minRandomEntropy
+}
+
+// hidden returns the leaves under n, or n itself, that are redacted.
+func (r *Redactor) hidden(n *nodes.Node) []*nodes.Node {
+	var out []*nodes.Node
+	for c := range nodes.PreOrder(n, nodes.WithNextNodes(nodes.AllChildren)) {
+		if nodes.IsLeaf(c) && c.Value != Mask && r.Hides(nodes.GetPathToNode(c), c.Value) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Apply redacts the values in the tree under n in place, and rebuilds the
+// summaries above them with repr, or nodes.LeafValuesOnly when repr is nil.
+func (r *Redactor) Apply(n *nodes.Node, repr nodes.ReprNode) {
+	if r == nil {
+		return
+	}
+	if repr == nil {
+		repr = nodes.LeafValuesOnly
+	}
+	stale := map[*nodes.Node]bool{}
+	for _, leaf := range r.hidden(n) {
+		leaf.Value = Mask
+		nodes.Invalidate(leaf)
+		for p := leaf.Parent; p != nil && !nodes.IsRoot(p) && !stale[p]; p = p.Parent {
+			stale[p] = true
+		}
+	}
+	for c := range nodes.PostOrder(n, nodes.WithNextNodes(nodes.AllChildren)) {
+		if stale[c] {
+			c.Value = repr(c)
+		}
+	}
+}
+
+// Summary returns the value to show for n: a leaf's value or Mask, or the
+// summary of an object or array built with repr, or nodes.LeafValuesOnly when
+// repr is nil, with its redacted values masked. It also reports whether
+// anything was masked.
+func (r *Redactor) Summary(n *nodes.Node, repr nodes.ReprNode) (string, bool) {
+	if repr == nil {
+		repr = nodes.LeafValuesOnly
+	}
+	if nodes.IsLeaf(n) {
+		if r.Hides(nodes.GetPathToNode(n), n.Value) {
+			return Mask, true
+		}
+		return n.Value, false
+	}
+	leaves := r.hidden(n)
+	if len(leaves) == 0 {
+		return repr(n), false
+	}
+	// mask the leaves only while the summary is built; they are put back as
+	// they were, so the cached hashes still hold
+	values := make([]string, len(leaves))
+	for i, leaf := range leaves {
+		values[i], leaf.Value = leaf.Value, Mask
+	}
+	value := repr(n)
+	for i, leaf := range leaves {
+		leaf.Value = values[i]
+	}
+	return value, true
+}
+
+// View returns Summary for each node of a tree summarized with repr, except
+// that a container with nothing masked keeps the summary it was built with.
+// The summaries are kept until the nodes under them change.
+func (r *Redactor) View(repr nodes.ReprNode) func(n *nodes.Node) (string, bool) {
+	type summary struct {
+		hash nodes.Hash
+		path string
+		// orig is the summary n had, which changes with the format
+		orig   string
+		value  string
+		hidden bool
+	}
+	summaries := map[*nodes.Node]summary{}
+	return func(n *nodes.Node) (string, bool) {
+		if nodes.IsLeaf(n) {
+			return r.Summary(n, repr)
+		}
+		key := strings.Join(nodes.GetPathToNode(n), "\x00")
+		if s, ok := summaries[n]; ok && s.hash == n.Hash() && s.path == key && s.orig == n.Value {
+			return s.value, s.hidden
+		}
+		s := summary{hash: n.Hash(), path: key, orig: n.Value, value: n.Value}
+		if len(r.hidden(n)) > 0 {
+			s.value, s.hidden = r.Summary(n, repr)
+		}
+		summaries[n] = s
+		return s.value, s.hidden
+	}
+}
+
+// Data returns a copy of v, decoded data found at p, with its redacted values
+// replaced by Mask.
+func (r *Redactor) Data(p []string, v any) any {
+	if r == nil {
+		return v
+	}
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for key, child := range t {
+			out[key] = r.Data(append(p, key), child)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, child := range t {
+			out[i] = r.Data(append(p, strconv.Itoa(i)), child)
+		}
+		return out
+	case nil:
+		if r.HidesPath(p) {
+			return Mask
+		}
+		return nil
+	default:
+		if r.Hides(p, fmt.Sprint(t)) {
+			return Mask
+		}
+		return v
+	}
+}
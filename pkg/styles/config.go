@@ -14,6 +14,7 @@ type StyleConfig struct {
 	SelectedForegroundColor   string
 	SelectedBackgroundColor   string
 	UnselectedForegroundColor string
+	ChangedColor              string
 	HelpColor                 string
 	DiffColors                []string
 }
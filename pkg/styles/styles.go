@@ -16,6 +16,7 @@ const (
 	dark_orange = lipgloss.Color("#cc8e55")
 	red         = lipgloss.Color("#ad0116")
 	green       = lipgloss.Color("#006222")
+	yellow      = lipgloss.Color("#e5c07b")
 )
 
 type Style struct {
@@ -24,6 +25,7 @@ type Style struct {
 	ExpandableStyle lipgloss.Style
 	Selected        lipgloss.Style
 	Unselected      lipgloss.Style
+	Changed         lipgloss.Style
 	Help            lipgloss.Style
 	KeyBasedStyles  map[string]lipgloss.Style
 }
@@ -51,6 +53,9 @@ func NewStyle(c *StyleConfig) Style {
 		fmt.Printf("UnselectedForegroundColor: %s\n", c.UnselectedForegroundColor)
 		style.Unselected = style.Unselected.Foreground(lipgloss.Color(c.UnselectedForegroundColor))
 	}
+	if c.ChangedColor != "" {
+		style.Changed = style.Changed.Foreground(lipgloss.Color(c.ChangedColor))
+	}
 	if c.HelpColor != "" {
 		style.Help = style.Help.Foreground(lipgloss.Color(c.HelpColor))
 	}
@@ -70,6 +75,7 @@ func DefaultStyles() Style {
 		ExpandableStyle: lipgloss.NewStyle().Margin(0, 0, 0, 0).Foreground(dark_orange),
 		Selected:        lipgloss.NewStyle().Margin(0, 0, 0, 0).Background(blue).Foreground(white),
 		Unselected:      lipgloss.NewStyle().Margin(0, 0, 0, 0).Foreground(white).Faint(true),
+		Changed:         lipgloss.NewStyle().Margin(0, 0, 0, 0).Foreground(yellow).Bold(true),
 		Help:            lipgloss.NewStyle().Margin(0, 0, 0, 0).Foreground(lipgloss.AdaptiveColor{Light: "#000000", Dark: "#ffffff"}),
 		KeyBasedStyles:  make(map[string]lipgloss.Style, 0),
 	}
@@ -31,6 +31,7 @@ func TestNewStyleOverrides(t *testing.T) {
 		SelectedForegroundColor:   "#ffffff",
 		SelectedBackgroundColor:   "#000000",
 		UnselectedForegroundColor: "#aaaaaa",
+		ChangedColor:              "#cccccc",
 		HelpColor:                 "#bbbbbb",
 	})
 	assert.Equal(t, lipgloss.Color("#ff0000"), s.LeafStyle.GetForeground())
@@ -39,6 +40,7 @@ func TestNewStyleOverrides(t *testing.T) {
 	assert.Equal(t, lipgloss.Color("#ffffff"), s.Selected.GetForeground())
 	assert.Equal(t, lipgloss.Color("#000000"), s.Selected.GetBackground())
 	assert.Equal(t, lipgloss.Color("#aaaaaa"), s.Unselected.GetForeground())
+	assert.Equal(t, lipgloss.Color("#cccccc"), s.Changed.GetForeground())
 	assert.Equal(t, lipgloss.Color("#bbbbbb"), s.Help.GetForeground())
 }
 
@@ -11,6 +11,7 @@ import (
 	"github.com/Netflix/go-env"
 	"github.com/crosleyzack/wndr/pkg/keys"
 	"github.com/crosleyzack/wndr/pkg/modules/tree"
+	"github.com/crosleyzack/wndr/pkg/redact"
 	"github.com/crosleyzack/wndr/pkg/styles"
 )
 
@@ -23,6 +24,7 @@ type Config struct {
 	styles.StyleConfig
 	keys.KeyConfig
 	tree.TreeConfig
+	redact.RedactConfig
 }
 
 const (
@@ -3,6 +3,7 @@ package tui
 
 import (
 	"os"
+	"time"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
@@ -12,6 +13,7 @@ import (
 	"github.com/crosleyzack/wndr/pkg/keys"
 	"github.com/crosleyzack/wndr/pkg/modules/tree"
 	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/crosleyzack/wndr/pkg/redact"
 	"github.com/crosleyzack/wndr/pkg/styles"
 )
 
@@ -22,15 +24,123 @@ type Model struct {
 	TreeView   *tree.Model
 	HelpView   help.Model
 	SearchView textinput.Model
+	// GoToView is the prompt for a JSONPath or JSON Pointer to go to
+	GoToView textinput.Model
+	// EditView is the prompt for a value or key in edit mode
+	EditView textinput.Model
 
 	width  int
 	height int
+	// load rebuilds the tree on refresh; nil when the data cannot be reloaded
+	load Loader
+	// interval between automatic refreshes; zero disables them
+	interval   time.Duration
+	refreshing bool
+	// changed reports whether the source data changed since it was last
+	// called; nil when nothing is watched
+	changed Watcher
+	// pollInterval is how often changed is polled
+	pollInterval time.Duration
+	// highlights counts reloads so a stale clearChangedMsg is ignored
+	highlights int
+	// status is a one line message shown under the tree, such as the outcome
+	// of the last refresh
+	status string
+	// editAction is what the EditView input is for
+	editAction editAction
+	// addKey is the key of the node being added while its value is prompted
+	addKey string
+	// save writes the tree back to where it was read from; nil when it
+	// cannot be written back
+	save Saver
+	// saveName names where save writes, for the confirmation prompt
+	saveName string
+	// confirmingSave is set while waiting for the save to be confirmed
+	confirmingSave bool
+	// confirmingQuit is set after quitting with unsaved edits was refused
+	// once; quitting again discards them
+	confirmingQuit bool
+	// annotate gives the notes shown after values; nil for none
+	annotate tree.Annotator
+	// redactor masks sensitive values; nil when nothing is masked
+	redactor *redact.Redactor
+	// warnings gives the warnings about the data; nil for none
+	warnings func() []Warning
+	// issues are the warnings about the tree shown
+	issues []Warning
+	// warned holds the warnings about each node
+	warned map[*nodes.Node][]Warning
+	// showWarnings lists the warnings under the tree
+	showWarnings bool
+	// warningsPage is the page of warnings listed, of maxWarningLines each
+	warningsPage int
+}
+
+// editAction is what the text typed into the EditView is used for
+type editAction int
+
+const (
+	editValue editAction = iota
+	editRename
+	editAddKey
+	editAddValue
+)
+
+// Loader produces a fresh tree, such as by re-running a command or re-reading
+// a file.
+type Loader func() (*nodes.Node, error)
+
+// Watcher reports whether the data behind the tree changed since the last
+// call.
+type Watcher func() (bool, error)
+
+// Saver writes an edited tree back to where it was read from. note is shown
+// with the saved message when it is set, such as to warn that the file's
+// layout could not be kept.
+type Saver func(root *nodes.Node) (note string, err error)
+
+// Option configures a Model built by New.
+type Option func(*Model)
+
+// WithRefresh reloads the tree with load when the refresh key is pressed and,
+// when interval is positive, every interval. Expanded nodes and the cursor
+// position are kept across reloads.
+func WithRefresh(load Loader, interval time.Duration) Option {
+	return func(m *Model) {
+		m.load = load
+		m.interval = interval
+	}
+}
+
+// WithSave lets edits be written back with save, which writes to name.
+func WithSave(name string, save Saver) Option {
+	return func(m *Model) {
+		m.saveName = name
+		m.save = save
+	}
+}
+
+// WithAnnotations shows the note given by annotate after each value, such as
+// where the value came from. The shadowed key switches the notes to their
+// detailed form.
+func WithAnnotations(annotate tree.Annotator) Option {
+	return func(m *Model) {
+		m.annotate = annotate
+	}
+}
+
+// WithRedaction masks the values r redacts, on screen and in copied text. The
+// reveal key shows them on screen.
+func WithRedaction(r *redact.Redactor) Option {
+	return func(m *Model) {
+		m.redactor = r
+	}
 }
 
 var _ tea.Model = &Model{}
 
 // New creates a new Model for the TUI
-func New(format *tree.TreeFormat, keymap keys.KeyMap, style styles.Style, nodes *nodes.Node) (*Model, error) {
+func New(format *tree.TreeFormat, keymap keys.KeyMap, style styles.Style, nodes *nodes.Node, opts ...Option) (*Model, error) {
 	w, h, err := term.GetSize(os.Stdout.Fd())
 	if err != nil {
 		return nil, err
@@ -40,15 +150,39 @@ func New(format *tree.TreeFormat, keymap keys.KeyMap, style styles.Style, nodes
 	treeView := tree.New(format, keymap, style, nodes)
 	helpView := help.New()
 	searchView := textinput.New()
-	return &Model{
+	goToView := textinput.New()
+	goToView.Prompt = "path: "
+	editView := textinput.New()
+	m := &Model{
 		KeyMap:     keymap,
 		Styles:     style,
 		TreeView:   treeView,
 		HelpView:   helpView,
 		SearchView: searchView,
+		GoToView:   goToView,
+		EditView:   editView,
 		width:      w,
 		height:     h,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	// the refresh key only applies, and is only listed in help, when there is
+	// something to reload
+	m.KeyMap.Refresh.SetEnabled(m.load != nil)
+	m.KeyMap.Shadowed.SetEnabled(m.annotate != nil)
+	m.KeyMap.Warnings.SetEnabled(m.warnings != nil)
+	if m.annotate != nil || m.warnings != nil {
+		m.TreeView.SetAnnotator(m.notes)
+	}
+	m.loadWarnings()
+	m.status = m.warningsStatus()
+	m.KeyMap.Reveal.SetEnabled(m.redactor != nil)
+	if m.redactor != nil {
+		m.TreeView.SetRedactor(m.redactor.Summary)
+	}
+	m.setEditKeysEnabled(false)
+	return m, nil
 }
 
 // ShortHelp returns a short help view for the TUI
@@ -78,13 +212,49 @@ func (m *Model) FullHelp() [][]key.Binding {
 		m.KeyMap.Search,
 		m.KeyMap.Submit,
 		m.KeyMap.Next,
+		m.KeyMap.GoTo,
+		m.KeyMap.Duplicates,
 		m.KeyMap.Num,
+		m.KeyMap.Refresh,
+		m.KeyMap.Shadowed,
+		m.KeyMap.Reveal,
+		m.KeyMap.Format,
+		m.KeyMap.Warnings,
+		m.KeyMap.Edit,
+		m.KeyMap.EditValue,
+		m.KeyMap.Add,
+		m.KeyMap.Delete,
+		m.KeyMap.Rename,
+		m.KeyMap.Undo,
+		m.KeyMap.Redo,
+		m.KeyMap.Save,
 		m.KeyMap.Quit,
 		m.KeyMap.Help,
 	}}
 }
 
+// setEditKeysEnabled enables the keys that only apply in edit mode. Save is
+// only enabled when there is somewhere to save to.
+func (m *Model) setEditKeysEnabled(enabled bool) {
+	m.KeyMap.EditValue.SetEnabled(enabled)
+	m.KeyMap.Add.SetEnabled(enabled)
+	m.KeyMap.Delete.SetEnabled(enabled)
+	m.KeyMap.Rename.SetEnabled(enabled)
+	m.KeyMap.Undo.SetEnabled(enabled)
+	m.KeyMap.Redo.SetEnabled(enabled)
+	m.KeyMap.Save.SetEnabled(enabled && m.save != nil)
+}
+
+// WithWatch polls changed every interval and reloads the tree with the loader
+// set by WithRefresh whenever it reports a change.
+func WithWatch(changed Watcher, interval time.Duration) Option {
+	return func(m *Model) {
+		m.changed = changed
+		m.pollInterval = interval
+	}
+}
+
 // Init Initialize the dashboard
 func (m *Model) Init() tea.Cmd {
-	return tea.ClearScreen
+	return tea.Batch(tea.ClearScreen, m.tick(), m.poll())
 }
@@ -1,13 +1,38 @@
 package tui
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/sirupsen/logrus"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/crosleyzack/wndr/pkg/modules/tree"
+	"github.com/crosleyzack/wndr/pkg/nodes"
+	"github.com/crosleyzack/wndr/pkg/nodes/query"
 )
 
+// changedHighlight is how long nodes changed by a reload stay highlighted
+const changedHighlight = 3 * time.Second
+
+// refreshTickMsg fires every refresh interval
+type refreshTickMsg struct{}
+
+// pollMsg fires every watch poll interval
+type pollMsg struct{}
+
+// clearChangedMsg ends the highlight of the reload numbered generation
+type clearChangedMsg struct {
+	generation int
+}
+
+// refreshedMsg carries the outcome of reloading the tree
+type refreshedMsg struct {
+	root *nodes.Node
+	err  error
+}
+
 // Update the tree view component
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if m == nil {
@@ -20,9 +45,79 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+	case refreshTickMsg:
+		return m, tea.Batch(m.refresh(), m.tick())
+	case refreshedMsg:
+		m.refreshing = false
+		if msg.err != nil {
+			m.status = fmt.Sprintf("refresh failed: %v", msg.err)
+			return m, nil
+		}
+		changed := m.TreeView.SetRoot(msg.root)
+		m.loadWarnings()
+		m.status = fmt.Sprintf("refreshed at %s, %d changed", time.Now().Format(time.TimeOnly), changed)
+		if len(m.issues) > 0 {
+			m.status += fmt.Sprintf(", %d warnings", len(m.issues))
+		}
+		m.highlights++
+		generation := m.highlights
+		return m, tea.Tick(changedHighlight, func(time.Time) tea.Msg {
+			return clearChangedMsg{generation: generation}
+		})
+	case clearChangedMsg:
+		if msg.generation == m.highlights {
+			m.TreeView.ClearChanged()
+		}
+	case pollMsg:
+		// leave the change to be picked up by the next poll while a reload
+		// is still running
+		if m.refreshing {
+			return m, m.poll()
+		}
+		changed, err := m.changed()
+		if err != nil {
+			m.status = fmt.Sprintf("watch failed: %v", err)
+			return m, m.poll()
+		}
+		if changed {
+			return m, tea.Batch(m.refresh(), m.poll())
+		}
+		return m, m.poll()
 	case tea.KeyMsg:
+		// a quit with unsaved edits is only confirmed by the very next key
+		confirmingQuit := m.confirmingQuit
+		m.confirmingQuit = false
+		if m.confirmingSave {
+			m.confirmingSave = false
+			if msg.String() == "y" {
+				m.saveTree()
+			} else {
+				m.status = "not saved"
+			}
+			return m, nil
+		}
 		switch {
+		case m.EditView.Focused() && msg.Type == tea.KeyEsc:
+			m.EditView.Blur()
+			m.EditView.Reset()
+		case m.GoToView.Focused() && msg.Type == tea.KeyEsc:
+			// cancel the prompt without moving the cursor
+			m.GoToView.Blur()
+			m.GoToView.Reset()
+		case m.SearchView.Focused() && msg.Type == tea.KeyEsc:
+			m.SearchView.Blur()
+			m.SearchView.Reset()
 		case key.Matches(msg, m.KeyMap.Submit):
+			if m.EditView.Focused() {
+				m.submitEdit()
+				return m, nil
+			}
+			if m.GoToView.Focused() {
+				m.goTo(m.GoToView.Value())
+				m.GoToView.Blur()
+				m.GoToView.Reset()
+				return m, nil
+			}
 			if !m.SearchView.Focused() {
 				// copy the path do this node
 				err := m.TreeView.CopyNodePath()
@@ -41,13 +136,84 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case m.SearchView.Focused():
 			// If the search view is focused, update it with any key
 			m.SearchView, _ = m.SearchView.Update(msg)
+		case m.GoToView.Focused():
+			m.GoToView, _ = m.GoToView.Update(msg)
+		case m.EditView.Focused():
+			m.EditView, _ = m.EditView.Update(msg)
 		case key.Matches(msg, m.KeyMap.Help):
 			m.HelpView.ShowAll = !m.HelpView.ShowAll
 		case key.Matches(msg, m.KeyMap.Quit):
+			if m.TreeView.Edited() && !confirmingQuit {
+				m.confirmingQuit = true
+				m.status = "unsaved changes: quit again to discard them"
+				return m, nil
+			}
 			return m, tea.Batch(tea.ClearScreen, tea.Quit)
 		case key.Matches(msg, m.KeyMap.Search):
 			m.SearchView.Reset()
 			m.SearchView.Focus()
+		case key.Matches(msg, m.KeyMap.GoTo):
+			m.GoToView.Reset()
+			m.GoToView.Focus()
+		case key.Matches(msg, m.KeyMap.Duplicates):
+			groups, found := m.TreeView.FindDuplicates()
+			if groups == 0 {
+				m.status = "no duplicate subtrees"
+				return m, nil
+			}
+			m.status = fmt.Sprintf("%d subtrees in %d groups of duplicates", found, groups)
+		case key.Matches(msg, m.KeyMap.Refresh):
+			return m, m.refresh()
+		case key.Matches(msg, m.KeyMap.Shadowed):
+			m.TreeView.ToggleDetails()
+		case key.Matches(msg, m.KeyMap.Reveal):
+			if m.TreeView.ToggleRevealed() {
+				m.status = "redacted values revealed"
+			} else {
+				m.status = "redacted values hidden"
+			}
+		case key.Matches(msg, m.KeyMap.Warnings):
+			m.nextWarningsPage()
+		case key.Matches(msg, m.KeyMap.Format):
+			m.TreeView.NextFormat()
+		case key.Matches(msg, m.KeyMap.Edit):
+			editing := !m.TreeView.Editing()
+			m.TreeView.SetEditing(editing)
+			m.setEditKeysEnabled(editing)
+		case key.Matches(msg, m.KeyMap.EditValue):
+			if n := m.TreeView.CurrentNode(); n != nil {
+				// a masked value is not put in the prompt, where it would show
+				value := n.Value
+				if m.TreeView.Hidden(n) {
+					value = ""
+				}
+				m.prompt(editValue, "value: ", value)
+			}
+		case key.Matches(msg, m.KeyMap.Add):
+			if m.TreeView.AddsToArray() {
+				m.prompt(editAddValue, "value: ", "")
+			} else {
+				m.prompt(editAddKey, "key: ", "")
+			}
+		case key.Matches(msg, m.KeyMap.Rename):
+			if n := m.TreeView.CurrentNode(); n != nil {
+				m.prompt(editRename, "key: ", n.Key)
+			}
+		case key.Matches(msg, m.KeyMap.Delete):
+			m.reportEdit(m.TreeView.Delete())
+		case key.Matches(msg, m.KeyMap.Undo):
+			ok, err := m.TreeView.Undo()
+			m.reportStep(ok, err, "nothing to undo")
+		case key.Matches(msg, m.KeyMap.Redo):
+			ok, err := m.TreeView.Redo()
+			m.reportStep(ok, err, "nothing to redo")
+		case key.Matches(msg, m.KeyMap.Save):
+			if !m.TreeView.Edited() {
+				m.status = "no changes to save"
+				return m, nil
+			}
+			m.confirmingSave = true
+			m.status = fmt.Sprintf("write changes to %s, keeping a backup in %s.bak? (y/n)", m.saveName, m.saveName)
 		default:
 			model, _ := m.TreeView.Update(msg)
 			var ok bool
@@ -58,3 +224,121 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 	return m, nil
 }
+
+// goTo moves the cursor to the nodes matching a JSONPath expression or JSON
+// Pointer, reporting the outcome in the status line.
+func (m *Model) goTo(expr string) {
+	matches, err := query.Find(m.TreeView.Root, expr)
+	if err != nil {
+		m.status = err.Error()
+		return
+	}
+	if !m.TreeView.GoTo(matches) {
+		m.status = fmt.Sprintf("no nodes match %s", expr)
+		return
+	}
+	m.status = fmt.Sprintf("%d nodes match %s", len(matches), expr)
+}
+
+// prompt focuses the EditView to read input for action, starting from value.
+func (m *Model) prompt(action editAction, prompt, value string) {
+	m.editAction = action
+	m.EditView.Prompt = prompt
+	m.EditView.SetValue(value)
+	m.EditView.CursorEnd()
+	m.EditView.Focus()
+}
+
+// submitEdit applies the input typed into the EditView.
+func (m *Model) submitEdit() {
+	input := m.EditView.Value()
+	m.EditView.Blur()
+	m.EditView.Reset()
+	switch m.editAction {
+	case editValue:
+		m.reportEdit(m.TreeView.SetValue(input))
+	case editRename:
+		m.reportEdit(m.TreeView.Rename(input))
+	case editAddKey:
+		// the key is known; ask for the value to give it
+		m.addKey = input
+		m.prompt(editAddValue, fmt.Sprintf("value of %s: ", input), "")
+	case editAddValue:
+		m.reportEdit(m.TreeView.Add(m.addKey, input))
+		m.addKey = ""
+	}
+}
+
+// reportEdit shows the outcome of an edit in the status line.
+func (m *Model) reportEdit(err error) {
+	if err != nil {
+		m.status = fmt.Sprintf("edit failed: %v", err)
+		return
+	}
+	m.status = ""
+}
+
+// reportStep shows the outcome of an undo or redo in the status line.
+func (m *Model) reportStep(ok bool, err error, none string) {
+	switch {
+	case err != nil:
+		m.status = err.Error()
+	case !ok:
+		m.status = none
+	default:
+		m.status = ""
+	}
+}
+
+// saveTree writes the tree back with the saver set by WithSave.
+func (m *Model) saveTree() {
+	note, err := m.save(m.TreeView.Root)
+	if err != nil {
+		m.status = fmt.Sprintf("save failed: %v", err)
+		return
+	}
+	m.TreeView.MarkSaved()
+	m.status = fmt.Sprintf("saved %s at %s", m.saveName, time.Now().Format(time.TimeOnly))
+	if note != "" {
+		m.status += ": " + note
+	}
+}
+
+// refresh reloads the tree in the background, delivering a refreshedMsg. It
+// does nothing when there is no loader or a reload is already running, and
+// refuses to reload over unsaved edits.
+func (m *Model) refresh() tea.Cmd {
+	if m.load == nil || m.refreshing {
+		return nil
+	}
+	if m.TreeView.Edited() {
+		m.status = "not reloaded: there are unsaved changes"
+		return nil
+	}
+	m.refreshing = true
+	load := m.load
+	return func() tea.Msg {
+		root, err := load()
+		return refreshedMsg{root: root, err: err}
+	}
+}
+
+// tick schedules the next automatic refresh, if enabled.
+func (m *Model) tick() tea.Cmd {
+	if m.load == nil || m.interval <= 0 {
+		return nil
+	}
+	return tea.Tick(m.interval, func(time.Time) tea.Msg {
+		return refreshTickMsg{}
+	})
+}
+
+// poll schedules the next check for changed data, if watching.
+func (m *Model) poll() tea.Cmd {
+	if m.changed == nil || m.pollInterval <= 0 {
+		return nil
+	}
+	return tea.Tick(m.pollInterval, func(time.Time) tea.Msg {
+		return pollMsg{}
+	})
+}
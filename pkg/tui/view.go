@@ -1,6 +1,8 @@
 package tui
 
 import (
+	"strings"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -25,6 +27,18 @@ func (m *Model) View() string {
 		availableHeight -= 1
 	}
 
+	if m.showWarnings {
+		warnings := m.warningsView()
+		sections = append([]string{m.Styles.Help.Render(warnings)}, sections...)
+		availableHeight -= strings.Count(warnings, "\n") + 1
+	}
+
+	// add the status of the last refresh, if any
+	if m.status != "" {
+		sections = append([]string{m.Styles.Help.Render(m.status)}, sections...)
+		availableHeight -= 1
+	}
+
 	var search string
 	if m.SearchView.Focused() {
 		search = m.Styles.Help.Render(m.SearchView.View())
@@ -32,9 +46,43 @@ func (m *Model) View() string {
 		availableHeight -= 1
 	}
 
+	if m.GoToView.Focused() {
+		sections = append(sections, m.Styles.Help.Render(m.GoToView.View()))
+		availableHeight -= 1
+	}
+
+	if m.EditView.Focused() {
+		sections = append(sections, m.Styles.Help.Render(m.EditView.View()))
+		availableHeight -= 1
+	}
+
+	// show the summary format, that keys edit the tree, and whether there
+	// is anything to save
+	if bar := m.statusBar(); bar != "" {
+		sections = append([]string{m.Styles.Help.Render(bar)}, sections...)
+		availableHeight -= 1
+	}
+
 	m.TreeView.Height = availableHeight - 1 // add a line of padding
 	tree := m.TreeView.View()
 
 	sections = append([]string{tree}, sections...)
 	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
+
+// statusBar returns the line kept under the tree: the edit mode and the
+// format objects and arrays are summarized in, when there are.
+func (m *Model) statusBar() string {
+	var parts []string
+	if m.TreeView.Editing() {
+		mode := "-- EDIT --"
+		if m.TreeView.Edited() {
+			mode = "-- EDIT (modified) --"
+		}
+		parts = append(parts, mode)
+	}
+	if format := m.TreeView.Format(); format != "" {
+		parts = append(parts, "format: "+format)
+	}
+	return strings.Join(parts, "  ")
+}
@@ -0,0 +1,120 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crosleyzack/wndr/pkg/nodes"
+)
+
+// maxWarningLines is the most warnings listed in the warnings panel at once;
+// the rest are paged through.
+const maxWarningLines = 10
+
+// Warning is a problem found in the data, such as a key written twice.
+type Warning struct {
+	// Path is the path to the value the warning is about
+	Path nodes.Path
+	// Line is the line of the source the value is on, counting from 1, or 0
+	// when it is not known
+	Line int
+	// Message describes the problem
+	Message string
+}
+
+// WithWarnings notes the warnings given by warnings after the values they are
+// about, and lists them in a panel shown with the warnings key. warnings is
+// called again after each reload, for the warnings about the new data.
+func WithWarnings(warnings func() []Warning) Option {
+	return func(m *Model) {
+		m.warnings = warnings
+	}
+}
+
+// loadWarnings gets the warnings about the tree shown and finds the nodes
+// they are about.
+func (m *Model) loadWarnings() {
+	m.issues, m.warned = nil, nil
+	if m.warnings == nil {
+		return
+	}
+	m.issues = m.warnings()
+	m.warningsPage = min(m.warningsPage, max(m.warningsPages()-1, 0))
+	m.warned = map[*nodes.Node][]Warning{}
+	for _, w := range m.issues {
+		if n := w.Path.Resolve(m.TreeView.Root); n != nil {
+			m.warned[n] = append(m.warned[n], w)
+		}
+	}
+}
+
+// warningsStatus reports how many warnings there are, or "" for none.
+func (m *Model) warningsStatus() string {
+	if len(m.issues) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d warnings: press %s to list them", len(m.issues), m.KeyMap.Warnings.Help().Key)
+}
+
+// notes returns the note shown after the value of n: its warnings, then the
+// note given by the annotator of WithAnnotations.
+func (m *Model) notes(n *nodes.Node, detailed bool, repr nodes.ReprNode) string {
+	var parts []string
+	for _, w := range m.warned[n] {
+		parts = append(parts, "⚠ "+w.Message)
+	}
+	if m.annotate != nil {
+		if note := m.annotate(n, detailed, repr); note != "" {
+			parts = append(parts, note)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// warningsPages returns the number of pages of warnings.
+func (m *Model) warningsPages() int {
+	return (len(m.issues) + maxWarningLines - 1) / maxWarningLines
+}
+
+// nextWarningsPage shows the warnings panel, or its next page, closing it
+// after the last.
+func (m *Model) nextWarningsPage() {
+	switch {
+	case !m.showWarnings:
+		m.showWarnings, m.warningsPage = true, 0
+	case m.warningsPage+1 < m.warningsPages():
+		m.warningsPage++
+	default:
+		m.showWarnings = false
+	}
+}
+
+// warningsView lists the page of warnings shown, one per line, with the line
+// and path of the value each is about.
+func (m *Model) warningsView() string {
+	if len(m.issues) == 0 {
+		return "no warnings"
+	}
+	start := m.warningsPage * maxWarningLines
+	page := m.issues[start:min(start+maxWarningLines, len(m.issues))]
+	lines := make([]string, 0, len(page)+1)
+	for _, w := range page {
+		path := m.TreeView.FormatPath(w.Path)
+		if n := w.Path.Resolve(m.TreeView.Root); n != nil {
+			path = m.TreeView.NodePath(n)
+		}
+		where := path
+		if w.Line > 0 {
+			where = fmt.Sprintf("line %d: %s", w.Line, path)
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", where, w.Message))
+	}
+	if pages := m.warningsPages(); pages > 1 {
+		next := "for the next"
+		if m.warningsPage == pages-1 {
+			next = "to close"
+		}
+		lines = append(lines, fmt.Sprintf("warnings %d-%d of %d: press %s %s", start+1, start+len(page), len(m.issues), m.KeyMap.Warnings.Help().Key, next))
+	}
+	return strings.Join(lines, "\n")
+}